@@ -0,0 +1,104 @@
+package activity
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"goflix/config"
+	"goflix/db"
+	"goflix/models"
+)
+
+// Aggregate recomputes the trending and top 10 boards from the recent events, per region and
+// for all regions, then forgets the events too old to count. Each user counts once per title
+// and kind of event, with their latest event, so replaying a title doesn't push it up.
+func Aggregate(ctx context.Context, store db.Storage, now time.Time) error {
+	events, err := store.GetEventsSince(ctx, now.Add(-config.TRENDING_WINDOW))
+	if err != nil {
+		return err
+	}
+	type key struct {
+		userID, movieID int
+		kind            string
+	}
+	latest := map[key]*models.Event{}
+	for _, event := range events {
+		latest[key{event.UserId, event.MovieId, event.Kind}] = event
+	}
+
+	trending := scores{}
+	top10 := scores{}
+	for _, event := range latest {
+		age := now.Sub(event.CreatedAt)
+		weight := config.EVENT_WEIGHTS[event.Kind]
+		trending.add(event, weight*math.Pow(0.5, float64(age)/float64(config.TRENDING_HALF_LIFE)))
+		if age < config.TOP10_WINDOW {
+			top10.add(event, weight)
+		}
+	}
+	err = store.SavePopularity(ctx, config.BOARD_TRENDING, trending.list())
+	if err != nil {
+		return err
+	}
+	err = store.SavePopularity(ctx, config.BOARD_TOP10, top10.list())
+	if err != nil {
+		return err
+	}
+	_, err = store.DeleteEventsBefore(ctx, now.Add(-config.TRENDING_WINDOW))
+	return err
+}
+
+type regionMovie struct {
+	region  string
+	movieID int
+}
+
+type scores map[regionMovie]float64
+
+func (s scores) add(event *models.Event, score float64) {
+	s[regionMovie{event.Region, event.MovieId}] += score
+	s[regionMovie{config.ALL_REGIONS, event.MovieId}] += score
+}
+
+func (s scores) list() []*models.Popularity {
+	list := make([]*models.Popularity, 0, len(s))
+	for key, score := range s {
+		list = append(list, &models.Popularity{MovieId: key.movieID, Region: key.region, Score: score})
+	}
+	return list
+}
+
+// Board ranks up to limit titles visible through filter. The board of the region comes first,
+// the one of all regions completes it when the region lacks activity.
+func Board(ctx context.Context, store db.Storage, board string, region string, filter *models.CatalogFilter, limit int) ([]*models.RankedTitle, error) {
+	// some titles may be hidden from the viewer, fetch more than needed
+	regional, err := store.GetPopularity(ctx, board, region, limit*2)
+	if err != nil {
+		return nil, err
+	}
+	global, err := store.GetPopularity(ctx, board, config.ALL_REGIONS, limit*2)
+	if err != nil {
+		return nil, err
+	}
+	score := map[int]float64{}
+	var ids []int
+	for _, popularity := range append(regional, global...) {
+		if _, ok := score[popularity.MovieId]; !ok {
+			score[popularity.MovieId] = popularity.Score
+			ids = append(ids, popularity.MovieId)
+		}
+	}
+	movies, err := store.GetMoviesByIds(ctx, ids, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(movies) > limit {
+		movies = movies[:limit]
+	}
+	titles := make([]*models.RankedTitle, len(movies))
+	for i, movie := range movies {
+		titles[i] = &models.RankedTitle{Rank: i + 1, Score: math.Round(score[movie.Id]*100) / 100, Movie: movie}
+	}
+	return titles, nil
+}
@@ -0,0 +1,131 @@
+package apierr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"goflix/db"
+	"goflix/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PROBLEM_CONTENT_TYPE is the media type of RFC 7807 responses.
+const PROBLEM_CONTENT_TYPE = "application/problem+json"
+
+// Error is an API error with a stable code, its message is looked up in the catalogs.
+type Error struct {
+	Status int
+	Code   string
+	Args   []any
+	Detail string
+	Extra  gin.H
+	Err    error
+}
+
+func New(status int, code string, args ...any) *Error {
+	return &Error{Status: status, Code: code, Args: args}
+}
+
+// BadRequest reports a request body or form that could not be decoded.
+func BadRequest(err error) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: INVALID_BODY, Detail: err.Error(), Err: err}
+}
+
+// WithDetail adds a human readable detail about this occurrence, it is never translated.
+func (e *Error) WithDetail(detail string) *Error {
+	e.Detail = detail
+	return e
+}
+
+// With adds an extension member to the problem document.
+func (e *Error) With(key string, value any) *Error {
+	if e.Extra == nil {
+		e.Extra = gin.H{}
+	}
+	e.Extra[key] = value
+	return e
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message(nil) + ": " + e.Err.Error()
+	}
+	return e.Message(nil)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Message translates the error in the first locale of the chain having a catalog.
+func (e *Error) Message(locales []string) string {
+	format, ok := catalogs[catalogLocale(locales)][e.Code]
+	if !ok {
+		format, ok = catalogs[DEFAULT_CATALOG][e.Code]
+	}
+	if !ok {
+		return e.Code
+	}
+	return fmt.Sprintf(format, e.Args...)
+}
+
+// From turns any error into an API error, storage errors get their matching status and
+// unexpected ones are logged and hidden behind an internal error.
+func From(err error) *Error {
+	var apiErr *Error
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.Is(err, db.ErrStreamLimit):
+		return &Error{Status: http.StatusConflict, Code: STREAM_LIMIT, Err: err}
+	case errors.Is(err, db.ErrStreamEnded):
+		return &Error{Status: http.StatusGone, Code: STREAM_ENDED, Err: err}
+	case db.IsNotFound(err):
+		return &Error{Status: http.StatusNotFound, Code: NOT_FOUND, Err: err}
+	case db.IsConflict(err):
+		return &Error{Status: http.StatusConflict, Code: CONFLICT, Err: err}
+	case errors.Is(err, db.ErrInvalid):
+		return &Error{Status: http.StatusBadRequest, Code: INVALID_REQUEST, Detail: err.Error(), Err: err}
+	// a query timed out, or the client went away and nobody reads the answer
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return &Error{Status: http.StatusServiceUnavailable, Code: TIMEOUT, Err: err}
+	}
+	log.Printf("internal error: %v", err)
+	return &Error{Status: http.StatusInternalServerError, Code: INTERNAL, Err: err}
+}
+
+// Write aborts the request with err as a problem document in the language of the client.
+func Write(c *gin.Context, err error) {
+	apiErr := From(err)
+	locales := i18n.Chain(append([]string{c.Query("lang")}, i18n.ParseAcceptLanguage(c.GetHeader("Accept-Language"))...)...)
+	problem := gin.H{
+		"type":     "urn:goflix:problem:" + apiErr.Code,
+		"title":    apiErr.Message(locales),
+		"status":   apiErr.Status,
+		"code":     apiErr.Code,
+		"instance": c.Request.URL.Path,
+	}
+	if apiErr.Detail != "" {
+		problem["detail"] = apiErr.Detail
+	}
+	for key, value := range apiErr.Extra {
+		problem[key] = value
+	}
+	c.Header("Content-Type", PROBLEM_CONTENT_TYPE)
+	c.Header("Content-Language", catalogLocale(locales))
+	c.Header("Vary", "Accept-Language")
+	c.AbortWithStatusJSON(apiErr.Status, problem)
+}
+
+func catalogLocale(locales []string) string {
+	for _, locale := range locales {
+		if _, ok := catalogs[locale]; ok {
+			return locale
+		}
+	}
+	return DEFAULT_CATALOG
+}
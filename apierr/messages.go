@@ -0,0 +1,164 @@
+package apierr
+
+import "goflix/config"
+
+// Stable error codes, clients may rely on them, never rename one.
+const (
+	INTERNAL        = "internal"
+	INVALID_BODY    = "invalid_body"
+	INVALID_REQUEST = "invalid_request"
+	INVALID_PARAM   = "invalid_param"
+	MISSING_FIELD   = "missing_field"
+	INVALID_CHOICE  = "invalid_choice"
+	NOT_FOUND       = "not_found"
+	CONFLICT        = "conflict"
+	TIMEOUT         = "timeout"
+
+	MISSING_TOKEN       = "missing_token"
+	INVALID_TOKEN       = "invalid_token"
+	INVALID_CREDENTIALS = "invalid_credentials"
+	ADMIN_ONLY          = "admin_only"
+	EDITOR_ONLY         = "editor_only"
+
+	SUBSCRIPTION_REQUIRED = "subscription_required"
+	SUBSCRIPTION_INACTIVE = "subscription_inactive"
+	PAYMENT_PROVIDER      = "payment_provider"
+	INVALID_SIGNATURE     = "invalid_signature"
+	NOT_SIMULATED         = "not_simulated"
+	INVALID_PLAN          = "invalid_plan"
+
+	STREAM_LIMIT = "stream_limit"
+	STREAM_ENDED = "stream_ended"
+
+	UNKNOWN_MATURITY   = "unknown_maturity"
+	PIN_TOO_SHORT      = "pin_too_short"
+	PIN_NOT_DIGITS     = "pin_not_digits"
+	WRONG_PIN          = "wrong_pin"
+	PIN_LOCKED         = "pin_locked"
+	INVALID_REGION     = "invalid_region"
+	INVALID_COUNTRY    = "invalid_country"
+	INVALID_WINDOW     = "invalid_window"
+	PUBLISH_IN_PAST    = "publish_in_past"
+	INVALID_TRANSITION = "invalid_transition"
+	FORBIDDEN_STATUS   = "forbidden_status"
+	FILE_TOO_LARGE     = "file_too_large"
+	INVALID_SUBTITLE   = "invalid_subtitle"
+
+	METADATA_PROVIDER  = "metadata_provider"
+	METADATA_NOT_FOUND = "metadata_not_found"
+	METADATA_DISABLED  = "metadata_disabled"
+
+	DEFAULT_LIST   = "default_list"
+	TOO_MANY_LISTS = "too_many_lists"
+
+	REVIEW_TOO_LONG = "review_too_long"
+	OWN_REVIEW      = "own_review"
+)
+
+// DEFAULT_CATALOG answers when no catalog matches the client languages.
+const DEFAULT_CATALOG = config.DEFAULT_LOCALE
+
+var catalogs = map[string]map[string]string{
+	"en": {
+		INTERNAL:        "Internal server error",
+		TIMEOUT:         "The server took too long to answer, try again later",
+		INVALID_BODY:    "Invalid request body",
+		INVALID_REQUEST: "Invalid request",
+		INVALID_PARAM:   "Invalid %s",
+		MISSING_FIELD:   "Missing %s",
+		INVALID_CHOICE:  "Invalid %s, want one of %v",
+		NOT_FOUND:       "Resource not found",
+		CONFLICT:        "Resource already exists or was modified meanwhile",
+
+		MISSING_TOKEN:       "Missing token",
+		INVALID_TOKEN:       "Invalid token",
+		INVALID_CREDENTIALS: "Wrong user or password",
+		ADMIN_ONLY:          "Access denied, admin only",
+		EDITOR_ONLY:         "Access denied, editors only",
+
+		SUBSCRIPTION_REQUIRED: "Subscription required",
+		SUBSCRIPTION_INACTIVE: "Subscription %s",
+		PAYMENT_PROVIDER:      "Payment provider unavailable",
+		INVALID_SIGNATURE:     "Invalid webhook signature",
+		NOT_SIMULATED:         "Payment provider is not simulated",
+		INVALID_PLAN:          "Invalid plan",
+
+		STREAM_LIMIT: "Maximum concurrent streams reached",
+		STREAM_ENDED: "Stream session ended",
+
+		UNKNOWN_MATURITY:   "Unknown maturity rating",
+		PIN_TOO_SHORT:      "PIN must have at least %d digits",
+		PIN_NOT_DIGITS:     "PIN must only have digits",
+		WRONG_PIN:          "Wrong PIN",
+		PIN_LOCKED:         "Too many wrong PINs, try again in %d minutes",
+		INVALID_REGION:     "Region must be a two letters country code",
+		INVALID_COUNTRY:    "Country must be a two letters country code or *",
+		INVALID_WINDOW:     "Availability window ends before it starts",
+		PUBLISH_IN_PAST:    "Publication date must be in the future",
+		INVALID_TRANSITION: "Cannot move a title from %s to %s",
+		FORBIDDEN_STATUS:   "%s cannot move a title to %s",
+		FILE_TOO_LARGE:     "File too large, %d MB maximum",
+		INVALID_SUBTITLE:   "Invalid subtitle file",
+
+		METADATA_PROVIDER:  "Metadata provider unavailable",
+		METADATA_NOT_FOUND: "Title not found at the metadata provider",
+		METADATA_DISABLED:  "Metadata enrichment is disabled, %s is not set",
+
+		DEFAULT_LIST:   "The default list cannot be deleted",
+		TOO_MANY_LISTS: "%d lists at most",
+
+		REVIEW_TOO_LONG: "Review too long, %d characters maximum",
+		OWN_REVIEW:      "You cannot vote for or report your own review",
+	},
+	"fr": {
+		INTERNAL:        "Erreur interne du serveur",
+		TIMEOUT:         "Le serveur a mis trop de temps à répondre, réessayez plus tard",
+		INVALID_BODY:    "Corps de requête invalide",
+		INVALID_REQUEST: "Requête invalide",
+		INVALID_PARAM:   "%s invalide",
+		MISSING_FIELD:   "%s manquant",
+		INVALID_CHOICE:  "%s invalide, valeurs possibles : %v",
+		NOT_FOUND:       "Ressource introuvable",
+		CONFLICT:        "La ressource existe déjà ou a été modifiée entre-temps",
+
+		MISSING_TOKEN:       "Token manquant",
+		INVALID_TOKEN:       "Token invalide",
+		INVALID_CREDENTIALS: "Utilisateur ou mot de passe incorrect",
+		ADMIN_ONLY:          "Accès refusé, réservé aux administrateurs",
+		EDITOR_ONLY:         "Accès refusé, réservé aux éditeurs",
+
+		SUBSCRIPTION_REQUIRED: "Abonnement requis",
+		SUBSCRIPTION_INACTIVE: "Abonnement %s",
+		PAYMENT_PROVIDER:      "Prestataire de paiement indisponible",
+		INVALID_SIGNATURE:     "Signature du webhook invalide",
+		NOT_SIMULATED:         "Le prestataire de paiement n'est pas simulé",
+		INVALID_PLAN:          "Offre invalide",
+
+		STREAM_LIMIT: "Nombre maximum de lectures simultanées atteint",
+		STREAM_ENDED: "Session de lecture terminée",
+
+		UNKNOWN_MATURITY:   "Classification inconnue",
+		PIN_TOO_SHORT:      "Le code PIN doit avoir au moins %d chiffres",
+		PIN_NOT_DIGITS:     "Le code PIN ne doit contenir que des chiffres",
+		WRONG_PIN:          "Code PIN incorrect",
+		PIN_LOCKED:         "Trop de codes PIN incorrects, réessayez dans %d minutes",
+		INVALID_REGION:     "La région doit être un code pays à deux lettres",
+		INVALID_COUNTRY:    "Le pays doit être un code pays à deux lettres ou *",
+		INVALID_WINDOW:     "La fenêtre de disponibilité se termine avant de commencer",
+		PUBLISH_IN_PAST:    "La date de publication doit être dans le futur",
+		INVALID_TRANSITION: "Impossible de passer un titre de %s à %s",
+		FORBIDDEN_STATUS:   "%s ne peut pas passer un titre en %s",
+		FILE_TOO_LARGE:     "Fichier trop volumineux, %d Mo maximum",
+		INVALID_SUBTITLE:   "Fichier de sous-titres invalide",
+
+		METADATA_PROVIDER:  "Fournisseur de métadonnées indisponible",
+		METADATA_NOT_FOUND: "Titre introuvable chez le fournisseur de métadonnées",
+		METADATA_DISABLED:  "L'enrichissement des métadonnées est désactivé, %s n'est pas définie",
+
+		DEFAULT_LIST:   "La liste par défaut ne peut pas être supprimée",
+		TOO_MANY_LISTS: "%d listes au maximum",
+
+		REVIEW_TOO_LONG: "Critique trop longue, %d caractères maximum",
+		OWN_REVIEW:      "Impossible de voter pour sa propre critique ou de la signaler",
+	},
+}
@@ -0,0 +1,264 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"goflix/config"
+	"goflix/models"
+)
+
+// Options of a benchmark run, User logs in and rates the titles of the catalog.
+type Options struct {
+	URL         string
+	User        string
+	Password    string
+	Concurrency int
+	Duration    time.Duration
+}
+
+// request builds one call of a scenario, each client has its own random source.
+type request struct {
+	name  string
+	build func(ctx context.Context, rnd *rand.Rand) (*http.Request, error)
+}
+
+type scenario struct {
+	name     string
+	requests []request
+}
+
+// stats are kept per client and request, then merged, so that clients never wait on each other.
+type stats struct {
+	requests  int
+	latencies []time.Duration
+	statuses  map[string]int
+	failed    int
+}
+
+// Run loads the API at opts.URL with the scenarios one after the other: catalog reads only,
+// rating writes only, then both at once, half of the clients each. Every scenario keeps
+// opts.Concurrency clients busy for opts.Duration.
+func Run(ctx context.Context, opts Options) (*models.BenchReport, error) {
+	if opts.Concurrency < 1 || opts.Duration <= 0 {
+		return nil, errors.New("concurrency and duration must be positive")
+	}
+	b := &bencher{
+		url:    opts.URL,
+		client: &http.Client{Timeout: config.BENCH_TIMEOUT, Transport: &http.Transport{MaxIdleConnsPerHost: opts.Concurrency}},
+	}
+	err := b.login(ctx, opts.User, opts.Password)
+	if err != nil {
+		return nil, err
+	}
+	err = b.loadMovies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	movies := request{"GET /movies", b.getMovies}
+	ratings := request{"POST /ratings", b.postRating}
+	report := &models.BenchReport{URL: opts.URL, Concurrency: opts.Concurrency, Duration: opts.Duration.String()}
+	for _, sc := range []scenario{
+		{"movies", []request{movies}},
+		{"ratings", []request{ratings}},
+		{"mixed", []request{movies, ratings}},
+	} {
+		results := b.run(ctx, sc, opts.Concurrency, opts.Duration)
+		report.Results = append(report.Results, results...)
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+	}
+	return report, nil
+}
+
+type bencher struct {
+	url      string
+	client   *http.Client
+	token    string
+	movieIDs []int
+}
+
+func (b *bencher) login(ctx context.Context, user string, password string) error {
+	body, _ := json.Marshal(map[string]string{"user": user, "pswd": password})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url+"/login", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	var res struct {
+		Token string `json:"token"`
+	}
+	err = b.decode(req, &res)
+	if err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	b.token = res.Token
+	return nil
+}
+
+// loadMovies reads the titles the user sees, the ratings go to them.
+func (b *bencher) loadMovies(ctx context.Context) error {
+	req, err := b.getMovies(ctx, nil)
+	if err != nil {
+		return err
+	}
+	var res struct {
+		Movies []*models.Movies `json:"movie"`
+	}
+	err = b.decode(req, &res)
+	if err != nil {
+		return fmt.Errorf("movies: %w", err)
+	}
+	for _, movie := range res.Movies {
+		b.movieIDs = append(b.movieIDs, movie.Id)
+	}
+	if len(b.movieIDs) == 0 {
+		return errors.New("movies: the catalog is empty, nothing to rate")
+	}
+	return nil
+}
+
+func (b *bencher) decode(req *http.Request, v any) error {
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (b *bencher) getMovies(ctx context.Context, _ *rand.Rand) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.url+"/movies", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", b.token)
+	return req, nil
+}
+
+func (b *bencher) postRating(ctx context.Context, rnd *rand.Rand) (*http.Request, error) {
+	body, _ := json.Marshal(models.Rating{MovieId: b.movieIDs[rnd.Intn(len(b.movieIDs))], Stars: 1 + rnd.Intn(5)})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url+"/ratings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", b.token)
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// run keeps the clients sending the requests of the scenario until d is over, client i sending
+// the request i modulo their number.
+func (b *bencher) run(ctx context.Context, sc scenario, clients int, d time.Duration) []*models.BenchResult {
+	perClient := make([]*stats, clients)
+	var wg sync.WaitGroup
+	start := time.Now()
+	deadline := start.Add(d)
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s := &stats{statuses: map[string]int{}}
+			perClient[i] = s
+			req := sc.requests[i%len(sc.requests)]
+			rnd := rand.New(rand.NewSource(start.UnixNano() + int64(i)))
+			for time.Now().Before(deadline) && ctx.Err() == nil {
+				b.do(ctx, req, rnd, s)
+			}
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	results := make([]*models.BenchResult, len(sc.requests))
+	for r, req := range sc.requests {
+		merged := &stats{statuses: map[string]int{}}
+		for i := r; i < clients; i += len(sc.requests) {
+			merged.requests += perClient[i].requests
+			merged.latencies = append(merged.latencies, perClient[i].latencies...)
+			merged.failed += perClient[i].failed
+			for status, n := range perClient[i].statuses {
+				merged.statuses[status] += n
+			}
+		}
+		results[r] = merged.result(sc.name, req.name, elapsed)
+	}
+	return results
+}
+
+func (b *bencher) do(ctx context.Context, req request, rnd *rand.Rand, s *stats) {
+	httpReq, err := req.build(ctx, rnd)
+	if err != nil {
+		s.requests++
+		s.failed++
+		s.statuses["error"]++
+		return
+	}
+	began := time.Now()
+	resp, err := b.client.Do(httpReq)
+	if err != nil {
+		// an interrupt is not a failure of the API
+		if ctx.Err() == nil {
+			s.requests++
+			s.failed++
+			s.statuses["error"]++
+		}
+		return
+	}
+	// the body is read to the end so that the connection is reused
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	s.requests++
+	s.latencies = append(s.latencies, time.Since(began))
+	s.statuses[strconv.Itoa(resp.StatusCode)]++
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		s.failed++
+	}
+}
+
+func (s *stats) result(scenario string, request string, elapsed time.Duration) *models.BenchResult {
+	sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+	result := &models.BenchResult{
+		Scenario:   scenario,
+		Request:    request,
+		Requests:   s.requests,
+		Failed:     s.failed,
+		Statuses:   s.statuses,
+		Throughput: math.Round(float64(s.requests)/elapsed.Seconds()*10) / 10,
+	}
+	if n := len(s.latencies); n > 0 {
+		result.P50 = s.percentile(0.50)
+		result.P95 = s.percentile(0.95)
+		result.P99 = s.percentile(0.99)
+		result.Max = milliseconds(s.latencies[n-1])
+	}
+	return result
+}
+
+// percentile of the sorted latencies, by nearest rank.
+func (s *stats) percentile(p float64) float64 {
+	rank := int(math.Ceil(p*float64(len(s.latencies)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return milliseconds(s.latencies[rank])
+}
+
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d.Microseconds())/10) / 100
+}
@@ -0,0 +1,85 @@
+package billing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"goflix/models"
+)
+
+const SIGNATURE_HEADER = "X-Goflix-Signature"
+
+const (
+	EVENT_PAYMENT_SUCCEEDED     = "payment.succeeded"
+	EVENT_PAYMENT_FAILED        = "payment.failed"
+	EVENT_SUBSCRIPTION_CANCELED = "subscription.canceled"
+)
+
+type PaymentProvider interface {
+	CreateSubscription(user *models.User, plan *models.Plan) (string, error)
+	CancelSubscription(ref string) error
+	// ChangePlan moves the subscription to the plan, the provider charges the difference.
+	ChangePlan(ref string, plan *models.Plan) error
+	VerifyWebhook(payload []byte, signature string) (*Event, error)
+}
+
+type Event struct {
+	Id          string    `json:"id"`
+	Type        string    `json:"type"`
+	ProviderRef string    `json:"providerref"`
+	Created     time.Time `json:"created"`
+}
+
+// Sign builds a signature header value "t=<unix>,v1=<hex hmac-sha256 of t.payload>".
+func Sign(secret []byte, payload []byte, at time.Time) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, computeSignature(secret, ts, payload))
+}
+
+func VerifySignature(secret []byte, payload []byte, header string, tolerance time.Duration) error {
+	if len(secret) == 0 {
+		return errors.New("no webhook secret configured")
+	}
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sig = v
+		}
+	}
+	if ts == "" || sig == "" {
+		return errors.New("malformed signature header")
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("malformed signature timestamp")
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return errors.New("signature timestamp outside tolerance")
+	}
+	expected, err := hex.DecodeString(computeSignature(secret, ts, payload))
+	if err != nil {
+		return err
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil || !hmac.Equal(expected, got) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func computeSignature(secret []byte, ts string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,82 @@
+package billing
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"goflix/config"
+	"goflix/models"
+	"goflix/utils"
+)
+
+// Fake is an in-process PaymentProvider: subscriptions always succeed and
+// webhook events are produced on demand with Emit.
+type Fake struct {
+	secret []byte
+	mu     sync.Mutex
+	subs   map[string]bool
+}
+
+func NewFake(secret string) *Fake {
+	return &Fake{secret: []byte(secret), subs: map[string]bool{}}
+}
+
+func (f *Fake) CreateSubscription(user *models.User, plan *models.Plan) (string, error) {
+	token, err := utils.RandomToken(8)
+	if err != nil {
+		return "", err
+	}
+	ref := "fake_sub_" + token
+	f.mu.Lock()
+	f.subs[ref] = true
+	f.mu.Unlock()
+	return ref, nil
+}
+
+func (f *Fake) CancelSubscription(ref string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.subs[ref] {
+		return errors.New("unknown subscription")
+	}
+	delete(f.subs, ref)
+	return nil
+}
+
+func (f *Fake) ChangePlan(ref string, plan *models.Plan) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.subs[ref] {
+		return errors.New("unknown subscription")
+	}
+	return nil
+}
+
+func (f *Fake) VerifyWebhook(payload []byte, signature string) (*Event, error) {
+	err := VerifySignature(f.secret, payload, signature, config.BILLING_WEBHOOK_TOLERANCE)
+	if err != nil {
+		return nil, err
+	}
+	var event Event
+	err = json.Unmarshal(payload, &event)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Emit returns a signed webhook payload as the provider would send it.
+func (f *Fake) Emit(eventType string, ref string) ([]byte, string, error) {
+	id, err := utils.RandomToken(8)
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now().UTC()
+	payload, err := json.Marshal(Event{Id: "evt_" + id, Type: eventType, ProviderRef: ref, Created: now})
+	if err != nil {
+		return nil, "", err
+	}
+	return payload, Sign(f.secret, payload, now), nil
+}
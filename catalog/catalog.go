@@ -0,0 +1,258 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"goflix/config"
+	"goflix/db"
+	"goflix/models"
+	"goflix/utils"
+)
+
+// Import reads a catalog file and upserts its titles, see db.Storage.ImportMovies for atomic and dryRun.
+func Import(ctx context.Context, store db.Storage, format string, r io.Reader, atomic bool, dryRun bool) (*models.ImportReport, error) {
+	rows, err := Read(format, r)
+	if err != nil {
+		return nil, err
+	}
+	report := models.ImportReport{Format: format, DryRun: dryRun, Atomic: atomic, Rows: rows}
+	report.Committed, err = store.ImportMovies(ctx, rows, atomic, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		switch row.Action {
+		case config.IMPORT_CREATED:
+			report.Created++
+		case config.IMPORT_UPDATED:
+			report.Updated++
+		case config.IMPORT_UNCHANGED:
+			report.Unchanged++
+		case config.IMPORT_FAILED:
+			report.Failed++
+		}
+	}
+	return &report, nil
+}
+
+// Export writes every title of the catalog, whatever its status.
+func Export(ctx context.Context, store db.Storage, format string, w io.Writer) error {
+	movies, err := store.GetCatalog(ctx)
+	if err != nil {
+		return err
+	}
+	return Write(format, w, movies)
+}
+
+// Read parses a catalog file. Rows that cannot be read or validated are returned with their
+// error, the error returned is for files that cannot be read at all and wraps db.ErrInvalid.
+func Read(format string, r io.Reader) ([]*models.ImportRow, error) {
+	var rows []*models.ImportRow
+	var err error
+	switch format {
+	case config.CATALOG_FORMAT_CSV:
+		rows, err = readCSV(r)
+	case config.CATALOG_FORMAT_JSONL:
+		rows, err = readJSONL(r)
+	default:
+		err = fmt.Errorf("unsupported format %s", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", db.ErrInvalid, err)
+	}
+	for _, row := range rows {
+		if row.Error == "" {
+			err = Validate(row.Movie)
+			if err != nil {
+				row.Error = err.Error()
+			}
+		}
+		if row.Movie != nil {
+			row.ExternalId = row.Movie.ExternalId
+		}
+	}
+	return rows, nil
+}
+
+func Write(format string, w io.Writer, movies []*models.Movies) error {
+	switch format {
+	case config.CATALOG_FORMAT_CSV:
+		return writeCSV(w, movies)
+	case config.CATALOG_FORMAT_JSONL:
+		enc := json.NewEncoder(w)
+		for _, movie := range movies {
+			err := enc.Encode(movie)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported format %s", format)
+}
+
+// Validate normalizes an imported title and computes its maturity level.
+func Validate(movie *models.Movies) error {
+	movie.ExternalId = strings.TrimSpace(movie.ExternalId)
+	movie.Title = strings.TrimSpace(movie.Title)
+	if movie.ExternalId == "" && movie.Id == 0 {
+		return errors.New("missing externalid")
+	}
+	if movie.Title == "" {
+		return errors.New("missing title")
+	}
+	if movie.Saison < 0 || movie.Episode < 0 {
+		return errors.New("saison and episode must be positive")
+	}
+	level, ok := config.TitleMaturityLevel(movie.Maturity)
+	if !ok {
+		return fmt.Errorf("unknown maturity rating %s", movie.Maturity)
+	}
+	movie.MaturityLevel = level
+	if movie.Status != "" && !utils.Contains(config.EDITORIAL_STATUSES, movie.Status) {
+		return fmt.Errorf("unknown status %s", movie.Status)
+	}
+	if movie.Status == config.STATUS_SCHEDULED {
+		return errors.New("titles cannot be imported as scheduled, use the editorial workflow")
+	}
+	return nil
+}
+
+func readCSV(r io.Reader) ([]*models.ImportRow, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !utils.Contains(config.CATALOG_COLUMNS, name) {
+			return nil, fmt.Errorf("unknown column %s", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errors.New("missing title column")
+	}
+
+	var rows []*models.ImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		// a malformed row is reported and the next ones still read, FieldPos is only valid after a successful Read
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, &models.ImportRow{Line: parseErr.Line, Error: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		row := models.ImportRow{Line: line}
+		rows = append(rows, &row)
+		row.Movie, err = csvMovie(columns, record)
+		if err != nil {
+			row.Error = err.Error()
+		}
+	}
+	return rows, nil
+}
+
+func csvMovie(columns map[string]int, record []string) (*models.Movies, error) {
+	get := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return record[i]
+		}
+		return ""
+	}
+	number := func(name string) (int, error) {
+		value := strings.TrimSpace(get(name))
+		if value == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q", name, value)
+		}
+		return n, nil
+	}
+	movie := models.Movies{
+		ExternalId: get("externalid"),
+		Title:      get("title"),
+		Actors:     get("actors"),
+		Details:    get("details"),
+		Genre:      get("genre"),
+		Maturity:   get("maturity"),
+		Status:     get("status"),
+		Poster:     get("poster"),
+	}
+	var err error
+	for name, dest := range map[string]*int{"id": &movie.Id, "rating": &movie.Rating, "saison": &movie.Saison, "episode": &movie.Episode} {
+		*dest, err = number(name)
+		if err != nil {
+			return &movie, err
+		}
+	}
+	return &movie, nil
+}
+
+func readJSONL(r io.Reader) ([]*models.ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), config.CATALOG_IMPORT_MAX_MB<<20)
+	var rows []*models.ImportRow
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		row := models.ImportRow{Line: line, Movie: &models.Movies{}}
+		err := json.Unmarshal(data, row.Movie)
+		if err != nil {
+			row.Error = err.Error()
+		}
+		rows = append(rows, &row)
+	}
+	return rows, scanner.Err()
+}
+
+func writeCSV(w io.Writer, movies []*models.Movies) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(config.CATALOG_COLUMNS)
+	if err != nil {
+		return err
+	}
+	for _, movie := range movies {
+		err = writer.Write([]string{
+			strconv.Itoa(movie.Id),
+			movie.ExternalId,
+			movie.Title,
+			movie.Actors,
+			strconv.Itoa(movie.Rating),
+			movie.Details,
+			movie.Genre,
+			strconv.Itoa(movie.Saison),
+			strconv.Itoa(movie.Episode),
+			movie.Maturity,
+			movie.Status,
+			movie.Poster,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,41 @@
+package catalog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadCSVMalformedRow(t *testing.T) {
+	input := "title,externalid,rating\n" +
+		"first,tt1,1\n" +
+		"x\"y,tt2,1\n" +
+		"second,tt3,2\n"
+	rows, err := Read("csv", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+	want := []struct {
+		line  int
+		title string
+		error bool
+	}{
+		{2, "first", false},
+		{3, "", true},
+		{4, "second", false},
+	}
+	for i, w := range want {
+		row := rows[i]
+		if row.Line != w.line {
+			t.Errorf("row %d: line %d, want %d", i, row.Line, w.line)
+		}
+		if (row.Error != "") != w.error {
+			t.Errorf("row %d: error %q, want error %v", i, row.Error, w.error)
+		}
+		if !w.error && (row.Movie == nil || row.Movie.Title != w.title) {
+			t.Errorf("row %d: movie %+v, want title %q", i, row.Movie, w.title)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"goflix/bench"
+	"goflix/catalog"
+	"goflix/config"
+	"goflix/db"
+	"goflix/library"
+	"goflix/webhooks"
+)
+
+var errUsage = errors.New("wrong arguments")
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, store db.Storage, args []string) error
+}
+
+var commands = map[string]command{
+	"import": {"import [-format csv|jsonl] [-dry-run] [-atomic] FILE", runImport},
+	"export": {"export [-format csv|jsonl] FILE", runExport},
+	"scan":   {"scan [-full] [DIR...]", runScan},
+	"bench":  {"bench -user USER -password PASSWORD [-url URL] [-c CLIENTS] [-d DURATION]", runBench},
+}
+
+// Run executes the command line tool, args starts with the command name.
+func Run(ctx context.Context, store db.Storage, args []string) error {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %s\n%s", args[0], Usage())
+	}
+	err := cmd.run(ctx, store, args[1:])
+	if errors.Is(err, errUsage) {
+		return fmt.Errorf("usage: goflix %s", cmd.usage)
+	}
+	return err
+}
+
+func Usage() string {
+	usage := "usage: goflix [command]\n\nWithout command the API is started. Commands:\n"
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		usage += "  goflix " + commands[name].usage + "\n"
+	}
+	return usage
+}
+
+func runImport(ctx context.Context, store db.Storage, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", config.CATALOG_FORMAT_CSV, "file format, csv or jsonl")
+	dryRun := flags.Bool("dry-run", false, "validate and report without saving")
+	atomic := flags.Bool("atomic", false, "save nothing if a row fails")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	report, err := catalog.Import(ctx, store, *format, f, *atomic, *dryRun)
+	if err != nil {
+		return err
+	}
+	// the server sends the events at its next webhooks run
+	_, err = webhooks.NewDispatcher(store).MoviesCreated(ctx, report.CreatedIds())
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(report)
+	if err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d rows failed", report.Failed)
+	}
+	return nil
+}
+
+func runExport(ctx context.Context, store db.Storage, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", config.CATALOG_FORMAT_CSV, "file format, csv or jsonl")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	// stdout is not an option, the storage setup already writes there
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+	f, err := os.Create(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = catalog.Export(ctx, store, *format, f)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func runScan(ctx context.Context, store db.Storage, args []string) error {
+	flags := flag.NewFlagSet("scan", flag.ContinueOnError)
+	full := flags.Bool("full", false, "read every file again, even unchanged ones")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	dirs := config.LIBRARY_DIRS
+	if flags.NArg() > 0 {
+		dirs = flags.Args()
+	}
+	report, err := library.Scan(ctx, store, dirs, *full)
+	if err != nil {
+		return err
+	}
+	// the server sends the events at its next webhooks run
+	_, err = webhooks.NewDispatcher(store).MoviesCreated(ctx, report.CreatedIds())
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(report)
+	if err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d files failed", report.Failed)
+	}
+	return nil
+}
+
+// runBench loads a running API, the storage of the command is not used.
+func runBench(ctx context.Context, store db.Storage, args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	url := flags.String("url", config.BENCH_URL, "address of the API")
+	user := flags.String("user", "", "user logging in, its ratings are overwritten")
+	password := flags.String("password", "", "password of the user")
+	clients := flags.Int("c", config.BENCH_CONCURRENCY, "concurrent clients")
+	duration := flags.Duration("d", config.BENCH_DURATION, "duration of each scenario")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 0 || *user == "" {
+		return errUsage
+	}
+	report, err := bench.Run(ctx, bench.Options{
+		URL: *url, User: *user, Password: *password, Concurrency: *clients, Duration: *duration,
+	})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
@@ -0,0 +1,33 @@
+package config
+
+import "time"
+
+const (
+	EVENT_PLAY     = "play"
+	EVENT_FAVORITE = "favorite"
+	EVENT_RATING   = "rating"
+)
+
+// EVENT_WEIGHTS is what each kind of event adds to the popularity of a title.
+var EVENT_WEIGHTS = map[string]float64{
+	EVENT_PLAY:     3,
+	EVENT_FAVORITE: 2,
+	EVENT_RATING:   1,
+}
+
+const (
+	BOARD_TRENDING = "trending"
+	BOARD_TOP10    = "top10"
+)
+
+const (
+	// TRENDING_WINDOW is how far back events count for trending, with a weight halved every TRENDING_HALF_LIFE.
+	TRENDING_WINDOW    = 7 * 24 * time.Hour
+	TRENDING_HALF_LIFE = 24 * time.Hour
+	TRENDING_LIMIT     = 20
+	// TOP10_WINDOW is the day the top 10 is made of, events count without decay.
+	TOP10_WINDOW = 24 * time.Hour
+	TOP10_SIZE   = 10
+)
+
+const ACTIVITY_AGGREGATE_INTERVAL = 10 * time.Minute
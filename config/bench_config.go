@@ -0,0 +1,11 @@
+package config
+
+import "time"
+
+// defaults of goflix bench, which loads a running API
+const (
+	BENCH_URL         = "http://localhost:4123"
+	BENCH_CONCURRENCY = 16
+	BENCH_DURATION    = 10 * time.Second
+	BENCH_TIMEOUT     = 30 * time.Second
+)
@@ -0,0 +1,17 @@
+package config
+
+import "time"
+
+const (
+	// the provider signs its events with this secret, without it they are not received
+	BILLING_WEBHOOK_SECRET_ENV = "BILLING_WEBHOOK_SECRET"
+	BILLING_WEBHOOK_TOLERANCE  = 5 * time.Minute
+	BILLING_PERIOD_MONTHS      = 1
+)
+
+const (
+	SUBSCRIPTION_TRIALING = "trialing"
+	SUBSCRIPTION_ACTIVE   = "active"
+	SUBSCRIPTION_PAST_DUE = "past_due"
+	SUBSCRIPTION_CANCELED = "canceled"
+)
@@ -0,0 +1,21 @@
+package config
+
+const (
+	CATALOG_FORMAT_CSV   = "csv"
+	CATALOG_FORMAT_JSONL = "jsonl"
+)
+
+var CATALOG_FORMATS = []string{CATALOG_FORMAT_CSV, CATALOG_FORMAT_JSONL}
+
+// CATALOG_COLUMNS is the header of CSV exports, imports accept them in any order.
+var CATALOG_COLUMNS = []string{"id", "externalid", "title", "actors", "rating", "details", "genre", "saison", "episode", "maturity", "status", "poster"}
+
+const (
+	IMPORT_CREATED   = "created"
+	IMPORT_UPDATED   = "updated"
+	IMPORT_UNCHANGED = "unchanged"
+	IMPORT_FAILED    = "failed"
+)
+
+// CATALOG_IMPORT_MAX_MB bounds the size of files sent to the import endpoint.
+const CATALOG_IMPORT_MAX_MB = 20
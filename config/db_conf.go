@@ -1,6 +1,25 @@
 package config
 
+import "time"
+
 const (
-	DRIVE_NAME       = "sqlite3"
-	DATA_SOURCE_NAME = "./sqlite3.db"
+	DRIVE_NAME = "sqlite3"
+	// the WAL journal lets readers run while a write is in progress, only writers wait for each
+	// other. Transactions take the write lock when they begin so that a read-modify-write never
+	// has to upgrade its lock, concurrent writers wait for it up to the busy timeout (ms)
+	DATA_SOURCE_NAME = "./sqlite3.db?_journal_mode=WAL&_synchronous=NORMAL&_txlock=immediate&_busy_timeout=5000"
+
+	// connections are kept open, each one holds its own copy of the prepared statements and
+	// SQLite cache
+	DB_MAX_OPEN_CONNS     = 8
+	DB_MAX_IDLE_CONNS     = 8
+	DB_CONN_MAX_IDLE_TIME = 30 * time.Minute
+
+	// a storage call is cancelled after DB_QUERY_TIMEOUT, or DB_BATCH_TIMEOUT for those walking
+	// whole tables (imports, exports, recommendations, activity), both can be overridden by the
+	// environment with a duration such as "10s"
+	DB_QUERY_TIMEOUT     = 5 * time.Second
+	DB_BATCH_TIMEOUT     = 2 * time.Minute
+	DB_QUERY_TIMEOUT_ENV = "DB_QUERY_TIMEOUT"
+	DB_BATCH_TIMEOUT_ENV = "DB_BATCH_TIMEOUT"
 )
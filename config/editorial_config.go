@@ -0,0 +1,42 @@
+package config
+
+import "time"
+
+const (
+	STATUS_DRAFT     = "draft"
+	STATUS_IN_REVIEW = "in_review"
+	STATUS_SCHEDULED = "scheduled"
+	STATUS_PUBLISHED = "published"
+	STATUS_ARCHIVED  = "archived"
+)
+
+var EDITORIAL_STATUSES = []string{STATUS_DRAFT, STATUS_IN_REVIEW, STATUS_SCHEDULED, STATUS_PUBLISHED, STATUS_ARCHIVED}
+
+const (
+	ROLE_ADMIN  = "admin"
+	ROLE_EDITOR = "editor"
+)
+
+const PUBLISH_SCHEDULER_INTERVAL = 30 * time.Second
+
+// EDITORIAL_TRANSITIONS gives, for each status, the reachable statuses and the accounts allowed to move there.
+var EDITORIAL_TRANSITIONS = map[string]map[string][]string{
+	STATUS_DRAFT: {
+		STATUS_IN_REVIEW: {ROLE_EDITOR, ROLE_ADMIN},
+	},
+	STATUS_IN_REVIEW: {
+		STATUS_DRAFT:     {ROLE_EDITOR, ROLE_ADMIN},
+		STATUS_SCHEDULED: {ROLE_ADMIN},
+		STATUS_PUBLISHED: {ROLE_ADMIN},
+	},
+	STATUS_SCHEDULED: {
+		STATUS_DRAFT:     {ROLE_ADMIN},
+		STATUS_PUBLISHED: {ROLE_ADMIN},
+	},
+	STATUS_PUBLISHED: {
+		STATUS_ARCHIVED: {ROLE_ADMIN},
+	},
+	STATUS_ARCHIVED: {
+		STATUS_DRAFT: {ROLE_ADMIN},
+	},
+}
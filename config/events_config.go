@@ -0,0 +1,22 @@
+package config
+
+import "time"
+
+// Live events pushed to the connected clients of a user.
+const (
+	LIVE_NEW_EPISODE   = "new_episode"
+	LIVE_LIST_CHANGED  = "list_changed"
+	LIVE_STREAM_KICKED = "stream_kicked"
+)
+
+const (
+	// LIVE_BUFFER is how many events wait for a slow client before new ones are dropped.
+	LIVE_BUFFER = 32
+	// LIVE_KEEPALIVE keeps idle connections open through proxies.
+	LIVE_KEEPALIVE = 25 * time.Second
+	// DEVICE_HEADER identifies the device of a request, live events carry the device that
+	// caused them so that it can ignore its own changes.
+	DEVICE_HEADER = "X-Device-Id"
+	// LIVE_TICKET_TTL is how long a ticket opening the event stream can wait to be used.
+	LIVE_TICKET_TTL = 30 * time.Second
+)
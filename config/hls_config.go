@@ -0,0 +1,38 @@
+package config
+
+const (
+	HLS_MEDIA_URL   = "/media"
+	SUBTITLE_MAX_MB = 2
+)
+
+const (
+	AUDIO_ROLE_MAIN        = "main"
+	AUDIO_ROLE_DUB         = "dub"
+	AUDIO_ROLE_COMMENTARY  = "commentary"
+	AUDIO_ROLE_DESCRIPTION = "description"
+)
+
+var AUDIO_ROLES = []string{AUDIO_ROLE_MAIN, AUDIO_ROLE_DUB, AUDIO_ROLE_COMMENTARY, AUDIO_ROLE_DESCRIPTION}
+
+type Rendition struct {
+	Name       string
+	Bandwidth  int
+	Resolution string
+}
+
+var HLS_RENDITIONS = []Rendition{
+	{Name: "480p", Bandwidth: 1400000, Resolution: "854x480"},
+	{Name: "720p", Bandwidth: 2800000, Resolution: "1280x720"},
+	{Name: "1080p", Bandwidth: 5000000, Resolution: "1920x1080"},
+	{Name: "2160p", Bandwidth: 15000000, Resolution: "3840x2160"},
+}
+
+// RenditionsUpTo keeps the renditions up to maxQuality, all of them when maxQuality is empty or unknown.
+func RenditionsUpTo(maxQuality string) []Rendition {
+	for i, rendition := range HLS_RENDITIONS {
+		if rendition.Name == maxQuality {
+			return HLS_RENDITIONS[:i+1]
+		}
+	}
+	return HLS_RENDITIONS
+}
@@ -0,0 +1,15 @@
+package config
+
+const (
+	ROW_CONTINUE_WATCHING = "continue_watching"
+	ROW_MY_LIST           = "my_list"
+	ROW_RECOMMENDED       = "recommended"
+	ROW_TRENDING          = "trending"
+	ROW_TOP10             = "top10"
+	ROW_NEW_RELEASES      = "new_releases"
+	ROW_GENRE             = "genre"
+)
+
+var ROW_KINDS = []string{ROW_CONTINUE_WATCHING, ROW_MY_LIST, ROW_RECOMMENDED, ROW_TRENDING, ROW_TOP10, ROW_NEW_RELEASES, ROW_GENRE}
+
+const HOME_ROW_DEFAULT_SIZE = 20
@@ -0,0 +1,16 @@
+package config
+
+import "time"
+
+// LIBRARY_DIRS are the media roots scanned for titles, each one holding a Movies folder laid
+// out as "Title (Year)/" and a Shows folder laid out as "Name/Season NN/".
+var LIBRARY_DIRS = []string{"./media"}
+
+const (
+	LIBRARY_MOVIES_DIR = "Movies"
+	LIBRARY_SHOWS_DIR  = "Shows"
+)
+
+var LIBRARY_VIDEO_EXTENSIONS = []string{".mkv", ".mp4", ".m4v", ".avi", ".mov", ".ts", ".webm"}
+
+const LIBRARY_SCAN_INTERVAL = 30 * time.Minute
@@ -0,0 +1,29 @@
+package config
+
+// List visibilities, link lists are readable by anyone holding their share token.
+const (
+	LIST_PRIVATE = "private"
+	LIST_PUBLIC  = "public"
+	LIST_LINK    = "link"
+)
+
+var LIST_VISIBILITIES = []string{LIST_PRIVATE, LIST_PUBLIC, LIST_LINK}
+
+// List changes told to the other devices of the owner.
+const (
+	LIST_CREATED      = "created"
+	LIST_UPDATED      = "updated"
+	LIST_DELETED      = "deleted"
+	LIST_ITEM_ADDED   = "item_added"
+	LIST_ITEM_REMOVED = "item_removed"
+	LIST_REORDERED    = "reordered"
+)
+
+const (
+	// LIST_DEFAULT_NAME names the list every user has, favorites are its titles.
+	LIST_DEFAULT_NAME     = "Ma liste"
+	LIST_NAME_MAX_LENGTH  = 60
+	LIST_MAX_PER_USER     = 50
+	LIST_MAX_ITEMS        = 500
+	LIST_SHARE_TOKEN_SIZE = 16
+)
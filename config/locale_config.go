@@ -0,0 +1,5 @@
+package config
+
+// DEFAULT_LOCALE is the language of the viewers without preference, and of the API messages
+// when none of their languages has a catalog.
+const DEFAULT_LOCALE = "en"
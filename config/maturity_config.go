@@ -0,0 +1,68 @@
+package config
+
+import "time"
+
+const MATURITY_DEFAULT_REGION = "US"
+
+// MATURITY_SCALES lists the ratings of each region from the least to the most restrictive,
+// the position of a rating is its level and levels are comparable across regions.
+var MATURITY_SCALES = map[string][]string{
+	"US": {"G", "PG", "PG-13", "R", "NC-17"},
+	"UK": {"U", "PG", "12A", "15", "18"},
+	"FR": {"TP", "10", "12", "16", "18"},
+}
+
+// MATURITY_UNRATED_LEVEL is given to titles without rating so that restricted profiles don't see them.
+const MATURITY_UNRATED_LEVEL = 4
+
+// PIN_MIN_DIGITS is the shortest parental control PIN accepted.
+const PIN_MIN_DIGITS = 4
+
+// PIN_MAX_ATTEMPTS wrong PINs in a row lock the parental controls of the user for PIN_LOCKOUT.
+const PIN_MAX_ATTEMPTS = 5
+const PIN_LOCKOUT = 15 * time.Minute
+
+// TitleMaturityLevel is the level stored with a title, unrated titles get MATURITY_UNRATED_LEVEL.
+func TitleMaturityLevel(rating string) (int, bool) {
+	if rating == "" {
+		return MATURITY_UNRATED_LEVEL, true
+	}
+	return MaturityLevel(MATURITY_DEFAULT_REGION, rating)
+}
+
+// MaturityLevel finds the level of a rating, looking at the given region first.
+func MaturityLevel(region string, rating string) (int, bool) {
+	if level, ok := indexOf(MATURITY_SCALES[region], rating); ok {
+		return level, true
+	}
+	if level, ok := indexOf(MATURITY_SCALES[MATURITY_DEFAULT_REGION], rating); ok {
+		return level, true
+	}
+	for _, scale := range MATURITY_SCALES {
+		if level, ok := indexOf(scale, rating); ok {
+			return level, true
+		}
+	}
+	return 0, false
+}
+
+// MaturityRating gives the rating of a level in a region's scale.
+func MaturityRating(region string, level int) string {
+	scale, ok := MATURITY_SCALES[region]
+	if !ok {
+		scale = MATURITY_SCALES[MATURITY_DEFAULT_REGION]
+	}
+	if level < 0 || level >= len(scale) {
+		return ""
+	}
+	return scale[level]
+}
+
+func indexOf(list []string, value string) (int, bool) {
+	for i, v := range list {
+		if v == value {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,15 @@
+package config
+
+import "time"
+
+const (
+	TMDB_API_URL     = "https://api.themoviedb.org/3"
+	TMDB_IMAGE_URL   = "https://image.tmdb.org/t/p/w500"
+	TMDB_API_KEY_ENV = "TMDB_API_KEY"
+	TMDB_LANGUAGE    = "fr-FR"
+)
+
+const METADATA_TIMEOUT = 10 * time.Second
+
+// METADATA_CAST_LIMIT is the number of actors kept from a provider cast.
+const METADATA_CAST_LIMIT = 10
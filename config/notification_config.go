@@ -0,0 +1,37 @@
+package config
+
+import "time"
+
+// Notification kinds, a user is notified once per kind and title.
+const (
+	NOTIFY_NEW_EPISODE  = "new_episode"
+	NOTIFY_LEAVING_SOON = "leaving_soon"
+)
+
+var NOTIFY_KINDS = []string{NOTIFY_NEW_EPISODE, NOTIFY_LEAVING_SOON}
+
+// Notification channels, email notifications are sent grouped in a digest.
+const (
+	CHANNEL_IN_APP = "in_app"
+	CHANNEL_EMAIL  = "email"
+)
+
+var NOTIFY_CHANNELS = []string{CHANNEL_IN_APP, CHANNEL_EMAIL}
+
+// NOTIFY_DEFAULTS are the channels of a kind a user didn't set a preference for.
+var NOTIFY_DEFAULTS = map[string]bool{CHANNEL_IN_APP: true, CHANNEL_EMAIL: true}
+
+const (
+	// NOTIFY_LEAVING_SOON_DAYS is how long before a title leaves the users having it in their list hear of it.
+	NOTIFY_LEAVING_SOON_DAYS     = 7
+	NOTIFY_LEAVING_SOON_INTERVAL = 6 * time.Hour
+	NOTIFY_DIGEST_INTERVAL       = 24 * time.Hour
+)
+
+// Digest emails are sent through SMTP_ADDR when set, logged otherwise.
+const (
+	SMTP_ADDR_ENV     = "SMTP_ADDR"
+	SMTP_USER_ENV     = "SMTP_USER"
+	SMTP_PASSWORD_ENV = "SMTP_PASSWORD"
+	MAIL_FROM         = "Goflix <noreply@goflix.local>"
+)
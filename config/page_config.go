@@ -0,0 +1,6 @@
+package config
+
+const (
+	PAGE_DEFAULT_LIMIT = 20
+	PAGE_MAX_LIMIT     = 100
+)
@@ -0,0 +1,12 @@
+package config
+
+const (
+	CREDIT_ACTOR    = "actor"
+	CREDIT_DIRECTOR = "director"
+	CREDIT_WRITER   = "writer"
+)
+
+var CREDIT_ROLES = []string{CREDIT_ACTOR, CREDIT_DIRECTOR, CREDIT_WRITER}
+
+// LIST_SEPARATORS split the legacy free-text actors and genre columns into names.
+var LIST_SEPARATORS = []string{",", ";", "/", "|"}
@@ -0,0 +1,53 @@
+package config
+
+import "time"
+
+const (
+	RATING_MIN_STARS = 1
+	RATING_MAX_STARS = 5
+)
+
+const (
+	SIMILARITY_RATINGS = "ratings"
+	SIMILARITY_CONTENT = "content"
+	// SIMILARITY_FAVORITES pairs the titles favorited by the same users, for "more like this"
+	SIMILARITY_FAVORITES = "favorites"
+)
+
+// Reasons given with each recommendation.
+const (
+	REASON_RATED   = "rated"
+	REASON_SIMILAR = "similar"
+	REASON_POPULAR = "popular"
+)
+
+const (
+	// RECOMMENDATION_NEIGHBORS is the number of similar titles kept per title by the model.
+	RECOMMENDATION_NEIGHBORS = 30
+	// RECOMMENDATION_MIN_CORATERS is the number of users two titles need in common to be compared.
+	RECOMMENDATION_MIN_CORATERS = 2
+	// RECOMMENDATION_LIKED_STARS is the rating from which a title seeds content recommendations.
+	RECOMMENDATION_LIKED_STARS = 4
+	// RECOMMENDATION_MIN_SCORE is the lowest predicted rating recommended.
+	RECOMMENDATION_MIN_SCORE = 3.5
+	RECOMMENDATION_LIMIT     = 20
+)
+
+const RECOMMENDATION_REBUILD_INTERVAL = 6 * time.Hour
+
+// Weights of the signals of the "more like this" similarity, they add up to 1.
+const (
+	SIMILAR_WEIGHT_CREDITS   = 0.4
+	SIMILAR_WEIGHT_TEXT      = 0.2
+	SIMILAR_WEIGHT_RATINGS   = 0.25
+	SIMILAR_WEIGHT_FAVORITES = 0.15
+)
+
+const (
+	SIMILAR_LIMIT = 12
+	// SIMILAR_CACHE_SIZE is the number of similar titles cached per title, before the viewer filter.
+	SIMILAR_CACHE_SIZE = 50
+	// SIMILAR_CACHE_TTL bounds the age of cached results, ratings and favorites don't invalidate them,
+	// their models follow RECOMMENDATION_REBUILD_INTERVAL.
+	SIMILAR_CACHE_TTL = time.Hour
+)
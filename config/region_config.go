@@ -0,0 +1,13 @@
+package config
+
+const (
+	DEFAULT_REGION        = "FR"
+	GEOIP_DB_PATH         = "./geoip.csv"
+	ALL_REGIONS           = "*"
+	LEAVING_SOON_DAYS     = 30
+	LEAVING_SOON_MAX_DAYS = 365
+)
+
+// TRUSTED_PROXIES_ENV lists, comma separated, the proxies whose X-Forwarded-For gives the client
+// address used by GeoIP. None by default: the address is the one of the connection.
+const TRUSTED_PROXIES_ENV = "TRUSTED_PROXIES"
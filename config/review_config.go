@@ -0,0 +1,59 @@
+package config
+
+const (
+	REVIEW_PENDING  = "pending"
+	REVIEW_APPROVED = "approved"
+	REVIEW_REJECTED = "rejected"
+	REVIEW_HIDDEN   = "hidden"
+)
+
+var REVIEW_STATUSES = []string{REVIEW_PENDING, REVIEW_APPROVED, REVIEW_REJECTED, REVIEW_HIDDEN}
+
+const (
+	REVIEW_APPROVE = "approve"
+	REVIEW_REJECT  = "reject"
+	REVIEW_HIDE    = "hide"
+)
+
+var REVIEW_ACTIONS = []string{REVIEW_APPROVE, REVIEW_REJECT, REVIEW_HIDE}
+
+// REVIEW_ACTION_STATUS is the status each moderation action gives a review.
+var REVIEW_ACTION_STATUS = map[string]string{
+	REVIEW_APPROVE: REVIEW_APPROVED,
+	REVIEW_REJECT:  REVIEW_REJECTED,
+	REVIEW_HIDE:    REVIEW_HIDDEN,
+}
+
+// Why a review waits in the moderation queue.
+const (
+	REVIEW_FLAG_PROFANITY = "profanity"
+	REVIEW_FLAG_REPORTED  = "reported"
+	REVIEW_FLAG_EDITED    = "edited"
+)
+
+const (
+	REVIEW_SORT_RECENT     = "recent"
+	REVIEW_SORT_OLDEST     = "oldest"
+	REVIEW_SORT_HELPFUL    = "helpful"
+	REVIEW_SORT_STARS_DESC = "stars_desc"
+	REVIEW_SORT_STARS_ASC  = "stars_asc"
+)
+
+var REVIEW_SORTS = []string{REVIEW_SORT_RECENT, REVIEW_SORT_OLDEST, REVIEW_SORT_HELPFUL, REVIEW_SORT_STARS_DESC, REVIEW_SORT_STARS_ASC}
+
+const (
+	REVIEW_MAX_LENGTH = 5000
+	// REVIEW_PREMODERATION sends every review to the queue, otherwise only flagged ones wait.
+	REVIEW_PREMODERATION = false
+	// REVIEW_REPORTS_TO_QUEUE reports since the last moderation take a review back to the queue.
+	REVIEW_REPORTS_TO_QUEUE = 3
+)
+
+// PROFANITY_WORDS_PATH lists banned words or phrases, one per line, PROFANITY_WORDS is used
+// when the file is missing.
+const PROFANITY_WORDS_PATH = "./profanity.txt"
+
+var PROFANITY_WORDS = []string{
+	"merde", "putain", "connard", "connasse", "salope", "encule", "batard", "ta gueule",
+	"fuck", "fucking", "shit", "bitch", "asshole", "bastard", "cunt", "motherfucker",
+}
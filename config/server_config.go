@@ -0,0 +1,8 @@
+package config
+
+import "time"
+
+const SERVER_ADDR = ":4123"
+
+// SHUTDOWN_TIMEOUT is how long the requests in flight and the running jobs get to finish on SIGINT or SIGTERM.
+const SHUTDOWN_TIMEOUT = 10 * time.Second
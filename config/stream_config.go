@@ -0,0 +1,11 @@
+package config
+
+import "time"
+
+const STREAM_HEARTBEAT_TIMEOUT = 90 * time.Second
+
+const (
+	STREAM_END_STOPPED = "stopped"
+	STREAM_END_TIMEOUT = "timeout"
+	STREAM_END_KILLED  = "killed"
+)
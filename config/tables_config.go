@@ -34,8 +34,331 @@ const CREATE_TABLE_FAVORITE = `
 `
 const CREATE_TABLE_RATING = `
 CREATE TABLE IF NOT EXISTS rating (
-	movieid INTEGER PRIMARY KEY ,
+	movieid INTEGER,
 	stars INTEGER,
-	userid INTEGER
+	userid INTEGER,
+	PRIMARY KEY (userid, movieid)
+);
+`
+const CREATE_TABLE_SUBTITLES = `
+CREATE TABLE IF NOT EXISTS subtitles (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	movieid INTEGER,
+	lang TEXT,
+	label TEXT,
+	forced INTEGER,
+	content TEXT
+);
+`
+const CREATE_TABLE_AUDIO_TRACKS = `
+CREATE TABLE IF NOT EXISTS audiotracks (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	movieid INTEGER,
+	lang TEXT,
+	label TEXT,
+	role TEXT,
+	isdefault INTEGER
+);
+`
+const CREATE_TABLE_STREAM_SESSIONS = `
+CREATE TABLE IF NOT EXISTS streamsessions (
+	id TEXT PRIMARY KEY,
+	userid INTEGER,
+	movieid INTEGER,
+	device TEXT,
+	started DATETIME,
+	heartbeat DATETIME,
+	ended DATETIME,
+	endreason TEXT
+);
+`
+const CREATE_TABLE_PLANS = `
+CREATE TABLE IF NOT EXISTS plans (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	code TEXT UNIQUE,
+	name TEXT,
+	price INTEGER,
+	currency TEXT,
+	maxstreams INTEGER,
+	maxquality TEXT,
+	trialdays INTEGER
+);
+`
+const SEED_PLANS = `
+INSERT OR IGNORE INTO plans (code, name, price, currency, maxstreams, maxquality, trialdays) VALUES
+	('basic', 'Basic', 799, 'EUR', 1, '720p', 7),
+	('standard', 'Standard', 1349, 'EUR', 2, '1080p', 7),
+	('premium', 'Premium', 1799, 'EUR', 4, '2160p', 0);
+`
+const CREATE_TABLE_SUBSCRIPTIONS = `
+CREATE TABLE IF NOT EXISTS subscriptions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	userid INTEGER,
+	planid INTEGER,
+	status TEXT,
+	providerref TEXT UNIQUE,
+	trialend DATETIME,
+	periodend DATETIME,
+	created DATETIME,
+	updated DATETIME
+);
+`
+const CREATE_TABLE_BILLING_EVENTS = `
+CREATE TABLE IF NOT EXISTS billingevents (
+	id TEXT PRIMARY KEY,
+	type TEXT,
+	providerref TEXT,
+	received DATETIME
+);
+`
+const CREATE_TABLE_MIGRATIONS = `
+CREATE TABLE IF NOT EXISTS migrations (
+	name TEXT PRIMARY KEY,
+	applied DATETIME
+);
+`
+const CREATE_TABLE_PARENTAL_CONTROLS = `
+CREATE TABLE IF NOT EXISTS parentalcontrols (
+	userid INTEGER PRIMARY KEY,
+	maxlevel INTEGER,
+	pin TEXT
+);
+`
+const CREATE_TABLE_AVAILABILITY = `
+CREATE TABLE IF NOT EXISTS availability (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	movieid INTEGER,
+	country TEXT,
+	starts DATETIME,
+	ends DATETIME
+);
+`
+const CREATE_TABLE_USER_SETTINGS = `
+CREATE TABLE IF NOT EXISTS usersettings (
+	userid INTEGER PRIMARY KEY,
+	region TEXT
+);
+`
+const CREATE_TABLE_PEOPLE = `
+CREATE TABLE IF NOT EXISTS people (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT UNIQUE COLLATE NOCASE
+);
+`
+const CREATE_TABLE_CREDITS = `
+CREATE TABLE IF NOT EXISTS credits (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	personid INTEGER,
+	movieid INTEGER,
+	role TEXT,
+	character TEXT,
+	billing INTEGER
+);
+`
+const CREATE_TABLE_GENRES = `
+CREATE TABLE IF NOT EXISTS genres (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	slug TEXT UNIQUE,
+	name TEXT
+);
+`
+const CREATE_TABLE_GENRE_NAMES = `
+CREATE TABLE IF NOT EXISTS genrenames (
+	genreid INTEGER,
+	locale TEXT,
+	name TEXT,
+	PRIMARY KEY (genreid, locale)
+);
+`
+const CREATE_TABLE_MOVIE_GENRES = `
+CREATE TABLE IF NOT EXISTS moviegenres (
+	movieid INTEGER,
+	genreid INTEGER,
+	PRIMARY KEY (movieid, genreid)
+);
+`
+const CREATE_TABLE_MOVIE_TRANSLATIONS = `
+CREATE TABLE IF NOT EXISTS movietranslations (
+	movieid INTEGER,
+	locale TEXT,
+	title TEXT,
+	details TEXT,
+	PRIMARY KEY (movieid, locale)
+);
+`
+
+const CREATE_TABLE_MEDIA_FILES = `
+CREATE TABLE IF NOT EXISTS mediafiles (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	movieid INTEGER,
+	path TEXT UNIQUE,
+	size INTEGER,
+	modtime DATETIME,
+	scannedat DATETIME
+);
+`
+
+const CREATE_TABLE_SIMILARITIES = `
+CREATE TABLE IF NOT EXISTS similarities (
+	kind TEXT,
+	movieid INTEGER,
+	similarid INTEGER,
+	score REAL,
+	PRIMARY KEY (kind, movieid, similarid)
+);
+`
+
+const CREATE_TABLE_CATALOG_VERSION = `
+CREATE TABLE IF NOT EXISTS catalogversion (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	version INTEGER
+);
+`
+
+const CREATE_TABLE_ACTIVITY = `
+CREATE TABLE IF NOT EXISTS activity (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	movieid INTEGER,
+	userid INTEGER,
+	kind TEXT,
+	region TEXT,
+	createdat DATETIME
+);
+`
+
+const CREATE_TABLE_POPULARITY = `
+CREATE TABLE IF NOT EXISTS popularity (
+	board TEXT,
+	region TEXT,
+	movieid INTEGER,
+	score REAL,
+	PRIMARY KEY (board, region, movieid)
+);
+`
+
+const CREATE_TABLE_HOME_ROWS = `
+CREATE TABLE IF NOT EXISTS homerows (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	position INTEGER,
+	kind TEXT,
+	title TEXT,
+	genre TEXT DEFAULT '',
+	size INTEGER,
+	enabled INTEGER DEFAULT 1
+);
+`
+const CREATE_TABLE_LISTS = `
+CREATE TABLE IF NOT EXISTS lists (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	userid INTEGER,
+	name TEXT,
+	visibility TEXT,
+	sharetoken TEXT UNIQUE,
+	isdefault INTEGER,
+	created DATETIME,
+	updated DATETIME,
+	UNIQUE (userid, name)
+);
+`
+const CREATE_TABLE_LIST_ITEMS = `
+CREATE TABLE IF NOT EXISTS listitems (
+	listid INTEGER,
+	movieid INTEGER,
+	position INTEGER,
+	added DATETIME,
+	PRIMARY KEY (listid, movieid)
+);
+`
+const CREATE_TABLE_REVIEWS = `
+CREATE TABLE IF NOT EXISTS reviews (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	movieid INTEGER,
+	userid INTEGER,
+	body TEXT,
+	spoiler INTEGER,
+	status TEXT,
+	flag TEXT DEFAULT '',
+	created DATETIME,
+	updated DATETIME,
+	moderatedby INTEGER DEFAULT 0,
+	moderatedat DATETIME,
+	moderationnote TEXT DEFAULT '',
+	UNIQUE (movieid, userid)
+);
+`
+const CREATE_TABLE_REVIEW_VOTES = `
+CREATE TABLE IF NOT EXISTS reviewvotes (
+	reviewid INTEGER,
+	userid INTEGER,
+	PRIMARY KEY (reviewid, userid)
+);
+`
+const CREATE_TABLE_REVIEW_REPORTS = `
+CREATE TABLE IF NOT EXISTS reviewreports (
+	reviewid INTEGER,
+	userid INTEGER,
+	reason TEXT,
+	created DATETIME,
+	PRIMARY KEY (reviewid, userid)
+);
+`
+const CREATE_TABLE_WEBHOOKS = `
+CREATE TABLE IF NOT EXISTS webhooks (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	url TEXT,
+	secret TEXT,
+	events TEXT,
+	enabled INTEGER,
+	created DATETIME
+);
+`
+const CREATE_TABLE_WEBHOOK_DELIVERIES = `
+CREATE TABLE IF NOT EXISTS webhookdeliveries (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	webhookid INTEGER,
+	eventid TEXT,
+	event TEXT,
+	payload TEXT,
+	status TEXT,
+	attempts INTEGER DEFAULT 0,
+	nextattempt DATETIME,
+	lastattempt DATETIME,
+	responsecode INTEGER DEFAULT 0,
+	lasterror TEXT DEFAULT '',
+	created DATETIME
+);
+`
+const CREATE_TABLE_WEBHOOK_ATTEMPTS = `
+CREATE TABLE IF NOT EXISTS webhookattempts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	deliveryid INTEGER,
+	attempted DATETIME,
+	responsecode INTEGER,
+	error TEXT,
+	duration INTEGER
+);
+`
+const CREATE_TABLE_NOTIFICATIONS = `
+CREATE TABLE IF NOT EXISTS notifications (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	userid INTEGER,
+	kind TEXT,
+	movieid INTEGER,
+	ends DATETIME,
+	inapp INTEGER,
+	email INTEGER,
+	created DATETIME,
+	readat DATETIME,
+	emailedat DATETIME,
+	UNIQUE(userid, kind, movieid)
+);
+`
+const CREATE_TABLE_NOTIFICATION_PREFS = `
+CREATE TABLE IF NOT EXISTS notificationprefs (
+	userid INTEGER,
+	kind TEXT,
+	channel TEXT,
+	enabled INTEGER,
+	PRIMARY KEY (userid, kind, channel)
 );
 `
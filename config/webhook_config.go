@@ -0,0 +1,37 @@
+package config
+
+import "time"
+
+// Outbound webhook event types, "*" subscribes a webhook to all of them.
+const (
+	WEBHOOK_MOVIE_CREATED = "movie.created"
+	WEBHOOK_MOVIE_DELETED = "movie.deleted"
+	WEBHOOK_USER_CREATED  = "user.created"
+	WEBHOOK_RATING_SAVED  = "rating.saved"
+	WEBHOOK_ALL_EVENTS    = "*"
+)
+
+var WEBHOOK_EVENTS = []string{WEBHOOK_MOVIE_CREATED, WEBHOOK_MOVIE_DELETED, WEBHOOK_USER_CREATED, WEBHOOK_RATING_SAVED}
+
+const (
+	DELIVERY_PENDING   = "pending"
+	DELIVERY_SUCCEEDED = "succeeded"
+	DELIVERY_FAILED    = "failed"
+)
+
+var DELIVERY_STATUSES = []string{DELIVERY_PENDING, DELIVERY_SUCCEEDED, DELIVERY_FAILED}
+
+const (
+	WEBHOOK_DELIVERY_INTERVAL = 10 * time.Second
+	WEBHOOK_TIMEOUT           = 10 * time.Second
+	// WEBHOOK_BATCH is how many due deliveries a run sends at most.
+	WEBHOOK_BATCH = 100
+	// WEBHOOK_SECRET_SIZE is the random bytes of a generated signing secret.
+	WEBHOOK_SECRET_SIZE = 24
+	// WEBHOOK_ERROR_MAX_LENGTH bounds what is kept of a failed response body.
+	WEBHOOK_ERROR_MAX_LENGTH = 512
+)
+
+// WEBHOOK_RETRY_DELAYS is the wait before each retry of a failed delivery, a delivery failing
+// once more is given up.
+var WEBHOOK_RETRY_DELAYS = []time.Duration{30 * time.Second, 2 * time.Minute, 10 * time.Minute, time.Hour, 6 * time.Hour}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"goflix/models"
+)
+
+func (db *DbSqlite) AddEvent(ctx context.Context, event *models.Event) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	insertSQL := "INSERT INTO activity (movieid, userid, kind, region, createdat) VALUES (?, ?, ?, ?, ?)"
+	res, err := db.execCached(ctx, insertSQL, event.MovieId, event.UserId, event.Kind, event.Region, event.CreatedAt.UTC())
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	event.Id = int(id)
+	return nil
+}
+
+func (db *DbSqlite) GetEventsSince(ctx context.Context, since time.Time) ([]*models.Event, error) {
+	ctx, cancel := db.batchTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, `SELECT id, movieid, userid, kind, region, createdat FROM activity
+		WHERE createdat >= ? ORDER BY createdat`, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []*models.Event
+	for rows.Next() {
+		event := models.Event{}
+		err = rows.Scan(&event.Id, &event.MovieId, &event.UserId, &event.Kind, &event.Region, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+	return events, rows.Err()
+}
+
+func (db *DbSqlite) DeleteEventsBefore(ctx context.Context, before time.Time) (int, error) {
+	ctx, cancel := db.batchTimeout(ctx)
+	defer cancel()
+	res, err := db.sqlite.ExecContext(ctx, "DELETE FROM activity WHERE createdat < ?", before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// SavePopularity replaces the scores of a board.
+func (db *DbSqlite) SavePopularity(ctx context.Context, board string, scores []*models.Popularity) error {
+	ctx, cancel := db.batchTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, "DELETE FROM popularity WHERE board = ?", board)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO popularity (board, region, movieid, score) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, score := range scores {
+		_, err = stmt.ExecContext(ctx, board, score.Region, score.MovieId, score.Score)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetPopularity returns the best scores of a board in a region, best first.
+func (db *DbSqlite) GetPopularity(ctx context.Context, board string, region string, limit int) ([]*models.Popularity, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, `SELECT movieid, region, score FROM popularity
+		WHERE board = ? AND region = ? ORDER BY score DESC, movieid LIMIT ?`, board, region, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var scores []*models.Popularity
+	for rows.Next() {
+		score := models.Popularity{}
+		err = rows.Scan(&score.MovieId, &score.Region, &score.Score)
+		if err != nil {
+			return nil, err
+		}
+		scores = append(scores, &score)
+	}
+	return scores, rows.Err()
+}
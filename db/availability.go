@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"goflix/config"
+	"goflix/models"
+)
+
+func (db *DbSqlite) AddAvailability(ctx context.Context, window *models.Availability) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	window.Starts = window.Starts.UTC()
+	if window.Ends != nil {
+		ends := window.Ends.UTC()
+		window.Ends = &ends
+	}
+	insertSQL := "INSERT INTO availability (movieid, country, starts, ends) VALUES (?, ?, ?, ?)"
+	res, err := db.sqlite.ExecContext(ctx, insertSQL, window.MovieId, window.Country, window.Starts, window.Ends)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	window.Id = int(id)
+	return nil
+}
+
+func (db *DbSqlite) GetAvailability(ctx context.Context, movieID int) ([]*models.Availability, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT id, movieid, country, starts, ends FROM availability WHERE movieid = ? ORDER BY country, starts", movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var windows []*models.Availability
+	for rows.Next() {
+		window := models.Availability{}
+		var ends sql.NullTime
+		err = rows.Scan(&window.Id, &window.MovieId, &window.Country, &window.Starts, &ends)
+		if err != nil {
+			return nil, err
+		}
+		if ends.Valid {
+			window.Ends = &ends.Time
+		}
+		windows = append(windows, &window)
+	}
+	return windows, rows.Err()
+}
+
+func (db *DbSqlite) DeleteAvailability(ctx context.Context, movieID int, id int) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	result, err := db.sqlite.ExecContext(ctx, "DELETE FROM availability WHERE id = ? AND movieid = ?", id, movieID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected != 1 {
+		return fmt.Errorf("errors want delete 1 reccord got: %d: %w", rowsAffected, ErrNotFound)
+	}
+	return nil
+}
+
+// GetLeavingSoon lists the titles visible through filter whose window in the filter region
+// closes before until, without a later window taking over.
+func (db *DbSqlite) GetLeavingSoon(ctx context.Context, filter *models.CatalogFilter, until time.Time) ([]*models.Movies, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	where, args := catalogWhere(filter)
+	now := time.Now().UTC()
+	query := `SELECT ` + qualifiedMovieColumns + `, MIN(a.ends) FROM movies
+		JOIN availability a ON a.movieid = movies.id
+		WHERE a.country IN (?, ?) AND a.starts <= ? AND a.ends > ? AND a.ends <= ?
+		AND NOT EXISTS (SELECT 1 FROM availability b WHERE b.movieid = movies.id AND b.country IN (?, ?)
+			AND b.id != a.id AND b.starts <= a.ends AND (b.ends IS NULL OR b.ends > a.ends))` + where + `
+		GROUP BY movies.id ORDER BY MIN(a.ends)`
+	params := []any{filter.Region, config.ALL_REGIONS, now, now, until.UTC(), filter.Region, config.ALL_REGIONS}
+	rows, err := db.sqlite.QueryContext(ctx, query, append(params, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var movies []*models.Movies
+	for rows.Next() {
+		var ends string
+		movie, err := scanMovie(rows, &ends)
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := parseTime(ends); ok {
+			movie.AvailableUntil = &t
+		}
+		movies = append(movies, movie)
+	}
+	return movies, rows.Err()
+}
@@ -0,0 +1,180 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"goflix/models"
+)
+
+const planColumns = "id, code, name, price, currency, maxstreams, maxquality, trialdays"
+const subscriptionColumns = "id, userid, planid, status, providerref, trialend, periodend, cancelatperiodend, created, updated"
+
+func (db *DbSqlite) AddPlan(ctx context.Context, plan *models.Plan) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	insertSQL := "INSERT INTO plans (code, name, price, currency, maxstreams, maxquality, trialdays) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	res, err := db.sqlite.ExecContext(ctx, insertSQL,
+		plan.Code, plan.Name, plan.Price, plan.Currency, plan.MaxStreams, plan.MaxQuality, plan.TrialDays)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	plan.Id = int(id)
+	return nil
+}
+
+func (db *DbSqlite) GetPlans(ctx context.Context) ([]*models.Plan, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT "+planColumns+" FROM plans ORDER BY price")
+	if err != nil {
+		return nil, err
+	}
+	return scanPlans(rows)
+}
+
+func (db *DbSqlite) GetPlan(ctx context.Context, id int) (*models.Plan, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT "+planColumns+" FROM plans WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	plans, err := scanPlans(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(plans) == 0 {
+		return nil, fmt.Errorf("plan %w", ErrNotFound)
+	}
+	return plans[0], nil
+}
+
+func (db *DbSqlite) GetPlanByCode(ctx context.Context, code string) (*models.Plan, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT "+planColumns+" FROM plans WHERE code = ?", code)
+	if err != nil {
+		return nil, err
+	}
+	plans, err := scanPlans(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(plans) == 0 {
+		return nil, fmt.Errorf("plan %w", ErrNotFound)
+	}
+	return plans[0], nil
+}
+
+func scanPlans(rows *sql.Rows) ([]*models.Plan, error) {
+	defer rows.Close()
+	var plans []*models.Plan
+	for rows.Next() {
+		plan := models.Plan{}
+		err := rows.Scan(&plan.Id, &plan.Code, &plan.Name, &plan.Price, &plan.Currency,
+			&plan.MaxStreams, &plan.MaxQuality, &plan.TrialDays)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, &plan)
+	}
+	return plans, rows.Err()
+}
+
+// SaveSubscription inserts the subscription when it has no id yet, updates it otherwise.
+func (db *DbSqlite) SaveSubscription(ctx context.Context, sub *models.Subscription) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	sub.Updated = time.Now().UTC()
+	if sub.Id == 0 {
+		sub.Created = sub.Updated
+		insertSQL := "INSERT INTO subscriptions (userid, planid, status, providerref, trialend, periodend, cancelatperiodend, created, updated) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+		res, err := db.sqlite.ExecContext(ctx, insertSQL,
+			sub.UserId, sub.PlanId, sub.Status, sub.ProviderRef, sub.TrialEnd, sub.PeriodEnd, sub.CancelAtPeriodEnd, sub.Created, sub.Updated)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		sub.Id = int(id)
+		return nil
+	}
+	updateSQL := "UPDATE subscriptions SET planid = ?, status = ?, trialend = ?, periodend = ?, cancelatperiodend = ?, updated = ? WHERE id = ?"
+	_, err := db.sqlite.ExecContext(ctx, updateSQL, sub.PlanId, sub.Status, sub.TrialEnd, sub.PeriodEnd, sub.CancelAtPeriodEnd, sub.Updated, sub.Id)
+	return err
+}
+
+// GetSubscriptionByUser returns the latest subscription of a user with its plan.
+func (db *DbSqlite) GetSubscriptionByUser(ctx context.Context, userID int) (*models.Subscription, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.queryCached(ctx, "SELECT "+subscriptionColumns+" FROM subscriptions WHERE userid = ? ORDER BY id DESC LIMIT 1", userID)
+	if err != nil {
+		return nil, err
+	}
+	return db.scanSubscription(ctx, rows)
+}
+
+func (db *DbSqlite) GetSubscriptionByRef(ctx context.Context, ref string) (*models.Subscription, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT "+subscriptionColumns+" FROM subscriptions WHERE providerref = ?", ref)
+	if err != nil {
+		return nil, err
+	}
+	return db.scanSubscription(ctx, rows)
+}
+
+func (db *DbSqlite) scanSubscription(ctx context.Context, rows *sql.Rows) (*models.Subscription, error) {
+	defer rows.Close()
+	var sub models.Subscription
+	if rows.Next() {
+		var trialEnd, periodEnd sql.NullTime
+		err := rows.Scan(&sub.Id, &sub.UserId, &sub.PlanId, &sub.Status, &sub.ProviderRef,
+			&trialEnd, &periodEnd, &sub.CancelAtPeriodEnd, &sub.Created, &sub.Updated)
+		if err != nil {
+			return nil, err
+		}
+		if trialEnd.Valid {
+			sub.TrialEnd = &trialEnd.Time
+		}
+		if periodEnd.Valid {
+			sub.PeriodEnd = &periodEnd.Time
+		}
+	}
+	rows.Close()
+	if sub.Id == 0 {
+		return nil, fmt.Errorf("subscription %w", ErrNotFound)
+	}
+	var err error
+	sub.Plan, err = db.GetPlan(ctx, sub.PlanId)
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// SaveBillingEvent records a provider event and reports false when it was already processed.
+func (db *DbSqlite) SaveBillingEvent(ctx context.Context, id string, eventType string, ref string) (bool, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	res, err := db.sqlite.ExecContext(ctx, "INSERT OR IGNORE INTO billingevents (id, type, providerref, received) VALUES (?, ?, ?, ?)",
+		id, eventType, ref, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"goflix/config"
+	"goflix/models"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+const movieColumns = "id, title, actors, rating, details, genre, saison, episode, maturity, maturitylevel, status, publishat, externalid, poster"
+
+// qualifiedMovieColumns is movieColumns for queries joining movies with other tables.
+var qualifiedMovieColumns = "movies." + strings.ReplaceAll(movieColumns, ", ", ", movies.")
+
+func scanMovies(rows *sql.Rows) ([]*models.Movies, error) {
+	defer rows.Close()
+	var movies []*models.Movies
+	for rows.Next() {
+		movie, err := scanMovie(rows)
+		if err != nil {
+			return nil, err
+		}
+		movies = append(movies, movie)
+	}
+	return movies, rows.Err()
+}
+
+// scanMovie reads the movieColumns of the current row followed by the extra destinations.
+func scanMovie(rows *sql.Rows, extra ...any) (*models.Movies, error) {
+	movie := models.Movies{}
+	var publishAt sql.NullTime
+	var externalID sql.NullString
+	dest := []any{&movie.Id,
+		&movie.Title,
+		&movie.Actors,
+		&movie.Rating,
+		&movie.Details,
+		&movie.Genre,
+		&movie.Saison,
+		&movie.Episode,
+		&movie.Maturity,
+		&movie.MaturityLevel,
+		&movie.Status,
+		&publishAt,
+		&externalID,
+		&movie.Poster}
+	err := rows.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	if publishAt.Valid {
+		movie.PublishAt = &publishAt.Time
+	}
+	movie.ExternalId = externalID.String
+	return &movie, nil
+}
+
+// catalogWhere turns a viewer filter into extra conditions on the movies table, a nil filter sees everything.
+func catalogWhere(filter *models.CatalogFilter) (string, []any) {
+	if filter == nil {
+		return "", nil
+	}
+	where := " AND movies.status = ? AND movies.maturitylevel <= ?"
+	args := []any{config.STATUS_PUBLISHED, filter.MaxMaturity}
+
+	// titles without any window are available everywhere
+	now := time.Now().UTC()
+	where += ` AND (NOT EXISTS (SELECT 1 FROM availability a WHERE a.movieid = movies.id)
+		OR EXISTS (SELECT 1 FROM availability a WHERE a.movieid = movies.id AND a.country IN (?, ?)
+			AND a.starts <= ? AND (a.ends IS NULL OR a.ends > ?)))`
+	args = append(args, filter.Region, config.ALL_REGIONS, now, now)
+	return where, args
+}
+
+// nullString stores empty strings as NULL, e.g. for columns under a unique index.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// parseTime reads a DATETIME that lost its column type, e.g. through an aggregate.
+func parseTime(value string) (time.Time, bool) {
+	for _, layout := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
@@ -1,10 +1,13 @@
 package db
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 	"log"
+	"os"
+	"strings"
+	"time"
 
 	"goflix/config"
 	"goflix/models"
@@ -14,44 +17,196 @@ import (
 )
 
 type Storage interface {
-	Setup() error
+	Setup(ctx context.Context) error
 	Close()
-	GetUser(int) (*models.User, error)
-	SaveUser(*models.User) error
-	DeleteUser(int) error
-	UpdateUser(*models.User) error
-	GetID(user *models.User) error
-	GetMoviesById(id int) (*models.Movies, error)
-	GetMovies() ([]*models.Movies, error)
-	DeleteMovieByID(id int) error
-	GetSeries() ([]*models.Movies, error)
-	AddMovie(movie *models.Movies) error
-	SaveRating(rating *models.Rating) error
-	GetFavoriteByUser(favorite *models.Favorite) error
-	SaveFavorite(favorite *models.Favorite) error
-	GetRatingByUser(id int) ([]*models.Rating, error)
+	WithTx(ctx context.Context, fn func(Storage) error) error
+	GetUser(ctx context.Context, id int) (*models.User, error)
+	SaveUser(ctx context.Context, user *models.User) error
+	DeleteUser(ctx context.Context, id int) error
+	UpdateUser(ctx context.Context, user *models.User) error
+	GetID(ctx context.Context, user *models.User) error
+	GetMoviesById(ctx context.Context, id int, filter *models.CatalogFilter) (*models.Movies, error)
+	GetMovies(ctx context.Context, filter *models.CatalogFilter) ([]*models.Movies, error)
+	DeleteMovieByID(ctx context.Context, id int) error
+	GetSeries(ctx context.Context, filter *models.CatalogFilter) ([]*models.Movies, error)
+	AddMovie(ctx context.Context, movie *models.Movies) error
+	UpdateMovie(ctx context.Context, movie *models.Movies) error
+	UpdateMaturity(ctx context.Context, id int, maturity string, level int) error
+	UpdateMovieStatus(ctx context.Context, id int, from string, to string, publishAt *time.Time) error
+	GetMoviesByStatus(ctx context.Context, status string) ([]*models.Movies, error)
+	PublishScheduled(ctx context.Context, now time.Time) ([]*models.Movies, error)
+	SaveRating(ctx context.Context, rating *models.Rating) error
+	GetFavoriteByUser(ctx context.Context, favorite *models.Favorite) error
+	GetRatingByUser(ctx context.Context, id int) ([]*models.Rating, error)
+	AddSubtitle(ctx context.Context, subtitle *models.Subtitle) error
+	GetSubtitles(ctx context.Context, movieID int) ([]*models.Subtitle, error)
+	GetSubtitle(ctx context.Context, id int) (*models.Subtitle, error)
+	DeleteSubtitle(ctx context.Context, id int) error
+	AddAudioTrack(ctx context.Context, track *models.AudioTrack) error
+	GetAudioTracks(ctx context.Context, movieID int) ([]*models.AudioTrack, error)
+	DeleteAudioTrack(ctx context.Context, id int) error
+	StartStreamSession(ctx context.Context, session *models.StreamSession, maxStreams int) ([]*models.StreamSession, error)
+	GetStreamSession(ctx context.Context, id string) (*models.StreamSession, error)
+	GetActiveStreamSessions(ctx context.Context, userID int) ([]*models.StreamSession, error)
+	HeartbeatStreamSession(ctx context.Context, id string) error
+	StopStreamSession(ctx context.Context, id string, reason string) error
+	AddPlan(ctx context.Context, plan *models.Plan) error
+	GetPlans(ctx context.Context) ([]*models.Plan, error)
+	GetPlan(ctx context.Context, id int) (*models.Plan, error)
+	GetPlanByCode(ctx context.Context, code string) (*models.Plan, error)
+	SaveSubscription(ctx context.Context, sub *models.Subscription) error
+	GetSubscriptionByUser(ctx context.Context, userID int) (*models.Subscription, error)
+	GetSubscriptionByRef(ctx context.Context, ref string) (*models.Subscription, error)
+	SaveBillingEvent(ctx context.Context, id string, eventType string, ref string) (bool, error)
+	GetParentalControl(ctx context.Context, userID int) (*models.ParentalControl, error)
+	SaveParentalControl(ctx context.Context, control *models.ParentalControl) error
+	TakePinAttempt(ctx context.Context, userID int) (*time.Time, error)
+	ResetPinAttempts(ctx context.Context, userID int) error
+	AddAvailability(ctx context.Context, window *models.Availability) error
+	GetAvailability(ctx context.Context, movieID int) ([]*models.Availability, error)
+	DeleteAvailability(ctx context.Context, movieID int, id int) error
+	GetLeavingSoon(ctx context.Context, filter *models.CatalogFilter, until time.Time) ([]*models.Movies, error)
+	GetUserSettings(ctx context.Context, userID int) (*models.UserSettings, error)
+	SaveUserSettings(ctx context.Context, settings *models.UserSettings) error
+	GetPerson(ctx context.Context, id int) (*models.Person, error)
+	SavePerson(ctx context.Context, person *models.Person) error
+	AddCredit(ctx context.Context, credit *models.Credit) error
+	DeleteCredit(ctx context.Context, movieID int, id int) error
+	GetCreditsByMovie(ctx context.Context, movieID int) ([]*models.Credit, error)
+	GetCreditsByPerson(ctx context.Context, personID int, filter *models.CatalogFilter) ([]*models.Credit, error)
+	GetGenres(ctx context.Context, locales []string) ([]*models.Genre, error)
+	GetGenre(ctx context.Context, slug string, locales []string) (*models.Genre, error)
+	GetGenresByMovie(ctx context.Context, movieID int, locales []string) ([]*models.Genre, error)
+	SaveGenre(ctx context.Context, genre *models.Genre) error
+	SaveGenreName(ctx context.Context, slug string, locale string, name string) error
+	DeleteGenreName(ctx context.Context, slug string, locale string) error
+	UpdateGenre(ctx context.Context, slug string, genre *models.Genre) error
+	MergeGenres(ctx context.Context, from string, into string) error
+	SetMovieGenres(ctx context.Context, movieID int, slugs []string) error
+	GetTitlesByGenre(ctx context.Context, slug string, filter *models.CatalogFilter, offset int, limit int) ([]*models.Movies, int, error)
+	GetMovieTranslations(ctx context.Context, movieID int) ([]*models.MovieTranslation, error)
+	SaveMovieTranslation(ctx context.Context, translation *models.MovieTranslation) error
+	DeleteMovieTranslation(ctx context.Context, movieID int, locale string) error
+	TranslateMovies(ctx context.Context, movies []*models.Movies, locales []string) error
+	GetCatalog(ctx context.Context) ([]*models.Movies, error)
+	ImportMovies(ctx context.Context, rows []*models.ImportRow, atomic bool, dryRun bool) (bool, error)
+	GetMediaFiles(ctx context.Context) ([]*models.MediaFile, error)
+	GetMediaFilesByMovie(ctx context.Context, movieID int) ([]*models.MediaFile, error)
+	SaveMediaFile(ctx context.Context, file *models.MediaFile) error
+	DeleteMediaFile(ctx context.Context, path string) error
+	GetAllRatings(ctx context.Context) ([]*models.Rating, error)
+	GetMovieFeatures(ctx context.Context) (map[int][]string, error)
+	SaveSimilarities(ctx context.Context, kind string, similarities []*models.Similarity) error
+	GetSimilarities(ctx context.Context, kind string, movieIDs []int) ([]*models.Similarity, error)
+	GetMoviesByIds(ctx context.Context, ids []int, filter *models.CatalogFilter) ([]*models.Movies, error)
+	GetPopularMovies(ctx context.Context, filter *models.CatalogFilter, limit int) ([]*models.Movies, error)
+	GetAllFavorites(ctx context.Context) (map[int][]int, error)
+	CatalogVersion(ctx context.Context) (int, error)
+	AddEvent(ctx context.Context, event *models.Event) error
+	GetEventsSince(ctx context.Context, since time.Time) ([]*models.Event, error)
+	DeleteEventsBefore(ctx context.Context, before time.Time) (int, error)
+	SavePopularity(ctx context.Context, board string, scores []*models.Popularity) error
+	GetPopularity(ctx context.Context, board string, region string, limit int) ([]*models.Popularity, error)
+	GetHomeRows(ctx context.Context, enabledOnly bool) ([]*models.HomeRow, error)
+	GetHomeRow(ctx context.Context, id int) (*models.HomeRow, error)
+	SaveHomeRow(ctx context.Context, row *models.HomeRow) error
+	DeleteHomeRow(ctx context.Context, id int) error
+	GetRecentlyWatched(ctx context.Context, userID int, filter *models.CatalogFilter, offset int, limit int) ([]*models.Movies, error)
+	GetNewReleases(ctx context.Context, filter *models.CatalogFilter, offset int, limit int) ([]*models.Movies, error)
+	GetLists(ctx context.Context, userID int) ([]*models.List, error)
+	GetList(ctx context.Context, id int) (*models.List, error)
+	GetListByToken(ctx context.Context, token string) (*models.List, error)
+	GetDefaultList(ctx context.Context, userID int) (*models.List, error)
+	SaveList(ctx context.Context, list *models.List) error
+	DeleteList(ctx context.Context, id int) error
+	GetListMovieIds(ctx context.Context, listID int) ([]int, error)
+	AddListItem(ctx context.Context, listID int, movieID int, position int) (bool, error)
+	RemoveListItem(ctx context.Context, listID int, movieID int) error
+	ReorderList(ctx context.Context, listID int, movieIDs []int) error
+	GetSeriesFans(ctx context.Context, seriesID string) ([]int, error)
+	GetSeriesFansByTitle(ctx context.Context, title string) ([]int, error)
+	GetReviews(ctx context.Context, query *models.ReviewQuery, offset int, limit int) ([]*models.Review, int, error)
+	GetReview(ctx context.Context, id int) (*models.Review, error)
+	GetReviewByUser(ctx context.Context, movieID int, userID int) (*models.Review, error)
+	SaveReview(ctx context.Context, review *models.Review) error
+	DeleteReview(ctx context.Context, id int) error
+	VoteReview(ctx context.Context, reviewID int, userID int, helpful bool) error
+	ReportReview(ctx context.Context, report *models.ReviewReport) (int, error)
+	GetReviewReports(ctx context.Context, reviewID int) ([]*models.ReviewReport, error)
+	ModerateReview(ctx context.Context, id int, status string, moderatorID int, note string) error
+	GetWebhooks(ctx context.Context) ([]*models.Webhook, error)
+	GetWebhook(ctx context.Context, id int) (*models.Webhook, error)
+	SaveWebhook(ctx context.Context, webhook *models.Webhook) error
+	DeleteWebhook(ctx context.Context, id int) error
+	AddWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]*models.WebhookDelivery, error)
+	GetWebhookDeliveries(ctx context.Context, webhookID int, status string, offset int, limit int) ([]*models.WebhookDelivery, int, error)
+	GetWebhookDelivery(ctx context.Context, id int) (*models.WebhookDelivery, error)
+	SaveWebhookAttempt(ctx context.Context, delivery *models.WebhookDelivery, attempt *models.WebhookAttempt) error
+	RequeueWebhookDelivery(ctx context.Context, id int) (*models.WebhookDelivery, error)
+	AddNotification(ctx context.Context, notification *models.Notification) (bool, error)
+	GetNotifications(ctx context.Context, userID int, unreadOnly bool, offset int, limit int) ([]*models.Notification, int, int, error)
+	MarkNotificationRead(ctx context.Context, userID int, id int, read bool) error
+	MarkAllNotificationsRead(ctx context.Context, userID int) (int, error)
+	GetPendingEmailNotifications(ctx context.Context) ([]*models.Notification, error)
+	MarkNotificationsEmailed(ctx context.Context, ids []int, at time.Time) error
+	GetNotificationPreferences(ctx context.Context, userID int) (models.NotificationPreferences, error)
+	SaveNotificationPreferences(ctx context.Context, userID int, prefs models.NotificationPreferences) error
 }
 
 type DbSqlite struct {
-	sqlite *sql.DB
+	sqlite conn
+	pool   *sql.DB
+	// tx is the transaction of a unit of work, see WithTx
+	tx         *sql.Tx
+	savepoints *int
+	dataSource string
+	// stmts is shared by the units of work, see stmt
+	stmts *stmtCache
+	// timeout and batch bound each storage call, see queryTimeout
+	timeout time.Duration
+	batch   time.Duration
 }
 
 func New() Storage {
-	return &DbSqlite{}
+	return &DbSqlite{dataSource: config.DATA_SOURCE_NAME, stmts: newStmtCache(), timeout: config.DB_QUERY_TIMEOUT, batch: config.DB_BATCH_TIMEOUT}
 }
 
-func (db *DbSqlite) Setup() error {
+// NewFile is New for the database file at path, with the same options, e.g. for tests.
+func NewFile(path string) Storage {
+	db := New().(*DbSqlite)
+	db.dataSource = "file:" + path + config.DATA_SOURCE_NAME[strings.Index(config.DATA_SOURCE_NAME, "?"):]
+	return db
+}
+
+func (db *DbSqlite) Setup(ctx context.Context) error {
 	var err error
-	db.sqlite, err = sql.Open(config.DRIVE_NAME, config.DATA_SOURCE_NAME)
+	db.timeout, err = envDuration(config.DB_QUERY_TIMEOUT_ENV, db.timeout)
+	if err != nil {
+		return err
+	}
+	db.batch, err = envDuration(config.DB_BATCH_TIMEOUT_ENV, db.batch)
+	if err != nil {
+		return err
+	}
+	db.pool, err = sql.Open(config.DRIVE_NAME, db.dataSource)
 	if err != nil {
 		return err
 	}
-	err = db.sqlite.Ping()
+	db.pool.SetMaxOpenConns(config.DB_MAX_OPEN_CONNS)
+	db.pool.SetMaxIdleConns(config.DB_MAX_IDLE_CONNS)
+	db.pool.SetConnMaxIdleTime(config.DB_CONN_MAX_IDLE_TIME)
+	db.sqlite = db.pool
+	err = db.pool.PingContext(ctx)
 	if err != nil {
 		return err
 	}
 
-	err = db.InitTables()
+	err = db.InitTables(ctx)
+	if err != nil {
+		return err
+	}
+	err = db.Migrate(ctx)
 	if err != nil {
 		return err
 	}
@@ -60,38 +215,226 @@ func (db *DbSqlite) Setup() error {
 	return nil
 }
 
-func (db *DbSqlite) InitTables() error {
-	_, err := db.sqlite.Exec(config.CREATE_TABLE_USERS)
+// queryTimeout bounds a storage call, the context of the caller can still cancel it sooner.
+func (db *DbSqlite) queryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, db.timeout)
+}
+
+// batchTimeout bounds a storage call walking whole tables.
+func (db *DbSqlite) batchTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, db.batch)
+}
+
+func envDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s: invalid duration %q", key, value)
+	}
+	return d, nil
+}
+
+func (db *DbSqlite) InitTables(ctx context.Context) error {
+	_, err := db.sqlite.ExecContext(ctx, config.CREATE_TABLE_USERS)
 	if err != nil {
 		return err
 	}
 	fmt.Println("user created!")
-	_, err = db.sqlite.Exec(config.CREATE_TABLE_MOVIES)
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_MOVIES)
 	if err != nil {
 		return err
 	}
 	fmt.Println("movies created!")
-	_, err = db.sqlite.Exec(config.CREATE_TABLE_FAVORITE)
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_FAVORITE)
 	if err != nil {
 		return err
 	}
 	fmt.Println("favorite created!")
 
-	_, err = db.sqlite.Exec(config.CREATE_TABLE_RATING)
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_RATING)
 	if err != nil {
 		return err
 	}
 	fmt.Println("rating created!")
 
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_SUBTITLES)
+	if err != nil {
+		return err
+	}
+	fmt.Println("subtitles created!")
+
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_AUDIO_TRACKS)
+	if err != nil {
+		return err
+	}
+	fmt.Println("audiotracks created!")
+
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_STREAM_SESSIONS)
+	if err != nil {
+		return err
+	}
+	fmt.Println("streamsessions created!")
+
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_PLANS)
+	if err != nil {
+		return err
+	}
+	_, err = db.sqlite.ExecContext(ctx, config.SEED_PLANS)
+	if err != nil {
+		return err
+	}
+	fmt.Println("plans created!")
+
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_SUBSCRIPTIONS)
+	if err != nil {
+		return err
+	}
+	fmt.Println("subscriptions created!")
+
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_BILLING_EVENTS)
+	if err != nil {
+		return err
+	}
+	fmt.Println("billingevents created!")
+
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_PARENTAL_CONTROLS)
+	if err != nil {
+		return err
+	}
+	fmt.Println("parentalcontrols created!")
+
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_AVAILABILITY)
+	if err != nil {
+		return err
+	}
+	fmt.Println("availability created!")
+
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_USER_SETTINGS)
+	if err != nil {
+		return err
+	}
+	fmt.Println("usersettings created!")
+
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_PEOPLE)
+	if err != nil {
+		return err
+	}
+	fmt.Println("people created!")
+
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_CREDITS)
+	if err != nil {
+		return err
+	}
+	fmt.Println("credits created!")
+
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_GENRES)
+	if err != nil {
+		return err
+	}
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_GENRE_NAMES)
+	if err != nil {
+		return err
+	}
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_MOVIE_GENRES)
+	if err != nil {
+		return err
+	}
+	fmt.Println("genres created!")
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_MOVIE_TRANSLATIONS)
+	if err != nil {
+		return err
+	}
+	fmt.Println("movietranslations created!")
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_MEDIA_FILES)
+	if err != nil {
+		return err
+	}
+	fmt.Println("mediafiles created!")
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_SIMILARITIES)
+	if err != nil {
+		return err
+	}
+	fmt.Println("similarities created!")
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_CATALOG_VERSION)
+	if err != nil {
+		return err
+	}
+	fmt.Println("catalogversion created!")
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_ACTIVITY)
+	if err != nil {
+		return err
+	}
+	fmt.Println("activity created!")
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_POPULARITY)
+	if err != nil {
+		return err
+	}
+	fmt.Println("popularity created!")
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_HOME_ROWS)
+	if err != nil {
+		return err
+	}
+	fmt.Println("homerows created!")
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_LISTS)
+	if err != nil {
+		return err
+	}
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_LIST_ITEMS)
+	if err != nil {
+		return err
+	}
+	fmt.Println("lists created!")
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_REVIEWS)
+	if err != nil {
+		return err
+	}
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_REVIEW_VOTES)
+	if err != nil {
+		return err
+	}
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_REVIEW_REPORTS)
+	if err != nil {
+		return err
+	}
+	fmt.Println("reviews created!")
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_WEBHOOKS)
+	if err != nil {
+		return err
+	}
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_WEBHOOK_DELIVERIES)
+	if err != nil {
+		return err
+	}
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_WEBHOOK_ATTEMPTS)
+	if err != nil {
+		return err
+	}
+	fmt.Println("webhooks created!")
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_NOTIFICATIONS)
+	if err != nil {
+		return err
+	}
+	_, err = db.sqlite.ExecContext(ctx, config.CREATE_TABLE_NOTIFICATION_PREFS)
+	if err != nil {
+		return err
+	}
+	fmt.Println("notifications created!")
+
 	return nil
 }
 
 func (db *DbSqlite) Close() {
-	db.sqlite.Close()
+	db.stmts.close()
+	db.pool.Close()
 }
 
-func (db *DbSqlite) GetUser(id int) (*models.User, error) {
-	rows, err := db.sqlite.Query("SELECT * FROM users  WHERE id=?", id)
+func (db *DbSqlite) GetUser(ctx context.Context, id int) (*models.User, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.queryCached(ctx, "SELECT * FROM users  WHERE id=?", id)
 	if err != nil {
 		return nil, err
 	}
@@ -109,18 +452,20 @@ func (db *DbSqlite) GetUser(id int) (*models.User, error) {
 		}
 	}
 	if user.Id == 0 {
-		return nil, errors.New("user not found")
+		return nil, fmt.Errorf("user %w", ErrNotFound)
 	}
 	return &user, nil
 }
 
-func (db *DbSqlite) SaveUser(user *models.User) error {
+func (db *DbSqlite) SaveUser(ctx context.Context, user *models.User) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
 	hashPswd, err := utils.HashPasswd([]byte(user.Pswd))
 	if err != nil {
 		return err
 	}
 	insertSQL := "INSERT INTO users (user,pswd,account,name,firstname,mail,cell,adress) VALUES (?,?,?,?,?,?,?,?)"
-	_, err = db.sqlite.Exec(insertSQL,
+	res, err := db.sqlite.ExecContext(ctx, insertSQL,
 		user.User,
 		hashPswd,
 		user.Account,
@@ -132,12 +477,19 @@ func (db *DbSqlite) SaveUser(user *models.User) error {
 	if err != nil {
 		return err
 	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	user.Id = int(id)
 
 	return nil
 }
-func (db *DbSqlite) GetID(user *models.User) error {
+func (db *DbSqlite) GetID(ctx context.Context, user *models.User) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
 	pswd := user.Pswd
-	rows, err := db.sqlite.Query("SELECT * FROM users  WHERE user=? ", user.User)
+	rows, err := db.queryCached(ctx, "SELECT * FROM users  WHERE user=? ", user.User)
 	if err != nil {
 		return err
 	}
@@ -154,22 +506,24 @@ func (db *DbSqlite) GetID(user *models.User) error {
 		}
 	}
 	if user.Id == 0 {
-		return errors.New("user not found")
+		return fmt.Errorf("user %w", ErrNotFound)
 	}
 	err = utils.CompareHashAndPassword([]byte(pswd), []byte(user.Pswd))
 	if err != nil {
-		return errors.New("error password")
+		return ErrWrongPassword
 	}
 	return nil
 }
 
-func (db *DbSqlite) UpdateUser(user *models.User) error {
+func (db *DbSqlite) UpdateUser(ctx context.Context, user *models.User) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
 	hashPswd, err := utils.HashPasswd([]byte(user.Pswd))
 	if err != nil {
 		return err
 	}
 	updateSQL := "UPDATE users SET user = ? , pswd = ? , account = ? , name = ? , firstname = ? , mail = ? , cell = ? , adress = ? WHERE id = ?"
-	res, err := db.sqlite.Exec(updateSQL,
+	res, err := db.sqlite.ExecContext(ctx, updateSQL,
 		&user.User,
 		&hashPswd,
 		&user.Account,
@@ -183,15 +537,17 @@ func (db *DbSqlite) UpdateUser(user *models.User) error {
 		return err
 	}
 	if n, err := res.RowsAffected(); n < 1 || err != nil {
-		return fmt.Errorf("update failed with id: %d", user.Id)
+		return fmt.Errorf("update failed with id: %d: %w", user.Id, ErrNotFound)
 	}
 
 	return nil
 }
 
-func (db *DbSqlite) DeleteUser(id int) error {
+func (db *DbSqlite) DeleteUser(ctx context.Context, id int) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
 	deleteSQL := "DELETE FROM users WHERE id = ?"
-	result, err := db.sqlite.Exec(deleteSQL, id)
+	result, err := db.sqlite.ExecContext(ctx, deleteSQL, id)
 	if err != nil {
 		return err
 	}
@@ -200,7 +556,7 @@ func (db *DbSqlite) DeleteUser(id int) error {
 		return err
 	}
 	if rowsAffected != 1 {
-		return fmt.Errorf("errors want delete 1 reccord got: %d", rowsAffected)
+		return fmt.Errorf("errors want delete 1 reccord got: %d: %w", rowsAffected, ErrNotFound)
 	}
 
 	return nil
@@ -208,61 +564,53 @@ func (db *DbSqlite) DeleteUser(id int) error {
 
 // * * *
 
-func (db *DbSqlite) GetMoviesById(id int) (*models.Movies, error) {
-	rows, err := db.sqlite.Query("SELECT * FROM movies  WHERE id=?", id)
+func (db *DbSqlite) GetMoviesById(ctx context.Context, id int, filter *models.CatalogFilter) (*models.Movies, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	where, args := catalogWhere(filter)
+	rows, err := db.queryCached(ctx, "SELECT "+movieColumns+" FROM movies WHERE id = ?"+where, append([]any{id}, args...)...)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	var movie models.Movies
-	if rows.Next() {
-		err = rows.Scan(&movie.Id,
-			&movie.Title,
-			&movie.Actors,
-			&movie.Rating,
-			&movie.Details,
-			&movie.Genre,
-			&movie.Saison,
-			&movie.Episode)
-		if err != nil {
-			return nil, err
-		}
+	movies, err := scanMovies(rows)
+	if err != nil {
+		return nil, err
 	}
-	if movie.Id == 0 {
-		return nil, errors.New("movie not found")
+	if len(movies) == 0 {
+		return nil, fmt.Errorf("movie %w", ErrNotFound)
 	}
-	return &movie, nil
+	return movies[0], nil
 }
-func (db *DbSqlite) GetMovies() ([]*models.Movies, error) {
-	rows, err := db.sqlite.Query("SELECT * FROM movies WHERE saison = 0 ")
+
+// GetMovies lists the films visible through filter, an empty catalog gives an empty list.
+func (db *DbSqlite) GetMovies(ctx context.Context, filter *models.CatalogFilter) ([]*models.Movies, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	where, args := catalogWhere(filter)
+	rows, err := db.queryCached(ctx, "SELECT "+movieColumns+" FROM movies WHERE saison = 0"+where, args...)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	var movies []*models.Movies
-	for rows.Next() {
-		movie := models.Movies{}
-		err = rows.Scan(&movie.Id,
-			&movie.Title,
-			&movie.Actors,
-			&movie.Rating,
-			&movie.Details,
-			&movie.Genre,
-			&movie.Saison,
-			&movie.Episode)
-		if err != nil {
-			return nil, err
-		}
-		movies = append(movies, &movie)
+	movies, err := scanMovies(rows)
+	if err != nil {
+		return nil, err
 	}
-	if len(movies) == 0 {
-		return nil, errors.New("movies not found")
+	if movies == nil {
+		movies = []*models.Movies{}
 	}
 	return movies, nil
 }
-func (db *DbSqlite) DeleteMovieByID(id int) error {
+func (db *DbSqlite) DeleteMovieByID(ctx context.Context, id int) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	// the title and everything attached to it go together
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 	deleteSQL := "DELETE FROM movies WHERE id = ?"
-	result, err := db.sqlite.Exec(deleteSQL, id)
+	result, err := tx.ExecContext(ctx, deleteSQL, id)
 	if err != nil {
 		return err
 	}
@@ -271,110 +619,157 @@ func (db *DbSqlite) DeleteMovieByID(id int) error {
 		return err
 	}
 	if rowsAffected != 1 {
-		return fmt.Errorf("errors want delete 1 reccord got: %d", rowsAffected)
+		return fmt.Errorf("errors want delete 1 reccord got: %d: %w", rowsAffected, ErrNotFound)
 	}
-
-	return nil
-}
-func (db *DbSqlite) GetSeries() ([]*models.Movies, error) {
-	rows, err := db.sqlite.Query("SELECT * FROM movies WHERE saison > 0 ")
+	_, err = tx.ExecContext(ctx, "DELETE FROM subtitles WHERE movieid = ?", id)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
-	var series []*models.Movies
-	for rows.Next() {
-		serie := models.Movies{}
-		err = rows.Scan(&serie.Id,
-			&serie.Title,
-			&serie.Actors,
-			&serie.Rating,
-			&serie.Details,
-			&serie.Genre,
-			&serie.Saison,
-			&serie.Episode)
-		if err != nil {
-			return nil, err
-		}
-		series = append(series, &serie)
+	_, err = tx.ExecContext(ctx, "DELETE FROM audiotracks WHERE movieid = ?", id)
+	if err != nil {
+		return err
 	}
-	if len(series) == 0 {
-		return nil, errors.New("series not found")
+	_, err = tx.ExecContext(ctx, "DELETE FROM credits WHERE movieid = ?", id)
+	if err != nil {
+		return err
 	}
-	return series, nil
-}
-func (db *DbSqlite) AddMovie(movie *models.Movies) error {
-	insertSQL := "INSERT INTO movies (title, actors, rating, details, genre, saison, episode) VALUES (?, ?, ?, ?, ?, ?, ?)"
-	_, err := db.sqlite.Exec(insertSQL,
-		movie.Title, movie.Actors, movie.Rating, movie.Details, movie.Genre, movie.Saison, movie.Episode)
+	_, err = tx.ExecContext(ctx, "DELETE FROM moviegenres WHERE movieid = ?", id)
 	if err != nil {
 		return err
 	}
-
-	return nil
-}
-
-func (db *DbSqlite) SaveFavorite(favorite *models.Favorite) error {
-
-	updateSQL := "UPDATE favorite SET moviesid = ? WHERE userid = ?"
-	res, err := db.sqlite.Exec(updateSQL, &favorite.MoviesID, &favorite.UserId)
+	_, err = tx.ExecContext(ctx, "DELETE FROM movietranslations WHERE movieid = ?", id)
 	if err != nil {
 		return err
 	}
-	n, err := res.RowsAffected()
+	_, err = tx.ExecContext(ctx, "DELETE FROM mediafiles WHERE movieid = ?", id)
 	if err != nil {
 		return err
 	}
-	if n > 0 {
-		log.Println("favorite updated")
-		return nil
+	_, err = tx.ExecContext(ctx, "DELETE FROM listitems WHERE movieid = ?", id)
+	if err != nil {
+		return err
 	}
-	insertSQL := "INSERT INTO favorite (userid, moviesid) VALUES (?, ?)"
-	_, err = db.sqlite.Exec(insertSQL, &favorite.UserId, &favorite.MoviesID)
+	_, err = tx.ExecContext(ctx, "DELETE FROM reviewvotes WHERE reviewid IN (SELECT id FROM reviews WHERE movieid = ?)", id)
 	if err != nil {
 		return err
 	}
-	log.Println("favorite add")
-	return nil
+	_, err = tx.ExecContext(ctx, "DELETE FROM reviewreports WHERE reviewid IN (SELECT id FROM reviews WHERE movieid = ?)", id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM reviews WHERE movieid = ?", id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM notifications WHERE movieid = ?", id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetSeries lists the episodes visible through filter, an empty catalog gives an empty list.
+func (db *DbSqlite) GetSeries(ctx context.Context, filter *models.CatalogFilter) ([]*models.Movies, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	where, args := catalogWhere(filter)
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT "+movieColumns+" FROM movies WHERE saison > 0"+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	series, err := scanMovies(rows)
+	if err != nil {
+		return nil, err
+	}
+	if series == nil {
+		series = []*models.Movies{}
+	}
+	return series, nil
 }
-func (db *DbSqlite) GetFavoriteByUser(favorite *models.Favorite) error {
-	rows, err := db.sqlite.Query("SELECT * FROM favorite WHERE userid = ?", favorite.UserId)
+
+// AddMovie inserts the title with its actor credits and genres, all of them or nothing.
+func (db *DbSqlite) AddMovie(ctx context.Context, movie *models.Movies) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
-	if rows.Next() {
-		err = rows.Scan(&favorite.UserId, &favorite.MoviesID)
-		if err != nil {
-			return err
-		}
+	defer tx.Rollback()
+	err = db.insertMovie(ctx, tx, movie)
+	if err != nil {
+		return err
 	}
-	return nil
+	return tx.Commit()
 }
-func (db *DbSqlite) SaveRating(rating *models.Rating) error {
-	updateSQL := "UPDATE rating SET stars = ? WHERE movieid = ? AND userid = ?"
-	res, err := db.sqlite.Exec(updateSQL, &rating.Stars, &rating.MovieId, &rating.UserId)
+
+func (db *DbSqlite) insertMovie(ctx context.Context, exec execer, movie *models.Movies) error {
+	if movie.Status == "" {
+		movie.Status = config.STATUS_DRAFT
+	}
+	insertSQL := "INSERT INTO movies (title, actors, rating, details, genre, saison, episode, maturity, maturitylevel, status, publishat, externalid, poster) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	res, err := exec.ExecContext(ctx, insertSQL,
+		movie.Title, movie.Actors, movie.Rating, movie.Details, movie.Genre, movie.Saison, movie.Episode, movie.Maturity, movie.MaturityLevel, movie.Status, movie.PublishAt, nullString(movie.ExternalId), movie.Poster)
 	if err != nil {
 		return err
 	}
-	n, err := res.RowsAffected()
+	id, err := res.LastInsertId()
 	if err != nil {
 		return err
 	}
-	if n > 0 {
-		log.Println("rating updated")
-		return nil
+	movie.Id = int(id)
+
+	err = db.addActorCredits(ctx, exec, movie.Id, movie.Actors)
+	if err != nil {
+		return err
 	}
-	insertSQL := "INSERT INTO rating (movieid, stars, userid) VALUES (?, ?, ?)"
-	_, err = db.sqlite.Exec(insertSQL, &rating.MovieId, &rating.Stars, &rating.UserId)
+	return db.addMovieGenres(ctx, exec, movie.Id, movie.Genre)
+}
+func (db *DbSqlite) UpdateMaturity(ctx context.Context, id int, maturity string, level int) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	res, err := db.sqlite.ExecContext(ctx, "UPDATE movies SET maturity = ?, maturitylevel = ? WHERE id = ?", maturity, level, id)
 	if err != nil {
 		return err
 	}
-	log.Println("rating add")
+	if n, err := res.RowsAffected(); n < 1 || err != nil {
+		return fmt.Errorf("update failed with id: %d: %w", id, ErrNotFound)
+	}
 	return nil
 }
-func (db *DbSqlite) GetRatingByUser(id int) ([]*models.Rating, error) {
-	rows, err := db.sqlite.Query("SELECT * FROM rating WHERE userid = ?", id)
+
+// SaveRating sets the stars of the user for the title, the update and the insert run in one
+// transaction so that concurrent ratings don't both insert.
+func (db *DbSqlite) SaveRating(ctx context.Context, rating *models.Rating) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	return db.withTx(ctx, func(tx *DbSqlite) error {
+		updateSQL := "UPDATE rating SET stars = ? WHERE movieid = ? AND userid = ?"
+		res, err := tx.execCached(ctx, updateSQL, &rating.Stars, &rating.MovieId, &rating.UserId)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			log.Println("rating updated")
+			return nil
+		}
+		insertSQL := "INSERT INTO rating (movieid, stars, userid) VALUES (?, ?, ?)"
+		_, err = tx.execCached(ctx, insertSQL, &rating.MovieId, &rating.Stars, &rating.UserId)
+		if err != nil {
+			return err
+		}
+		log.Println("rating add")
+		return nil
+	})
+}
+func (db *DbSqlite) GetRatingByUser(ctx context.Context, id int) ([]*models.Rating, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT * FROM rating WHERE userid = ?", id)
 	if err != nil {
 		return nil, err
 	}
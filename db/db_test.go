@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"goflix/models"
+)
+
+// workers is how many goroutines race in each test, more than DB_MAX_OPEN_CONNS so that some
+// wait for a connection.
+const workers = 20
+
+// openTest opens a fresh database in a temporary file, with the options of the real one.
+func openTest(t *testing.T) *DbSqlite {
+	t.Helper()
+	db := NewFile(filepath.Join(t.TempDir(), "test.db")).(*DbSqlite)
+	err := db.Setup(context.Background())
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// race runs fn in workers goroutines started together and returns the errors they got.
+func race(fn func(i int) error) []error {
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make([]error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = fn(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+func count(t *testing.T, db *DbSqlite, query string, args ...any) int {
+	t.Helper()
+	var n int
+	err := db.sqlite.QueryRowContext(context.Background(), query, args...).Scan(&n)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+// TestSaveRatingConcurrent has each worker read the stars and save them plus one in a unit of
+// work, the rating ends at workers only when no write was lost.
+func TestSaveRatingConcurrent(t *testing.T) {
+	db := openTest(t)
+	ctx := context.Background()
+	errs := race(func(i int) error {
+		return db.WithTx(ctx, func(store Storage) error {
+			ratings, err := store.GetRatingByUser(ctx, 1)
+			if err != nil && !IsNotFound(err) {
+				return err
+			}
+			stars := 0
+			for _, rating := range ratings {
+				if rating.MovieId == 1 {
+					stars = rating.Stars
+				}
+			}
+			return store.SaveRating(ctx, &models.Rating{UserId: 1, MovieId: 1, Stars: stars + 1})
+		})
+	})
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("SaveRating %d: %v", i, err)
+		}
+	}
+	if n := count(t, db, "SELECT stars FROM rating WHERE userid = 1 AND movieid = 1"); n != workers {
+		t.Fatalf("got %d stars, want %d", n, workers)
+	}
+}
+
+func TestGetDefaultListConcurrent(t *testing.T) {
+	db := openTest(t)
+	ids := make([]int, workers)
+	errs := race(func(i int) error {
+		list, err := db.GetDefaultList(context.Background(), 1)
+		if err == nil {
+			ids[i] = list.Id
+		}
+		return err
+	})
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("GetDefaultList %d: %v", i, err)
+		}
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM lists WHERE userid = 1 AND isdefault = 1"); n != 1 {
+		t.Fatalf("got %d default lists, want 1", n)
+	}
+	for i, id := range ids {
+		if id != ids[0] {
+			t.Errorf("GetDefaultList %d: list %d, want %d", i, id, ids[0])
+		}
+	}
+}
+
+func TestAddListItemConcurrent(t *testing.T) {
+	db := openTest(t)
+	list, err := db.GetDefaultList(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetDefaultList: %v", err)
+	}
+	// half of the workers add a title, the other half add the same titles again
+	errs := race(func(i int) error {
+		_, err := db.AddListItem(context.Background(), list.Id, 1+i%(workers/2), 1)
+		return err
+	})
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("AddListItem %d: %v", i, err)
+		}
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM listitems WHERE listid = ?", list.Id); n != workers/2 {
+		t.Fatalf("got %d items, want %d", n, workers/2)
+	}
+	// the positions are still 1 to n, each once
+	if n := count(t, db, "SELECT COUNT(DISTINCT position) FROM listitems WHERE listid = ? AND position BETWEEN 1 AND ?", list.Id, workers/2); n != workers/2 {
+		t.Fatalf("got %d distinct positions, want %d", n, workers/2)
+	}
+}
+
+func TestWithTxNestedRollback(t *testing.T) {
+	db := openTest(t)
+	ctx := context.Background()
+	errNested := errors.New("nested")
+	err := db.WithTx(ctx, func(store Storage) error {
+		err := store.SaveRating(ctx, &models.Rating{UserId: 1, MovieId: 1, Stars: 4})
+		if err != nil {
+			return err
+		}
+		err = store.WithTx(ctx, func(nested Storage) error {
+			err := nested.SaveRating(ctx, &models.Rating{UserId: 1, MovieId: 2, Stars: 2})
+			if err != nil {
+				return err
+			}
+			// updates the rating of the outer unit, undone with the savepoint as well
+			err = nested.SaveRating(ctx, &models.Rating{UserId: 1, MovieId: 1, Stars: 1})
+			if err != nil {
+				return err
+			}
+			return errNested
+		})
+		if !errors.Is(err, errNested) {
+			return fmt.Errorf("nested WithTx: %v", err)
+		}
+		return store.SaveRating(ctx, &models.Rating{UserId: 1, MovieId: 3, Stars: 5})
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	ratings, err := db.GetRatingByUser(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetRatingByUser: %v", err)
+	}
+	got := map[int]int{}
+	for _, rating := range ratings {
+		got[rating.MovieId] = rating.Stars
+	}
+	want := map[int]int{1: 4, 3: 5}
+	if len(got) != len(want) || got[1] != want[1] || got[3] != want[3] {
+		t.Fatalf("got ratings %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"goflix/config"
+	"goflix/models"
+)
+
+// UpdateMovieStatus moves a title from one editorial status to another, failing if it
+// is no longer in the from status.
+func (db *DbSqlite) UpdateMovieStatus(ctx context.Context, id int, from string, to string, publishAt *time.Time) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	if publishAt != nil {
+		utc := publishAt.UTC()
+		publishAt = &utc
+	}
+	res, err := db.sqlite.ExecContext(ctx, "UPDATE movies SET status = ?, publishat = ? WHERE id = ? AND status = ?", to, publishAt, id, from)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); n < 1 || err != nil {
+		return fmt.Errorf("movie %d is not %s anymore: %w", id, from, ErrConflict)
+	}
+	return nil
+}
+
+func (db *DbSqlite) GetMoviesByStatus(ctx context.Context, status string) ([]*models.Movies, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT "+movieColumns+" FROM movies WHERE status = ? ORDER BY id", status)
+	if err != nil {
+		return nil, err
+	}
+	return scanMovies(rows)
+}
+
+// PublishScheduled publishes the scheduled titles due by now and returns them.
+func (db *DbSqlite) PublishScheduled(ctx context.Context, now time.Time) ([]*models.Movies, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.QueryContext(ctx, "SELECT "+movieColumns+" FROM movies WHERE status = ? AND publishat <= ? ORDER BY id",
+		config.STATUS_SCHEDULED, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	movies, err := scanMovies(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, movie := range movies {
+		_, err = tx.ExecContext(ctx, "UPDATE movies SET status = ? WHERE id = ?", config.STATUS_PUBLISHED, movie.Id)
+		if err != nil {
+			return nil, err
+		}
+		movie.Status = config.STATUS_PUBLISHED
+	}
+	return movies, tx.Commit()
+}
@@ -0,0 +1,357 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"goflix/models"
+	"goflix/utils"
+)
+
+// genreName picks the genre name in the first locale of the localeList given twice as arguments,
+// the default name otherwise.
+const genreName = `COALESCE((SELECT name FROM genrenames WHERE genreid = genres.id AND instr(?, ',' || locale || ',') > 0
+	ORDER BY instr(?, ',' || locale || ',') LIMIT 1), genres.name)`
+
+// syncGenres keeps the legacy genre column of the selected movies in line with their genres.
+const syncGenres = `UPDATE movies SET genre = COALESCE((SELECT group_concat(name, ', ') FROM (
+		SELECT genres.name FROM moviegenres JOIN genres ON genres.id = moviegenres.genreid
+		WHERE moviegenres.movieid = movies.id ORDER BY genres.name)), '')
+	WHERE id IN `
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (db *DbSqlite) GetGenres(ctx context.Context, locales []string) ([]*models.Genre, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	list := localeList(locales)
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT id, slug, "+genreName+" FROM genres ORDER BY slug", list, list)
+	if err != nil {
+		return nil, err
+	}
+	return scanGenres(rows)
+}
+
+func (db *DbSqlite) GetGenre(ctx context.Context, slug string, locales []string) (*models.Genre, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	list := localeList(locales)
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT id, slug, "+genreName+" FROM genres WHERE slug = ?", list, list, slug)
+	if err != nil {
+		return nil, err
+	}
+	genres, err := scanGenres(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(genres) == 0 {
+		return nil, fmt.Errorf("genre %w", ErrNotFound)
+	}
+	genre := genres[0]
+
+	rows, err = db.sqlite.QueryContext(ctx, "SELECT locale, name FROM genrenames WHERE genreid = ?", genre.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	genre.Names = map[string]string{}
+	for rows.Next() {
+		var locale, name string
+		err = rows.Scan(&locale, &name)
+		if err != nil {
+			return nil, err
+		}
+		genre.Names[locale] = name
+	}
+	return genre, rows.Err()
+}
+
+func (db *DbSqlite) GetGenresByMovie(ctx context.Context, movieID int, locales []string) ([]*models.Genre, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	list := localeList(locales)
+	rows, err := db.sqlite.QueryContext(ctx, `SELECT genres.id, genres.slug, `+genreName+` FROM genres
+		JOIN moviegenres ON moviegenres.genreid = genres.id
+		WHERE moviegenres.movieid = ? ORDER BY genres.slug`, list, list, movieID)
+	if err != nil {
+		return nil, err
+	}
+	return scanGenres(rows)
+}
+
+// SaveGenre finds a genre by slug, creating it with its names when missing.
+func (db *DbSqlite) SaveGenre(ctx context.Context, genre *models.Genre) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	return db.saveGenre(ctx, db.sqlite, genre)
+}
+
+func (db *DbSqlite) saveGenre(ctx context.Context, exec execer, genre *models.Genre) error {
+	if genre.Slug == "" {
+		genre.Slug = utils.Slugify(genre.Name)
+	}
+	if genre.Slug == "" {
+		return fmt.Errorf("%w: genre name is empty", ErrInvalid)
+	}
+	if genre.Name == "" {
+		genre.Name = genre.Slug
+	}
+	_, err := exec.ExecContext(ctx, "INSERT OR IGNORE INTO genres (slug, name) VALUES (?, ?)", genre.Slug, genre.Name)
+	if err != nil {
+		return err
+	}
+	err = exec.QueryRowContext(ctx, "SELECT id FROM genres WHERE slug = ?", genre.Slug).Scan(&genre.Id)
+	if err != nil {
+		return err
+	}
+	return db.saveGenreNames(ctx, exec, genre)
+}
+
+// SaveGenreName sets the name of a genre in one locale.
+func (db *DbSqlite) SaveGenreName(ctx context.Context, slug string, locale string, name string) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	genre := models.Genre{Names: map[string]string{locale: name}}
+	err := db.sqlite.QueryRowContext(ctx, "SELECT id FROM genres WHERE slug = ?", slug).Scan(&genre.Id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("genre %w", ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	return db.saveGenreNames(ctx, db.sqlite, &genre)
+}
+
+func (db *DbSqlite) DeleteGenreName(ctx context.Context, slug string, locale string) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	res, err := db.sqlite.ExecContext(ctx, "DELETE FROM genrenames WHERE locale = ? AND genreid = (SELECT id FROM genres WHERE slug = ?)", locale, slug)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("genre translation %w", ErrNotFound)
+	}
+	return nil
+}
+
+// UpdateGenre renames the genre found by slug, across every title using it.
+func (db *DbSqlite) UpdateGenre(ctx context.Context, slug string, genre *models.Genre) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = tx.QueryRowContext(ctx, "SELECT id FROM genres WHERE slug = ?", slug).Scan(&genre.Id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("genre %w", ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE genres SET slug = ?, name = ? WHERE id = ?", genre.Slug, genre.Name, genre.Id)
+	if err != nil {
+		return err
+	}
+	err = db.saveGenreNames(ctx, tx, genre)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, syncGenres+"(SELECT movieid FROM moviegenres WHERE genreid = ?)", genre.Id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MergeGenres moves every title of the from genre to the into genre and deletes from.
+func (db *DbSqlite) MergeGenres(ctx context.Context, from string, into string) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	if from == into {
+		return fmt.Errorf("%w: cannot merge a genre into itself", ErrInvalid)
+	}
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var fromID, intoID int
+	err = tx.QueryRowContext(ctx, "SELECT id FROM genres WHERE slug = ?", from).Scan(&fromID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("genre %s %w", from, ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	err = tx.QueryRowContext(ctx, "SELECT id FROM genres WHERE slug = ?", into).Scan(&intoID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("genre %s %w", into, ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT OR IGNORE INTO moviegenres (movieid, genreid) SELECT movieid, ? FROM moviegenres WHERE genreid = ?", intoID, fromID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT OR IGNORE INTO genrenames (genreid, locale, name) SELECT ?, locale, name FROM genrenames WHERE genreid = ?", intoID, fromID)
+	if err != nil {
+		return err
+	}
+	for _, query := range []string{
+		"DELETE FROM moviegenres WHERE genreid = ?",
+		"DELETE FROM genrenames WHERE genreid = ?",
+		"DELETE FROM genres WHERE id = ?",
+	} {
+		_, err = tx.ExecContext(ctx, query, fromID)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, syncGenres+"(SELECT movieid FROM moviegenres WHERE genreid = ?)", intoID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetMovieGenres replaces the genres of a title, unknown slugs are rejected.
+func (db *DbSqlite) SetMovieGenres(ctx context.Context, movieID int, slugs []string) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, "DELETE FROM moviegenres WHERE movieid = ?", movieID)
+	if err != nil {
+		return err
+	}
+	for _, slug := range slugs {
+		var genreID int
+		err = tx.QueryRowContext(ctx, "SELECT id FROM genres WHERE slug = ?", slug).Scan(&genreID)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: unknown genre %s", ErrInvalid, slug)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "INSERT OR IGNORE INTO moviegenres (movieid, genreid) VALUES (?, ?)", movieID, genreID)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, syncGenres+"(?)", movieID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetTitlesByGenre pages through the titles of a genre visible through filter and counts them.
+func (db *DbSqlite) GetTitlesByGenre(ctx context.Context, slug string, filter *models.CatalogFilter, offset int, limit int) ([]*models.Movies, int, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	where, args := catalogWhere(filter)
+	from := ` FROM movies
+		JOIN moviegenres ON moviegenres.movieid = movies.id
+		JOIN genres ON genres.id = moviegenres.genreid
+		WHERE genres.slug = ?` + where
+	params := append([]any{slug}, args...)
+
+	var total int
+	err := db.sqlite.QueryRowContext(ctx, "SELECT COUNT(*)"+from, params...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT "+qualifiedMovieColumns+from+" ORDER BY movies.title LIMIT ? OFFSET ?", append(params, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	movies, err := scanMovies(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return movies, total, nil
+}
+
+// addMovieGenres links a title to the genres of a free-text genre list, creating them when missing.
+func (db *DbSqlite) addMovieGenres(ctx context.Context, exec execer, movieID int, list string) error {
+	for _, name := range splitNames(list) {
+		genre := models.Genre{Name: name}
+		err := db.saveGenre(ctx, exec, &genre)
+		if err != nil {
+			return err
+		}
+		_, err = exec.ExecContext(ctx, "INSERT OR IGNORE INTO moviegenres (movieid, genreid) VALUES (?, ?)", movieID, genre.Id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *DbSqlite) saveGenreNames(ctx context.Context, exec execer, genre *models.Genre) error {
+	for locale, name := range genre.Names {
+		_, err := exec.ExecContext(ctx, "INSERT INTO genrenames (genreid, locale, name) VALUES (?, ?, ?) ON CONFLICT(genreid, locale) DO UPDATE SET name = excluded.name",
+			genre.Id, locale, name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *DbSqlite) migrateGenreColumn(ctx context.Context) error {
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT id, genre FROM movies WHERE genre IS NOT NULL AND genre != ''")
+	if err != nil {
+		return err
+	}
+	genres := map[int]string{}
+	for rows.Next() {
+		var id int
+		var list string
+		err = rows.Scan(&id, &list)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		genres[id] = list
+	}
+	rows.Close()
+	for id, list := range genres {
+		err = db.addMovieGenres(ctx, db.sqlite, id, list)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanGenres(rows *sql.Rows) ([]*models.Genre, error) {
+	defer rows.Close()
+	var genres []*models.Genre
+	for rows.Next() {
+		genre := models.Genre{}
+		err := rows.Scan(&genre.Id, &genre.Slug, &genre.Name)
+		if err != nil {
+			return nil, err
+		}
+		genres = append(genres, &genre)
+	}
+	return genres, rows.Err()
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"goflix/config"
+	"goflix/models"
+)
+
+const homeRowColumns = "id, position, kind, title, genre, size, enabled"
+
+// migrateDefaultHomeRows lays out the home screen as it was before rows were configurable.
+func (db *DbSqlite) migrateDefaultHomeRows(ctx context.Context) error {
+	rows := []*models.HomeRow{
+		{Kind: config.ROW_CONTINUE_WATCHING, Title: "Reprendre la lecture"},
+		{Kind: config.ROW_MY_LIST, Title: "Ma liste"},
+		{Kind: config.ROW_RECOMMENDED, Title: "Recommandés pour vous"},
+		{Kind: config.ROW_TOP10, Title: "Top 10 aujourd'hui"},
+		{Kind: config.ROW_TRENDING, Title: "Tendances actuelles"},
+		{Kind: config.ROW_NEW_RELEASES, Title: "Nouveautés"},
+	}
+	for i, row := range rows {
+		row.Position = i + 1
+		row.Size = config.HOME_ROW_DEFAULT_SIZE
+		row.Enabled = true
+		err := db.SaveHomeRow(ctx, row)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *DbSqlite) GetHomeRows(ctx context.Context, enabledOnly bool) ([]*models.HomeRow, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	query := "SELECT " + homeRowColumns + " FROM homerows"
+	if enabledOnly {
+		query += " WHERE enabled = 1"
+	}
+	rows, err := db.sqlite.QueryContext(ctx, query+" ORDER BY position, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var homeRows []*models.HomeRow
+	for rows.Next() {
+		row, err := scanHomeRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		homeRows = append(homeRows, row)
+	}
+	return homeRows, rows.Err()
+}
+
+func (db *DbSqlite) GetHomeRow(ctx context.Context, id int) (*models.HomeRow, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	row, err := scanHomeRow(db.sqlite.QueryRowContext(ctx, "SELECT "+homeRowColumns+" FROM homerows WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("home row %w", ErrNotFound)
+	}
+	return row, err
+}
+
+// SaveHomeRow creates the row when it has no id and updates it otherwise.
+func (db *DbSqlite) SaveHomeRow(ctx context.Context, row *models.HomeRow) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	if row.Id != 0 {
+		res, err := db.sqlite.ExecContext(ctx, "UPDATE homerows SET position = ?, kind = ?, title = ?, genre = ?, size = ?, enabled = ? WHERE id = ?",
+			row.Position, row.Kind, row.Title, row.Genre, row.Size, row.Enabled, row.Id)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); n < 1 || err != nil {
+			return fmt.Errorf("home row %w", ErrNotFound)
+		}
+		return nil
+	}
+	res, err := db.sqlite.ExecContext(ctx, "INSERT INTO homerows (position, kind, title, genre, size, enabled) VALUES (?, ?, ?, ?, ?, ?)",
+		row.Position, row.Kind, row.Title, row.Genre, row.Size, row.Enabled)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	row.Id = int(id)
+	return nil
+}
+
+func (db *DbSqlite) DeleteHomeRow(ctx context.Context, id int) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	res, err := db.sqlite.ExecContext(ctx, "DELETE FROM homerows WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); n < 1 || err != nil {
+		return fmt.Errorf("home row %w", ErrNotFound)
+	}
+	return nil
+}
+
+// GetRecentlyWatched returns the titles the user streamed, the last watched first.
+func (db *DbSqlite) GetRecentlyWatched(ctx context.Context, userID int, filter *models.CatalogFilter, offset int, limit int) ([]*models.Movies, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	where, args := catalogWhere(filter)
+	rows, err := db.sqlite.QueryContext(ctx, `SELECT `+qualifiedMovieColumns+` FROM movies
+		JOIN streamsessions ON streamsessions.movieid = movies.id
+		WHERE streamsessions.userid = ?`+where+`
+		GROUP BY movies.id ORDER BY MAX(streamsessions.started) DESC LIMIT ? OFFSET ?`,
+		append(append([]any{userID}, args...), limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	return scanMovies(rows)
+}
+
+// GetNewReleases returns the titles by publication, titles published before scheduling existed last.
+func (db *DbSqlite) GetNewReleases(ctx context.Context, filter *models.CatalogFilter, offset int, limit int) ([]*models.Movies, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	where, args := catalogWhere(filter)
+	rows, err := db.sqlite.QueryContext(ctx, `SELECT `+movieColumns+` FROM movies WHERE 1`+where+`
+		ORDER BY publishat IS NULL, publishat DESC, id DESC LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	return scanMovies(rows)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanHomeRow(row scanner) (*models.HomeRow, error) {
+	homeRow := models.HomeRow{}
+	err := row.Scan(&homeRow.Id, &homeRow.Position, &homeRow.Kind, &homeRow.Title, &homeRow.Genre, &homeRow.Size, &homeRow.Enabled)
+	if err != nil {
+		return nil, err
+	}
+	return &homeRow, nil
+}
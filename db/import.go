@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"goflix/config"
+	"goflix/models"
+)
+
+func (db *DbSqlite) migrateExternalID(ctx context.Context) error {
+	err := addColumns("movies", "externalid TEXT")(db, ctx)
+	if err != nil {
+		return err
+	}
+	_, err = db.sqlite.ExecContext(ctx, "CREATE UNIQUE INDEX IF NOT EXISTS movies_externalid ON movies (externalid)")
+	return err
+}
+
+// GetCatalog returns every movie and episode whatever its status, for exports.
+func (db *DbSqlite) GetCatalog(ctx context.Context) ([]*models.Movies, error) {
+	ctx, cancel := db.batchTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT "+movieColumns+" FROM movies ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	return scanMovies(rows)
+}
+
+// ImportMovies upserts the rows in a single transaction, each row in its own savepoint so a
+// failing row doesn't affect the others. Rows already holding an error are reported as failed.
+// Nothing is committed in dryRun, nor in atomic mode when a row failed; the returned bool tells
+// whether the import was committed.
+func (db *DbSqlite) ImportMovies(ctx context.Context, rows []*models.ImportRow, atomic bool, dryRun bool) (bool, error) {
+	ctx, cancel := db.batchTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	failed := false
+	for _, row := range rows {
+		if row.Error != "" {
+			row.Action = config.IMPORT_FAILED
+			failed = true
+			continue
+		}
+		_, err = tx.ExecContext(ctx, "SAVEPOINT import_row")
+		if err != nil {
+			return false, err
+		}
+		action, err := db.upsertMovie(ctx, tx, row.Movie)
+		if err != nil {
+			_, rollbackErr := tx.ExecContext(ctx, "ROLLBACK TO import_row")
+			if rollbackErr != nil {
+				return false, rollbackErr
+			}
+			row.Action = config.IMPORT_FAILED
+			row.Error = err.Error()
+			failed = true
+		} else {
+			row.Action = action
+			row.MovieId = row.Movie.Id
+		}
+		_, err = tx.ExecContext(ctx, "RELEASE import_row")
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if dryRun || (atomic && failed) {
+		// ids given to new titles are rolled back with them
+		for _, row := range rows {
+			if row.Action == config.IMPORT_CREATED {
+				row.MovieId = 0
+			}
+		}
+		return false, nil
+	}
+	return true, tx.Commit()
+}
+
+// upsertMovie matches the movie by external id, then by id, and creates it when neither matches.
+func (db *DbSqlite) upsertMovie(ctx context.Context, tx conn, movie *models.Movies) (string, error) {
+	var current *models.Movies
+	var err error
+	if movie.ExternalId != "" {
+		current, err = getMovieWhere(ctx, tx, "externalid = ?", movie.ExternalId)
+		if err != nil {
+			return "", err
+		}
+	}
+	if current == nil && movie.Id != 0 {
+		current, err = getMovieWhere(ctx, tx, "id = ?", movie.Id)
+		if err != nil {
+			return "", err
+		}
+		if current == nil {
+			return "", fmt.Errorf("movie %d %w", movie.Id, ErrNotFound)
+		}
+	}
+	if current == nil {
+		err = db.insertMovie(ctx, tx, movie)
+		if err != nil {
+			return "", err
+		}
+		return config.IMPORT_CREATED, nil
+	}
+
+	movie.Id = current.Id
+	movie.PublishAt = current.PublishAt
+	if movie.Poster == "" {
+		movie.Poster = current.Poster
+	}
+	if movie.ExternalId == "" {
+		movie.ExternalId = current.ExternalId
+	}
+	if movie.Status == "" {
+		movie.Status = current.Status
+	}
+	if sameMovie(current, movie) {
+		return config.IMPORT_UNCHANGED, nil
+	}
+	err = db.updateMovie(ctx, tx, current, movie)
+	if err != nil {
+		return "", err
+	}
+	return config.IMPORT_UPDATED, nil
+}
+
+// UpdateMovie saves the descriptive fields of a title, its actor credits and genres follow
+// the actors and genre lists. Status and publication date are left to the editorial workflow.
+func (db *DbSqlite) UpdateMovie(ctx context.Context, movie *models.Movies) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	current, err := getMovieWhere(ctx, tx, "id = ?", movie.Id)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return fmt.Errorf("movie %w", ErrNotFound)
+	}
+	movie.Status = current.Status
+	movie.PublishAt = current.PublishAt
+	err = db.updateMovie(ctx, tx, current, movie)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DbSqlite) updateMovie(ctx context.Context, tx conn, current *models.Movies, movie *models.Movies) error {
+	updateSQL := `UPDATE movies SET externalid = ?, title = ?, actors = ?, rating = ?, details = ?, genre = ?,
+		saison = ?, episode = ?, maturity = ?, maturitylevel = ?, status = ?, poster = ? WHERE id = ?`
+	_, err := tx.ExecContext(ctx, updateSQL, nullString(movie.ExternalId), movie.Title, movie.Actors, movie.Rating, movie.Details, movie.Genre,
+		movie.Saison, movie.Episode, movie.Maturity, movie.MaturityLevel, movie.Status, movie.Poster, movie.Id)
+	if err != nil {
+		return err
+	}
+	if movie.Actors != current.Actors {
+		_, err = tx.ExecContext(ctx, "DELETE FROM credits WHERE movieid = ? AND role = ?", movie.Id, config.CREDIT_ACTOR)
+		if err != nil {
+			return err
+		}
+		err = db.addActorCredits(ctx, tx, movie.Id, movie.Actors)
+		if err != nil {
+			return err
+		}
+	}
+	if movie.Genre != current.Genre {
+		_, err = tx.ExecContext(ctx, "DELETE FROM moviegenres WHERE movieid = ?", movie.Id)
+		if err != nil {
+			return err
+		}
+		err = db.addMovieGenres(ctx, tx, movie.Id, movie.Genre)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getMovieWhere(ctx context.Context, tx conn, where string, args ...any) (*models.Movies, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT "+movieColumns+" FROM movies WHERE "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	movies, err := scanMovies(rows)
+	if err != nil || len(movies) == 0 {
+		return nil, err
+	}
+	return movies[0], nil
+}
+
+func sameMovie(a *models.Movies, b *models.Movies) bool {
+	return a.ExternalId == b.ExternalId && a.Title == b.Title && a.Actors == b.Actors && a.Rating == b.Rating &&
+		a.Details == b.Details && a.Genre == b.Genre && a.Saison == b.Saison && a.Episode == b.Episode &&
+		a.Maturity == b.Maturity && a.MaturityLevel == b.MaturityLevel && a.Status == b.Status && a.Poster == b.Poster
+}
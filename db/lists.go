@@ -0,0 +1,413 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"goflix/config"
+	"goflix/metadata"
+	"goflix/models"
+)
+
+const listColumns = "id, userid, name, visibility, sharetoken, isdefault, created, updated, (SELECT COUNT(*) FROM listitems WHERE listid = lists.id)"
+
+// migrateFavoritesToLists turns each favorite set into the default list of its user, the last
+// added title first as the home screen showed it.
+func (db *DbSqlite) migrateFavoritesToLists(ctx context.Context) error {
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT userid, moviesid FROM favorite")
+	if err != nil {
+		return err
+	}
+	var favorites []*models.Favorite
+	for rows.Next() {
+		favorite := models.Favorite{}
+		err = rows.Scan(&favorite.UserId, &favorite.MoviesID)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		favorites = append(favorites, &favorite)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	now := time.Now().UTC()
+	for _, favorite := range favorites {
+		res, err := tx.ExecContext(ctx, "INSERT INTO lists (userid, name, visibility, isdefault, created, updated) VALUES (?, ?, ?, 1, ?, ?)",
+			favorite.UserId, config.LIST_DEFAULT_NAME, config.LIST_PRIVATE, now, now)
+		if err != nil {
+			return err
+		}
+		listID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		ids := favorite.MovieIds()
+		for i, id := range ids {
+			_, err = tx.ExecContext(ctx, "INSERT OR IGNORE INTO listitems (listid, movieid, position, added) VALUES (?, ?, ?, ?)",
+				listID, id, len(ids)-i, now)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// GetLists returns the lists of the user, the default list first.
+func (db *DbSqlite) GetLists(ctx context.Context, userID int) ([]*models.List, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT "+listColumns+" FROM lists WHERE userid = ? ORDER BY isdefault DESC, created, id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var lists []*models.List
+	for rows.Next() {
+		list, err := scanList(rows)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	return lists, rows.Err()
+}
+
+func (db *DbSqlite) GetList(ctx context.Context, id int) (*models.List, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	list, err := scanList(db.sqlite.QueryRowContext(ctx, "SELECT "+listColumns+" FROM lists WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("list %w", ErrNotFound)
+	}
+	return list, err
+}
+
+func (db *DbSqlite) GetListByToken(ctx context.Context, token string) (*models.List, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	list, err := scanList(db.sqlite.QueryRowContext(ctx, "SELECT "+listColumns+" FROM lists WHERE sharetoken = ?", token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("list %w", ErrNotFound)
+	}
+	return list, err
+}
+
+// GetDefaultList returns the list holding the favorites of the user, creating it on first use.
+func (db *DbSqlite) GetDefaultList(ctx context.Context, userID int) (*models.List, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	var list *models.List
+	err := db.withTx(ctx, func(tx *DbSqlite) error {
+		var err error
+		list, err = scanList(tx.sqlite.QueryRowContext(ctx, "SELECT "+listColumns+" FROM lists WHERE userid = ? AND isdefault = 1", userID))
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		list = &models.List{UserId: userID, Name: config.LIST_DEFAULT_NAME, Visibility: config.LIST_PRIVATE, IsDefault: true}
+		return tx.SaveList(ctx, list)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// SaveList creates the list when it has no id and updates its name and visibility otherwise.
+func (db *DbSqlite) SaveList(ctx context.Context, list *models.List) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	list.Updated = time.Now().UTC()
+	if list.Id != 0 {
+		res, err := db.sqlite.ExecContext(ctx, "UPDATE lists SET name = ?, visibility = ?, sharetoken = ?, updated = ? WHERE id = ?",
+			list.Name, list.Visibility, nullString(list.ShareToken), list.Updated, list.Id)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); n < 1 || err != nil {
+			return fmt.Errorf("list %w", ErrNotFound)
+		}
+		return nil
+	}
+	list.Created = list.Updated
+	res, err := db.sqlite.ExecContext(ctx, "INSERT INTO lists (userid, name, visibility, sharetoken, isdefault, created, updated) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		list.UserId, list.Name, list.Visibility, nullString(list.ShareToken), list.IsDefault, list.Created, list.Updated)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	list.Id = int(id)
+	return nil
+}
+
+func (db *DbSqlite) DeleteList(ctx context.Context, id int) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, "DELETE FROM lists WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); n < 1 || err != nil {
+		return fmt.Errorf("list %w", ErrNotFound)
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM listitems WHERE listid = ?", id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetListMovieIds returns the titles of the list in its order.
+func (db *DbSqlite) GetListMovieIds(ctx context.Context, listID int) ([]int, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	return listMovieIds(ctx, db.sqlite, listID)
+}
+
+// AddListItem inserts the title at position, 1 being the top of the list, positions out of
+// range put it at the top. It returns false when the title was already in the list.
+func (db *DbSqlite) AddListItem(ctx context.Context, listID int, movieID int, position int) (bool, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	ids, err := listMovieIds(ctx, tx, listID)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range ids {
+		if id == movieID {
+			return false, nil
+		}
+	}
+	if len(ids) >= config.LIST_MAX_ITEMS {
+		return false, fmt.Errorf("list holds %d titles at most: %w", config.LIST_MAX_ITEMS, ErrInvalid)
+	}
+	if position < 1 || position > len(ids)+1 {
+		position = 1
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE listitems SET position = position + 1 WHERE listid = ? AND position >= ?", listID, position)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now().UTC()
+	_, err = tx.ExecContext(ctx, "INSERT INTO listitems (listid, movieid, position, added) VALUES (?, ?, ?, ?)", listID, movieID, position, now)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE lists SET updated = ? WHERE id = ?", now, listID)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (db *DbSqlite) RemoveListItem(ctx context.Context, listID int, movieID int) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var position int
+	err = tx.QueryRowContext(ctx, "SELECT position FROM listitems WHERE listid = ? AND movieid = ?", listID, movieID).Scan(&position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("list item %w", ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM listitems WHERE listid = ? AND movieid = ?", listID, movieID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE listitems SET position = position - 1 WHERE listid = ? AND position > ?", listID, position)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE lists SET updated = ? WHERE id = ?", time.Now().UTC(), listID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReorderList sets the order of the list, movieIDs must hold each of its titles once.
+func (db *DbSqlite) ReorderList(ctx context.Context, listID int, movieIDs []int) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	ids, err := listMovieIds(ctx, tx, listID)
+	if err != nil {
+		return err
+	}
+	current := map[int]bool{}
+	for _, id := range ids {
+		current[id] = true
+	}
+	for _, id := range movieIDs {
+		if !current[id] {
+			return fmt.Errorf("title %d is not in the list or given twice: %w", id, ErrInvalid)
+		}
+		delete(current, id)
+	}
+	if len(current) > 0 {
+		return fmt.Errorf("the order must hold the %d titles of the list: %w", len(ids), ErrInvalid)
+	}
+	for i, id := range movieIDs {
+		_, err = tx.ExecContext(ctx, "UPDATE listitems SET position = ? WHERE listid = ? AND movieid = ?", i+1, listID, id)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE lists SET updated = ? WHERE id = ?", time.Now().UTC(), listID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetFavoriteByUser reads the default list of the user as favorites, in the order titles were added.
+func (db *DbSqlite) GetFavoriteByUser(ctx context.Context, favorite *models.Favorite) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, `SELECT listitems.movieid FROM listitems
+		JOIN lists ON lists.id = listitems.listid
+		WHERE lists.userid = ? AND lists.isdefault = 1
+		ORDER BY listitems.added, listitems.position DESC`, favorite.UserId)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	favorite.MoviesID = ""
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			return err
+		}
+		favorite.MoviesID += fmt.Sprintf("#%d|", id)
+	}
+	return rows.Err()
+}
+
+// GetSeriesFans returns the users having an episode of the series in their default list.
+func (db *DbSqlite) GetSeriesFans(ctx context.Context, seriesID string) ([]int, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	pattern := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(seriesID) + ":s%"
+	rows, err := db.sqlite.QueryContext(ctx, `SELECT DISTINCT lists.userid FROM listitems
+		JOIN lists ON lists.id = listitems.listid
+		JOIN movies ON movies.id = listitems.movieid
+		WHERE lists.isdefault = 1 AND movies.externalid LIKE ? ESCAPE '\'`, pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var users []int
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, id)
+	}
+	return users, rows.Err()
+}
+
+// GetSeriesFansByTitle returns the users having an episode of the series in their default list,
+// the episodes being found by their series title, see metadata.SeriesTitle.
+func (db *DbSqlite) GetSeriesFansByTitle(ctx context.Context, title string) ([]int, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	if title == "" {
+		return nil, nil
+	}
+	// LIKE narrows to the titles starting with the series title, the suffix is checked below
+	pattern := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(title) + "%"
+	rows, err := db.sqlite.QueryContext(ctx, `SELECT DISTINCT lists.userid, movies.title FROM listitems
+		JOIN lists ON lists.id = listitems.listid
+		JOIN movies ON movies.id = listitems.movieid
+		WHERE lists.isdefault = 1 AND movies.saison > 0 AND movies.title LIKE ? ESCAPE '\'`, pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var users []int
+	seen := map[int]bool{}
+	for rows.Next() {
+		var id int
+		var episode string
+		err = rows.Scan(&id, &episode)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[id] && strings.EqualFold(metadata.SeriesTitle(episode), title) {
+			seen[id] = true
+			users = append(users, id)
+		}
+	}
+	return users, rows.Err()
+}
+
+func listMovieIds(ctx context.Context, q querier, listID int) ([]int, error) {
+	rows, err := q.QueryContext(ctx, "SELECT movieid FROM listitems WHERE listid = ? ORDER BY position", listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func scanList(row scanner) (*models.List, error) {
+	list := models.List{}
+	var token sql.NullString
+	err := row.Scan(&list.Id, &list.UserId, &list.Name, &list.Visibility, &token, &list.IsDefault, &list.Created, &list.Updated, &list.Count)
+	if err != nil {
+		return nil, err
+	}
+	list.ShareToken = token.String
+	return &list, nil
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"goflix/models"
+)
+
+const mediaFileColumns = "id, movieid, path, size, modtime, scannedat"
+
+func (db *DbSqlite) GetMediaFiles(ctx context.Context) ([]*models.MediaFile, error) {
+	ctx, cancel := db.batchTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT "+mediaFileColumns+" FROM mediafiles ORDER BY path")
+	if err != nil {
+		return nil, err
+	}
+	return scanMediaFiles(rows)
+}
+
+func (db *DbSqlite) GetMediaFilesByMovie(ctx context.Context, movieID int) ([]*models.MediaFile, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT "+mediaFileColumns+" FROM mediafiles WHERE movieid = ? ORDER BY path", movieID)
+	if err != nil {
+		return nil, err
+	}
+	return scanMediaFiles(rows)
+}
+
+// SaveMediaFile creates or updates the file with the same path.
+func (db *DbSqlite) SaveMediaFile(ctx context.Context, file *models.MediaFile) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	upsertSQL := `INSERT INTO mediafiles (movieid, path, size, modtime, scannedat) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (path) DO UPDATE SET movieid = excluded.movieid, size = excluded.size,
+		modtime = excluded.modtime, scannedat = excluded.scannedat`
+	_, err := db.sqlite.ExecContext(ctx, upsertSQL, file.MovieId, file.Path, file.Size, file.ModTime.UTC(), file.ScannedAt.UTC())
+	if err != nil {
+		return err
+	}
+	return db.sqlite.QueryRowContext(ctx, "SELECT id FROM mediafiles WHERE path = ?", file.Path).Scan(&file.Id)
+}
+
+func (db *DbSqlite) DeleteMediaFile(ctx context.Context, path string) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	res, err := db.sqlite.ExecContext(ctx, "DELETE FROM mediafiles WHERE path = ?", path)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); n < 1 || err != nil {
+		return fmt.Errorf("media file %s %w", path, ErrNotFound)
+	}
+	return nil
+}
+
+func scanMediaFiles(rows *sql.Rows) ([]*models.MediaFile, error) {
+	defer rows.Close()
+	var files []*models.MediaFile
+	for rows.Next() {
+		file := models.MediaFile{}
+		err := rows.Scan(&file.Id, &file.MovieId, &file.Path, &file.Size, &file.ModTime, &file.ScannedAt)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, &file)
+	}
+	return files, rows.Err()
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"goflix/config"
+)
+
+type migration struct {
+	name string
+	up   func(db *DbSqlite, ctx context.Context) error
+}
+
+// migrations run once each, in order, on top of the CREATE TABLE statements.
+// Append new ones at the end, never edit or reorder the ones already released.
+var migrations = []migration{
+	{"movies_maturity", addColumns("movies",
+		"maturity TEXT DEFAULT ''",
+		fmt.Sprintf("maturitylevel INTEGER DEFAULT %d", config.MATURITY_UNRATED_LEVEL))},
+	// titles created before the editorial workflow stay live
+	{"movies_editorial_status", addColumns("movies",
+		fmt.Sprintf("status TEXT DEFAULT '%s'", config.STATUS_PUBLISHED),
+		"publishat DATETIME")},
+	{"credits_from_actors", (*DbSqlite).migrateActorsToCredits},
+	{"genres_from_genre", (*DbSqlite).migrateGenreColumn},
+	{"usersettings_language", addColumns("usersettings", "language TEXT DEFAULT ''")},
+	{"movies_externalid", (*DbSqlite).migrateExternalID},
+	{"movies_poster", addColumns("movies", "poster TEXT DEFAULT ''")},
+	{"rating_primary_key", (*DbSqlite).migrateRatingKey},
+	{"catalog_version_triggers", (*DbSqlite).migrateCatalogVersion},
+	{"homerows_defaults", (*DbSqlite).migrateDefaultHomeRows},
+	{"lists_from_favorites", (*DbSqlite).migrateFavoritesToLists},
+	{"parentalcontrols_pin_lockout", addColumns("parentalcontrols",
+		"pinattempts INTEGER DEFAULT 0",
+		"pinlockeduntil DATETIME")},
+	{"people_name_nocase", (*DbSqlite).migratePeopleNoCase},
+	{"subscriptions_cancel_at_period_end", addColumns("subscriptions", "cancelatperiodend INTEGER DEFAULT 0")},
+}
+
+func (db *DbSqlite) Migrate(ctx context.Context) error {
+	_, err := db.sqlite.ExecContext(ctx, config.CREATE_TABLE_MIGRATIONS)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		var n int
+		err = db.sqlite.QueryRowContext(ctx, "SELECT COUNT(*) FROM migrations WHERE name = ?", m.name).Scan(&n)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		err = m.up(db, ctx)
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", m.name, err)
+		}
+		_, err = db.sqlite.ExecContext(ctx, "INSERT INTO migrations (name, applied) VALUES (?, ?)", m.name, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("migration %s applied!\n", m.name)
+	}
+	return nil
+}
+
+func addColumns(table string, columns ...string) func(db *DbSqlite, ctx context.Context) error {
+	return func(db *DbSqlite, ctx context.Context) error {
+		for _, column := range columns {
+			_, err := db.sqlite.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, column))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"goflix/config"
+	"goflix/models"
+)
+
+const notificationSelect = `SELECT notifications.id, notifications.userid, notifications.kind, notifications.movieid,
+	IFNULL(movies.title, ''), IFNULL(movies.saison, 0), IFNULL(movies.episode, 0), notifications.ends,
+	notifications.inapp, notifications.email, notifications.created, notifications.readat
+	FROM notifications
+	LEFT JOIN movies ON movies.id = notifications.movieid`
+
+// AddNotification stores the notification, it returns false when the user was already notified
+// of this kind for the title.
+func (db *DbSqlite) AddNotification(ctx context.Context, notification *models.Notification) (bool, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	notification.Created = time.Now().UTC()
+	res, err := db.sqlite.ExecContext(ctx, "INSERT OR IGNORE INTO notifications (userid, kind, movieid, ends, inapp, email, created) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		notification.UserId, notification.Kind, notification.MovieId, notification.Ends, notification.InApp, notification.Email, notification.Created)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); n < 1 || err != nil {
+		return false, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	notification.Id = int(id)
+	return true, nil
+}
+
+// GetNotifications returns a page of the in-app notifications of the user, the last first, their
+// total and how many are unread.
+func (db *DbSqlite) GetNotifications(ctx context.Context, userID int, unreadOnly bool, offset int, limit int) ([]*models.Notification, int, int, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	where := " WHERE notifications.userid = ? AND notifications.inapp = 1"
+	var total, unread int
+	err := db.sqlite.QueryRowContext(ctx, "SELECT COUNT(*), COUNT(*) - COUNT(readat) FROM notifications"+where, userID).Scan(&total, &unread)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if unreadOnly {
+		where += " AND notifications.readat IS NULL"
+		total = unread
+	}
+	rows, err := db.sqlite.QueryContext(ctx, notificationSelect+where+" ORDER BY notifications.created DESC, notifications.id DESC LIMIT ? OFFSET ?", userID, limit, offset)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	notifications, err := scanNotifications(rows)
+	return notifications, total, unread, err
+}
+
+// MarkNotificationRead marks the in-app notification of the user as read, or unread.
+func (db *DbSqlite) MarkNotificationRead(ctx context.Context, userID int, id int, read bool) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	var readAt any
+	if read {
+		readAt = time.Now().UTC()
+	}
+	res, err := db.sqlite.ExecContext(ctx, "UPDATE notifications SET readat = ? WHERE id = ? AND userid = ? AND inapp = 1", readAt, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); n < 1 || err != nil {
+		return fmt.Errorf("notification %w", ErrNotFound)
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead marks the unread in-app notifications of the user as read and returns how many.
+func (db *DbSqlite) MarkAllNotificationsRead(ctx context.Context, userID int) (int, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	res, err := db.sqlite.ExecContext(ctx, "UPDATE notifications SET readat = ? WHERE userid = ? AND inapp = 1 AND readat IS NULL", time.Now().UTC(), userID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// GetPendingEmailNotifications returns the notifications waiting for the email digest, grouped
+// by user, the oldest first.
+func (db *DbSqlite) GetPendingEmailNotifications(ctx context.Context) ([]*models.Notification, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, notificationSelect+" WHERE notifications.email = 1 AND notifications.emailedat IS NULL ORDER BY notifications.userid, notifications.created, notifications.id")
+	if err != nil {
+		return nil, err
+	}
+	return scanNotifications(rows)
+}
+
+func (db *DbSqlite) MarkNotificationsEmailed(ctx context.Context, ids []int, at time.Time) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	if len(ids) == 0 {
+		return nil
+	}
+	args := []any{at.UTC()}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	_, err := db.sqlite.ExecContext(ctx, "UPDATE notifications SET emailedat = ? WHERE id IN (?"+strings.Repeat(", ?", len(ids)-1)+")", args...)
+	return err
+}
+
+// GetNotificationPreferences returns the channels of each kind for the user, defaults filling
+// the preferences not set.
+func (db *DbSqlite) GetNotificationPreferences(ctx context.Context, userID int) (models.NotificationPreferences, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	prefs := models.NotificationPreferences{}
+	for _, kind := range config.NOTIFY_KINDS {
+		prefs[kind] = map[string]bool{}
+		for _, channel := range config.NOTIFY_CHANNELS {
+			prefs[kind][channel] = config.NOTIFY_DEFAULTS[channel]
+		}
+	}
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT kind, channel, enabled FROM notificationprefs WHERE userid = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var kind, channel string
+		var enabled bool
+		err = rows.Scan(&kind, &channel, &enabled)
+		if err != nil {
+			return nil, err
+		}
+		if channels, ok := prefs[kind]; ok {
+			channels[channel] = enabled
+		}
+	}
+	return prefs, rows.Err()
+}
+
+// SaveNotificationPreferences sets the channels given for the user, the others are kept.
+func (db *DbSqlite) SaveNotificationPreferences(ctx context.Context, userID int, prefs models.NotificationPreferences) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for kind, channels := range prefs {
+		for channel, enabled := range channels {
+			_, err = tx.ExecContext(ctx, `INSERT INTO notificationprefs (userid, kind, channel, enabled) VALUES (?, ?, ?, ?)
+				ON CONFLICT(userid, kind, channel) DO UPDATE SET enabled = excluded.enabled`, userID, kind, channel, enabled)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func scanNotifications(rows *sql.Rows) ([]*models.Notification, error) {
+	defer rows.Close()
+	notifications := []*models.Notification{}
+	for rows.Next() {
+		notification := models.Notification{}
+		var ends, readAt sql.NullTime
+		err := rows.Scan(&notification.Id, &notification.UserId, &notification.Kind, &notification.MovieId,
+			&notification.Title, &notification.Saison, &notification.Episode, &ends,
+			&notification.InApp, &notification.Email, &notification.Created, &readAt)
+		if err != nil {
+			return nil, err
+		}
+		if ends.Valid {
+			notification.Ends = &ends.Time
+		}
+		if readAt.Valid {
+			notification.ReadAt = &readAt.Time
+		}
+		notifications = append(notifications, &notification)
+	}
+	return notifications, rows.Err()
+}
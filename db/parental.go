@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"goflix/config"
+	"goflix/models"
+	"time"
+)
+
+// GetParentalControl returns the user's settings, unrestricted and without PIN when never saved.
+func (db *DbSqlite) GetParentalControl(ctx context.Context, userID int) (*models.ParentalControl, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.queryCached(ctx, "SELECT userid, maxlevel, pin FROM parentalcontrols WHERE userid = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	control := models.ParentalControl{UserId: userID, MaxLevel: config.MATURITY_UNRATED_LEVEL}
+	if rows.Next() {
+		err = rows.Scan(&control.UserId, &control.MaxLevel, &control.Pin)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &control, nil
+}
+
+func (db *DbSqlite) SaveParentalControl(ctx context.Context, control *models.ParentalControl) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	upsertSQL := "INSERT INTO parentalcontrols (userid, maxlevel, pin) VALUES (?, ?, ?) ON CONFLICT(userid) DO UPDATE SET maxlevel = excluded.maxlevel, pin = excluded.pin"
+	_, err := db.sqlite.ExecContext(ctx, upsertSQL, control.UserId, control.MaxLevel, control.Pin)
+	return err
+}
+
+// TakePinAttempt counts a PIN attempt before it is checked, so that concurrent guesses cannot get
+// past the limit. The PIN_MAX_ATTEMPTS-th attempt in a row locks the controls for PIN_LOCKOUT
+// unless it succeeds, a locked user gets the end of the lockout and the attempt is not counted.
+func (db *DbSqlite) TakePinAttempt(ctx context.Context, userID int) (*time.Time, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var attempts int
+	var lockedUntil sql.NullTime
+	err = tx.QueryRowContext(ctx, "SELECT pinattempts, pinlockeduntil FROM parentalcontrols WHERE userid = ?", userID).Scan(&attempts, &lockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if lockedUntil.Valid {
+		if now.Before(lockedUntil.Time) {
+			return &lockedUntil.Time, nil
+		}
+		attempts = 0
+		lockedUntil.Valid = false
+	}
+	attempts++
+	if attempts >= config.PIN_MAX_ATTEMPTS {
+		attempts = 0
+		lockedUntil = sql.NullTime{Time: now.Add(config.PIN_LOCKOUT), Valid: true}
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE parentalcontrols SET pinattempts = ?, pinlockeduntil = ? WHERE userid = ?", attempts, lockedUntil, userID)
+	if err != nil {
+		return nil, err
+	}
+	return nil, tx.Commit()
+}
+
+// ResetPinAttempts forgets the wrong attempts and lifts the lockout, after a right PIN.
+func (db *DbSqlite) ResetPinAttempts(ctx context.Context, userID int) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	_, err := db.sqlite.ExecContext(ctx, "UPDATE parentalcontrols SET pinattempts = 0, pinlockeduntil = NULL WHERE userid = ?", userID)
+	return err
+}
@@ -0,0 +1,237 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"goflix/config"
+	"goflix/models"
+)
+
+const creditColumns = "credits.id, credits.personid, people.name, credits.movieid, movies.title, credits.role, credits.character, credits.billing"
+
+func (db *DbSqlite) GetPerson(ctx context.Context, id int) (*models.Person, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	var person models.Person
+	err := db.sqlite.QueryRowContext(ctx, "SELECT id, name FROM people WHERE id = ?", id).Scan(&person.Id, &person.Name)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("person %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &person, nil
+}
+
+// SavePerson finds a person by name, creating it when missing.
+func (db *DbSqlite) SavePerson(ctx context.Context, person *models.Person) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	return db.savePerson(ctx, db.sqlite, person)
+}
+
+func (db *DbSqlite) savePerson(ctx context.Context, exec execer, person *models.Person) error {
+	person.Name = strings.TrimSpace(person.Name)
+	if person.Name == "" {
+		return fmt.Errorf("%w: person name is empty", ErrInvalid)
+	}
+	_, err := exec.ExecContext(ctx, "INSERT OR IGNORE INTO people (name) VALUES (?)", person.Name)
+	if err != nil {
+		return err
+	}
+	return exec.QueryRowContext(ctx, "SELECT id FROM people WHERE name = ?", person.Name).Scan(&person.Id)
+}
+
+func (db *DbSqlite) AddCredit(ctx context.Context, credit *models.Credit) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	insertSQL := "INSERT INTO credits (personid, movieid, role, character, billing) VALUES (?, ?, ?, ?, ?)"
+	res, err := tx.ExecContext(ctx, insertSQL, credit.PersonId, credit.MovieId, credit.Role, credit.Character, credit.Billing)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	credit.Id = int(id)
+	err = syncActors(ctx, tx, credit.MovieId)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DbSqlite) DeleteCredit(ctx context.Context, movieID int, id int) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	result, err := tx.ExecContext(ctx, "DELETE FROM credits WHERE id = ? AND movieid = ?", id, movieID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected != 1 {
+		return fmt.Errorf("errors want delete 1 reccord got: %d: %w", rowsAffected, ErrNotFound)
+	}
+	err = syncActors(ctx, tx, movieID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DbSqlite) GetCreditsByMovie(ctx context.Context, movieID int) ([]*models.Credit, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, `SELECT `+creditColumns+` FROM credits
+		JOIN people ON people.id = credits.personid
+		JOIN movies ON movies.id = credits.movieid
+		WHERE credits.movieid = ? ORDER BY credits.role, credits.billing, credits.id`, movieID)
+	if err != nil {
+		return nil, err
+	}
+	return scanCredits(rows)
+}
+
+// GetCreditsByPerson returns the filmography of a person restricted to the titles visible through filter.
+func (db *DbSqlite) GetCreditsByPerson(ctx context.Context, personID int, filter *models.CatalogFilter) ([]*models.Credit, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	where, args := catalogWhere(filter)
+	rows, err := db.sqlite.QueryContext(ctx, `SELECT `+creditColumns+` FROM credits
+		JOIN people ON people.id = credits.personid
+		JOIN movies ON movies.id = credits.movieid
+		WHERE credits.personid = ?`+where+` ORDER BY movies.title, credits.role`, append([]any{personID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	return scanCredits(rows)
+}
+
+func scanCredits(rows *sql.Rows) ([]*models.Credit, error) {
+	defer rows.Close()
+	var credits []*models.Credit
+	for rows.Next() {
+		credit := models.Credit{}
+		err := rows.Scan(&credit.Id, &credit.PersonId, &credit.Name, &credit.MovieId, &credit.Title,
+			&credit.Role, &credit.Character, &credit.Billing)
+		if err != nil {
+			return nil, err
+		}
+		credits = append(credits, &credit)
+	}
+	return credits, rows.Err()
+}
+
+// addActorCredits turns a free-text actors list into people credited as actors.
+func (db *DbSqlite) addActorCredits(ctx context.Context, exec execer, movieID int, actors string) error {
+	for i, name := range splitNames(actors) {
+		person := models.Person{Name: name}
+		err := db.savePerson(ctx, exec, &person)
+		if err != nil {
+			return err
+		}
+		_, err = exec.ExecContext(ctx, "INSERT INTO credits (personid, movieid, role, character, billing) VALUES (?, ?, ?, '', ?)",
+			person.Id, movieID, config.CREDIT_ACTOR, i+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncActors keeps the legacy actors column in line with the actor credits.
+func syncActors(ctx context.Context, exec execer, movieID int) error {
+	_, err := exec.ExecContext(ctx, `UPDATE movies SET actors = COALESCE((SELECT group_concat(name, ', ') FROM (
+			SELECT people.name FROM credits JOIN people ON people.id = credits.personid
+			WHERE credits.movieid = ? AND credits.role = ? ORDER BY credits.billing, credits.id)), '')
+		WHERE id = ?`, movieID, config.CREDIT_ACTOR, movieID)
+	return err
+}
+
+// splitNames splits a legacy free-text list such as the actors or genre columns.
+func splitNames(list string) []string {
+	for _, sep := range config.LIST_SEPARATORS[1:] {
+		list = strings.ReplaceAll(list, sep, config.LIST_SEPARATORS[0])
+	}
+	var names []string
+	seen := map[string]bool{}
+	for _, name := range strings.Split(list, config.LIST_SEPARATORS[0]) {
+		name = strings.Join(strings.Fields(name), " ")
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+func (db *DbSqlite) migrateActorsToCredits(ctx context.Context) error {
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT id, actors FROM movies WHERE actors IS NOT NULL AND actors != ''")
+	if err != nil {
+		return err
+	}
+	actors := map[int]string{}
+	for rows.Next() {
+		var id int
+		var list string
+		err = rows.Scan(&id, &list)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		actors[id] = list
+	}
+	rows.Close()
+	for id, list := range actors {
+		err = db.addActorCredits(ctx, db.sqlite, id, list)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratePeopleNoCase makes people names unique whatever their case, as splitNames compares them,
+// the people differing only by case are merged into the first one created.
+func (db *DbSqlite) migratePeopleNoCase(ctx context.Context) error {
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	statements := []string{
+		"ALTER TABLE people RENAME TO people_old",
+		config.CREATE_TABLE_PEOPLE,
+		`UPDATE credits SET personid = (SELECT MIN(other.id) FROM people_old person
+			JOIN people_old other ON other.name = person.name COLLATE NOCASE WHERE person.id = credits.personid)
+		WHERE personid IN (SELECT id FROM people_old)`,
+		// the bare name comes from the row of MIN(id)
+		"INSERT INTO people (id, name) SELECT MIN(id), name FROM people_old GROUP BY name COLLATE NOCASE",
+		"DROP TABLE people_old",
+	}
+	for _, statement := range statements {
+		_, err = tx.ExecContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,241 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"goflix/config"
+	"goflix/models"
+)
+
+// migrateRatingKey rebuilds the rating table, its primary key on movieid alone kept a
+// single rating per title across all users.
+func (db *DbSqlite) migrateRatingKey(ctx context.Context) error {
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	statements := []string{
+		"ALTER TABLE rating RENAME TO rating_old",
+		config.CREATE_TABLE_RATING,
+		"INSERT OR REPLACE INTO rating (movieid, stars, userid) SELECT movieid, stars, userid FROM rating_old",
+		"DROP TABLE rating_old",
+	}
+	for _, statement := range statements {
+		_, err = tx.ExecContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (db *DbSqlite) GetAllRatings(ctx context.Context) ([]*models.Rating, error) {
+	ctx, cancel := db.batchTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT movieid, stars, userid FROM rating")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ratings []*models.Rating
+	for rows.Next() {
+		rating := models.Rating{}
+		err = rows.Scan(&rating.MovieId, &rating.Stars, &rating.UserId)
+		if err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, &rating)
+	}
+	return ratings, rows.Err()
+}
+
+// GetMovieFeatures lists the genres and credits of every title, e.g. "genre:3" or "actor:12".
+func (db *DbSqlite) GetMovieFeatures(ctx context.Context) (map[int][]string, error) {
+	ctx, cancel := db.batchTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, `SELECT movieid, 'genre:' || genreid FROM moviegenres
+		UNION ALL SELECT DISTINCT movieid, role || ':' || personid FROM credits`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	features := map[int][]string{}
+	for rows.Next() {
+		var movieID int
+		var feature string
+		err = rows.Scan(&movieID, &feature)
+		if err != nil {
+			return nil, err
+		}
+		features[movieID] = append(features[movieID], feature)
+	}
+	return features, rows.Err()
+}
+
+// SaveSimilarities replaces the model of the given kind.
+func (db *DbSqlite) SaveSimilarities(ctx context.Context, kind string, similarities []*models.Similarity) error {
+	ctx, cancel := db.batchTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, "DELETE FROM similarities WHERE kind = ?", kind)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO similarities (kind, movieid, similarid, score) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, similarity := range similarities {
+		_, err = stmt.ExecContext(ctx, kind, similarity.MovieId, similarity.SimilarId, similarity.Score)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetSimilarities returns the neighbours of the given titles, best first.
+func (db *DbSqlite) GetSimilarities(ctx context.Context, kind string, movieIDs []int) ([]*models.Similarity, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	if len(movieIDs) == 0 {
+		return nil, nil
+	}
+	in, args := inIds(movieIDs)
+	rows, err := db.sqlite.QueryContext(ctx, `SELECT movieid, similarid, score FROM similarities
+		WHERE kind = ? AND movieid IN `+in+` ORDER BY score DESC`, append([]any{kind}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var similarities []*models.Similarity
+	for rows.Next() {
+		similarity := models.Similarity{}
+		err = rows.Scan(&similarity.MovieId, &similarity.SimilarId, &similarity.Score)
+		if err != nil {
+			return nil, err
+		}
+		similarities = append(similarities, &similarity)
+	}
+	return similarities, rows.Err()
+}
+
+// GetMoviesByIds returns the titles visible through filter, in the order of ids.
+func (db *DbSqlite) GetMoviesByIds(ctx context.Context, ids []int, filter *models.CatalogFilter) ([]*models.Movies, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	in, args := inIds(ids)
+	where, filterArgs := catalogWhere(filter)
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT "+movieColumns+" FROM movies WHERE id IN "+in+where,
+		append(args, filterArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	found, err := scanMovies(rows)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[int]*models.Movies{}
+	for _, movie := range found {
+		byID[movie.Id] = movie
+	}
+	movies := make([]*models.Movies, 0, len(found))
+	for _, id := range ids {
+		if movie, ok := byID[id]; ok {
+			movies = append(movies, movie)
+			delete(byID, id)
+		}
+	}
+	return movies, nil
+}
+
+// GetPopularMovies ranks the titles by number of ratings, then by average rating.
+func (db *DbSqlite) GetPopularMovies(ctx context.Context, filter *models.CatalogFilter, limit int) ([]*models.Movies, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	where, args := catalogWhere(filter)
+	rows, err := db.sqlite.QueryContext(ctx, `SELECT `+qualifiedMovieColumns+` FROM movies
+		JOIN rating ON rating.movieid = movies.id WHERE 1`+where+`
+		GROUP BY movies.id ORDER BY COUNT(*) DESC, AVG(rating.stars) DESC, movies.id LIMIT ?`,
+		append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	return scanMovies(rows)
+}
+
+// GetAllFavorites returns the titles of the default list of every user, by user id.
+func (db *DbSqlite) GetAllFavorites(ctx context.Context) (map[int][]int, error) {
+	ctx, cancel := db.batchTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, `SELECT lists.userid, listitems.movieid FROM listitems
+		JOIN lists ON lists.id = listitems.listid WHERE lists.isdefault = 1
+		ORDER BY lists.userid, listitems.position`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	favorites := map[int][]int{}
+	for rows.Next() {
+		var userID, movieID int
+		err = rows.Scan(&userID, &movieID)
+		if err != nil {
+			return nil, err
+		}
+		favorites[userID] = append(favorites[userID], movieID)
+	}
+	return favorites, rows.Err()
+}
+
+// migrateCatalogVersion makes every change to titles, their genres or their credits bump the
+// catalog version, whichever process makes it.
+func (db *DbSqlite) migrateCatalogVersion(ctx context.Context) error {
+	_, err := db.sqlite.ExecContext(ctx, "INSERT OR IGNORE INTO catalogversion (id, version) VALUES (1, 0)")
+	if err != nil {
+		return err
+	}
+	for _, table := range []string{"movies", "moviegenres", "credits"} {
+		for _, event := range []string{"INSERT", "UPDATE", "DELETE"} {
+			_, err = db.sqlite.ExecContext(ctx, fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS catalogversion_%[1]s_%[2]s
+				AFTER %[2]s ON %[1]s BEGIN UPDATE catalogversion SET version = version + 1 WHERE id = 1; END`,
+				table, strings.ToLower(event)))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (db *DbSqlite) CatalogVersion(ctx context.Context) (int, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	stmt, err := db.stmt(ctx, "SELECT version FROM catalogversion WHERE id = 1")
+	if err != nil {
+		return 0, err
+	}
+	var version int
+	err = stmt.QueryRowContext(ctx).Scan(&version)
+	return version, err
+}
+
+// inIds returns the placeholders of an IN lookup on ids, e.g. "(?, ?)", and their arguments,
+// so that the lookup goes through the index of the column.
+func inIds(ids []int) (string, []any) {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return "(?" + strings.Repeat(", ?", len(ids)-1) + ")", args
+}
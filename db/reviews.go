@@ -0,0 +1,248 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"goflix/config"
+	"goflix/models"
+)
+
+// reviewSelect reads the stars from the rating of the author and counts the reports made
+// since the last moderation.
+const reviewSelect = `SELECT reviews.id, reviews.movieid, reviews.userid, IFNULL(users.user, ''), IFNULL(rating.stars, 0) AS stars,
+	reviews.body, reviews.spoiler, reviews.status, reviews.flag,
+	(SELECT COUNT(*) FROM reviewvotes WHERE reviewvotes.reviewid = reviews.id) AS helpful,
+	(SELECT COUNT(*) FROM reviewreports WHERE reviewreports.reviewid = reviews.id
+		AND (reviews.moderatedat IS NULL OR reviewreports.created > reviews.moderatedat)),
+	reviews.created, reviews.updated, reviews.moderatedby, reviews.moderatedat, reviews.moderationnote
+	FROM reviews
+	LEFT JOIN users ON users.id = reviews.userid
+	LEFT JOIN rating ON rating.movieid = reviews.movieid AND rating.userid = reviews.userid`
+
+var reviewOrders = map[string]string{
+	config.REVIEW_SORT_RECENT:     "reviews.created DESC, reviews.id DESC",
+	config.REVIEW_SORT_OLDEST:     "reviews.created, reviews.id",
+	config.REVIEW_SORT_HELPFUL:    "helpful DESC, reviews.created DESC, reviews.id DESC",
+	config.REVIEW_SORT_STARS_DESC: "stars DESC, reviews.created DESC, reviews.id DESC",
+	config.REVIEW_SORT_STARS_ASC:  "stars, reviews.created DESC, reviews.id DESC",
+}
+
+// GetReviews returns a page of the reviews matching query and their total.
+func (db *DbSqlite) GetReviews(ctx context.Context, query *models.ReviewQuery, offset int, limit int) ([]*models.Review, int, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	where := " WHERE 1"
+	var args []any
+	if query.MovieId != 0 {
+		where += " AND reviews.movieid = ?"
+		args = append(args, query.MovieId)
+	}
+	if query.Status != "" {
+		where += " AND reviews.status = ?"
+		args = append(args, query.Status)
+	}
+	if !query.Spoilers {
+		where += " AND reviews.spoiler = 0"
+	}
+	order, ok := reviewOrders[query.Sort]
+	if !ok {
+		order = reviewOrders[config.REVIEW_SORT_RECENT]
+	}
+
+	var total int
+	err := db.sqlite.QueryRowContext(ctx, "SELECT COUNT(*) FROM reviews"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+	rows, err := db.sqlite.QueryContext(ctx, reviewSelect+where+" ORDER BY "+order+" LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	reviews := []*models.Review{}
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, total, rows.Err()
+}
+
+func (db *DbSqlite) GetReview(ctx context.Context, id int) (*models.Review, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	review, err := scanReview(db.sqlite.QueryRowContext(ctx, reviewSelect+" WHERE reviews.id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("review %w", ErrNotFound)
+	}
+	return review, err
+}
+
+func (db *DbSqlite) GetReviewByUser(ctx context.Context, movieID int, userID int) (*models.Review, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	review, err := scanReview(db.sqlite.QueryRowContext(ctx, reviewSelect+" WHERE reviews.movieid = ? AND reviews.userid = ?", movieID, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("review %w", ErrNotFound)
+	}
+	return review, err
+}
+
+// SaveReview creates the review when it has no id and updates its text and status otherwise.
+func (db *DbSqlite) SaveReview(ctx context.Context, review *models.Review) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	review.Updated = time.Now().UTC()
+	if review.Id != 0 {
+		res, err := db.sqlite.ExecContext(ctx, "UPDATE reviews SET body = ?, spoiler = ?, status = ?, flag = ?, updated = ? WHERE id = ?",
+			review.Body, review.Spoiler, review.Status, review.Flag, review.Updated, review.Id)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); n < 1 || err != nil {
+			return fmt.Errorf("review %w", ErrNotFound)
+		}
+		return nil
+	}
+	review.Created = review.Updated
+	res, err := db.sqlite.ExecContext(ctx, "INSERT INTO reviews (movieid, userid, body, spoiler, status, flag, created, updated) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		review.MovieId, review.UserId, review.Body, review.Spoiler, review.Status, review.Flag, review.Created, review.Updated)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	review.Id = int(id)
+	return nil
+}
+
+func (db *DbSqlite) DeleteReview(ctx context.Context, id int) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, "DELETE FROM reviews WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); n < 1 || err != nil {
+		return fmt.Errorf("review %w", ErrNotFound)
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM reviewvotes WHERE reviewid = ?", id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM reviewreports WHERE reviewid = ?", id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// VoteReview marks the review as helpful for the user, or takes the vote back.
+func (db *DbSqlite) VoteReview(ctx context.Context, reviewID int, userID int, helpful bool) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	if helpful {
+		_, err := db.sqlite.ExecContext(ctx, "INSERT OR IGNORE INTO reviewvotes (reviewid, userid) VALUES (?, ?)", reviewID, userID)
+		return err
+	}
+	_, err := db.sqlite.ExecContext(ctx, "DELETE FROM reviewvotes WHERE reviewid = ? AND userid = ?", reviewID, userID)
+	return err
+}
+
+// ReportReview records the report and returns the reports made since the last moderation.
+// An approved review reaching REVIEW_REPORTS_TO_QUEUE of them goes back to the moderation queue.
+func (db *DbSqlite) ReportReview(ctx context.Context, report *models.ReviewReport) (int, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	report.Created = time.Now().UTC()
+	_, err = tx.ExecContext(ctx, "INSERT INTO reviewreports (reviewid, userid, reason, created) VALUES (?, ?, ?, ?)",
+		report.ReviewId, report.UserId, report.Reason, report.Created)
+	if IsConflict(err) {
+		return 0, fmt.Errorf("review already reported: %w", ErrConflict)
+	}
+	if err != nil {
+		return 0, err
+	}
+	var reports int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM reviewreports JOIN reviews ON reviews.id = reviewreports.reviewid
+		WHERE reviews.id = ? AND (reviews.moderatedat IS NULL OR reviewreports.created > reviews.moderatedat)`, report.ReviewId).Scan(&reports)
+	if err != nil {
+		return 0, err
+	}
+	if reports >= config.REVIEW_REPORTS_TO_QUEUE {
+		_, err = tx.ExecContext(ctx, "UPDATE reviews SET status = ?, flag = ? WHERE id = ? AND status = ?",
+			config.REVIEW_PENDING, config.REVIEW_FLAG_REPORTED, report.ReviewId, config.REVIEW_APPROVED)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return reports, tx.Commit()
+}
+
+func (db *DbSqlite) GetReviewReports(ctx context.Context, reviewID int) ([]*models.ReviewReport, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT reviewid, userid, reason, created FROM reviewreports WHERE reviewid = ? ORDER BY created", reviewID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	reports := []*models.ReviewReport{}
+	for rows.Next() {
+		report := models.ReviewReport{}
+		err = rows.Scan(&report.ReviewId, &report.UserId, &report.Reason, &report.Created)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, &report)
+	}
+	return reports, rows.Err()
+}
+
+// ModerateReview sets the status decided by a moderator, reports made until now are settled.
+func (db *DbSqlite) ModerateReview(ctx context.Context, id int, status string, moderatorID int, note string) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	res, err := db.sqlite.ExecContext(ctx, "UPDATE reviews SET status = ?, flag = '', moderatedby = ?, moderatedat = ?, moderationnote = ? WHERE id = ?",
+		status, moderatorID, time.Now().UTC(), note, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); n < 1 || err != nil {
+		return fmt.Errorf("review %w", ErrNotFound)
+	}
+	return nil
+}
+
+func scanReview(row scanner) (*models.Review, error) {
+	review := models.Review{}
+	var moderatedAt sql.NullTime
+	err := row.Scan(&review.Id, &review.MovieId, &review.UserId, &review.User, &review.Stars,
+		&review.Body, &review.Spoiler, &review.Status, &review.Flag, &review.Helpful, &review.Reports,
+		&review.Created, &review.Updated, &review.ModeratedBy, &moderatedAt, &review.ModerationNote)
+	if err != nil {
+		return nil, err
+	}
+	if moderatedAt.Valid {
+		review.ModeratedAt = &moderatedAt.Time
+	}
+	return &review, nil
+}
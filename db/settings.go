@@ -0,0 +1,33 @@
+package db
+
+import (
+	"context"
+	"goflix/models"
+)
+
+func (db *DbSqlite) GetUserSettings(ctx context.Context, userID int) (*models.UserSettings, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.queryCached(ctx, "SELECT userid, region, language FROM usersettings WHERE userid = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	settings := models.UserSettings{UserId: userID}
+	if rows.Next() {
+		err = rows.Scan(&settings.UserId, &settings.Region, &settings.Language)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &settings, nil
+}
+
+func (db *DbSqlite) SaveUserSettings(ctx context.Context, settings *models.UserSettings) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	upsertSQL := `INSERT INTO usersettings (userid, region, language) VALUES (?, ?, ?)
+		ON CONFLICT(userid) DO UPDATE SET region = excluded.region, language = excluded.language`
+	_, err := db.sqlite.ExecContext(ctx, upsertSQL, settings.UserId, settings.Region, settings.Language)
+	return err
+}
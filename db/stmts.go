@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// stmtCache keeps the hot queries prepared, so that SQLite parses them once per connection
+// instead of at every call. Only fixed query strings go through it: one built with a variable
+// number of placeholders would add a statement per length.
+type stmtCache struct {
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+func newStmtCache() *stmtCache {
+	return &stmtCache{stmts: map[string]*sql.Stmt{}}
+}
+
+// stmt returns the prepared statement of query, bound to the transaction when the storage runs
+// in one. Statements are prepared without holding the lock, and a unit of work missing one
+// prepares it on its own transaction: the pool may have no connection left to give while the
+// units of work hold them all.
+func (db *DbSqlite) stmt(ctx context.Context, query string) (*sql.Stmt, error) {
+	db.stmts.mu.Lock()
+	stmt, ok := db.stmts.stmts[query]
+	db.stmts.mu.Unlock()
+	if db.tx != nil {
+		if ok {
+			return db.tx.StmtContext(ctx, stmt), nil
+		}
+		// closed with the transaction, the next call outside a unit of work caches the query
+		return db.tx.PrepareContext(ctx, query)
+	}
+	if ok {
+		return stmt, nil
+	}
+	prepared, err := db.pool.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	db.stmts.mu.Lock()
+	defer db.stmts.mu.Unlock()
+	// another call may have prepared it meanwhile, the first one stays
+	if stmt, ok := db.stmts.stmts[query]; ok {
+		prepared.Close()
+		return stmt, nil
+	}
+	db.stmts.stmts[query] = prepared
+	return prepared, nil
+}
+
+// queryCached is QueryContext through the statement cache.
+func (db *DbSqlite) queryCached(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	stmt, err := db.stmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.QueryContext(ctx, args...)
+}
+
+// execCached is ExecContext through the statement cache.
+func (db *DbSqlite) execCached(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	stmt, err := db.stmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(ctx, args...)
+}
+
+func (c *stmtCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for query, stmt := range c.stmts {
+		stmt.Close()
+		delete(c.stmts, query)
+	}
+}
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"goflix/config"
+	"goflix/models"
+)
+
+var (
+	ErrStreamLimit = errors.New("maximum concurrent streams reached")
+	ErrStreamEnded = errors.New("stream session ended")
+)
+
+const streamSessionColumns = "id, userid, movieid, device, started, heartbeat, ended, endreason"
+
+// StartStreamSession opens a session unless the user already has maxStreams active ones,
+// in which case the active sessions are returned with ErrStreamLimit. maxStreams 0 is unlimited.
+func (db *DbSqlite) StartStreamSession(ctx context.Context, session *models.StreamSession, maxStreams int) ([]*models.StreamSession, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	_, err = tx.ExecContext(ctx, "UPDATE streamsessions SET ended = ?, endreason = ? WHERE ended IS NULL AND heartbeat < ?",
+		now, config.STREAM_END_TIMEOUT, now.Add(-config.STREAM_HEARTBEAT_TIMEOUT))
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT "+streamSessionColumns+" FROM streamsessions WHERE ended IS NULL AND userid = ? ORDER BY started", session.UserId)
+	if err != nil {
+		return nil, err
+	}
+	active, err := scanStreamSessions(rows)
+	if err != nil {
+		return nil, err
+	}
+	if maxStreams > 0 && len(active) >= maxStreams {
+		return active, ErrStreamLimit
+	}
+
+	session.Started = now
+	session.Heartbeat = now
+	insertSQL := "INSERT INTO streamsessions (id, userid, movieid, device, started, heartbeat) VALUES (?, ?, ?, ?, ?, ?)"
+	_, err = tx.ExecContext(ctx, insertSQL, session.Id, session.UserId, session.MovieId, session.Device, session.Started, session.Heartbeat)
+	if err != nil {
+		return nil, err
+	}
+	return nil, tx.Commit()
+}
+
+func (db *DbSqlite) GetStreamSession(ctx context.Context, id string) (*models.StreamSession, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.queryCached(ctx, "SELECT "+streamSessionColumns+" FROM streamsessions WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := scanStreamSessions(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, fmt.Errorf("stream session %w", ErrNotFound)
+	}
+	return sessions[0], nil
+}
+
+// GetActiveStreamSessions lists the sessions still alive for a user, or for everyone when userID is 0.
+func (db *DbSqlite) GetActiveStreamSessions(ctx context.Context, userID int) ([]*models.StreamSession, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	now := time.Now().UTC()
+	_, err := db.sqlite.ExecContext(ctx, "UPDATE streamsessions SET ended = ?, endreason = ? WHERE ended IS NULL AND heartbeat < ?",
+		now, config.STREAM_END_TIMEOUT, now.Add(-config.STREAM_HEARTBEAT_TIMEOUT))
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT "+streamSessionColumns+" FROM streamsessions WHERE ended IS NULL AND (? = 0 OR userid = ?) ORDER BY started", userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanStreamSessions(rows)
+}
+
+func (db *DbSqlite) HeartbeatStreamSession(ctx context.Context, id string) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	now := time.Now().UTC()
+	res, err := db.execCached(ctx, "UPDATE streamsessions SET heartbeat = ? WHERE id = ? AND ended IS NULL AND heartbeat >= ?",
+		now, id, now.Add(-config.STREAM_HEARTBEAT_TIMEOUT))
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n < 1 {
+		return ErrStreamEnded
+	}
+	return nil
+}
+
+func (db *DbSqlite) StopStreamSession(ctx context.Context, id string, reason string) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	res, err := db.sqlite.ExecContext(ctx, "UPDATE streamsessions SET ended = ?, endreason = ? WHERE id = ? AND ended IS NULL",
+		time.Now().UTC(), reason, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n < 1 {
+		return fmt.Errorf("stream session %w", ErrNotFound)
+	}
+	return nil
+}
+
+func scanStreamSessions(rows *sql.Rows) ([]*models.StreamSession, error) {
+	defer rows.Close()
+	var sessions []*models.StreamSession
+	for rows.Next() {
+		session := models.StreamSession{}
+		var ended sql.NullTime
+		var reason sql.NullString
+		err := rows.Scan(&session.Id, &session.UserId, &session.MovieId, &session.Device,
+			&session.Started, &session.Heartbeat, &ended, &reason)
+		if err != nil {
+			return nil, err
+		}
+		if ended.Valid {
+			session.Ended = &ended.Time
+		}
+		session.EndReason = reason.String
+		sessions = append(sessions, &session)
+	}
+	return sessions, rows.Err()
+}
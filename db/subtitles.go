@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"goflix/models"
+)
+
+func (db *DbSqlite) AddSubtitle(ctx context.Context, subtitle *models.Subtitle) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	insertSQL := "INSERT INTO subtitles (movieid, lang, label, forced, content) VALUES (?, ?, ?, ?, ?)"
+	res, err := db.sqlite.ExecContext(ctx, insertSQL,
+		subtitle.MovieId, subtitle.Lang, subtitle.Label, subtitle.Forced, subtitle.Content)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	subtitle.Id = int(id)
+	return nil
+}
+
+func (db *DbSqlite) GetSubtitles(ctx context.Context, movieID int) ([]*models.Subtitle, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT id, movieid, lang, label, forced FROM subtitles WHERE movieid = ? ORDER BY lang, id", movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var subtitles []*models.Subtitle
+	for rows.Next() {
+		subtitle := models.Subtitle{}
+		err = rows.Scan(&subtitle.Id, &subtitle.MovieId, &subtitle.Lang, &subtitle.Label, &subtitle.Forced)
+		if err != nil {
+			return nil, err
+		}
+		subtitles = append(subtitles, &subtitle)
+	}
+	return subtitles, nil
+}
+
+func (db *DbSqlite) GetSubtitle(ctx context.Context, id int) (*models.Subtitle, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT id, movieid, lang, label, forced, content FROM subtitles WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var subtitle models.Subtitle
+	if rows.Next() {
+		err = rows.Scan(&subtitle.Id, &subtitle.MovieId, &subtitle.Lang, &subtitle.Label, &subtitle.Forced, &subtitle.Content)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if subtitle.Id == 0 {
+		return nil, fmt.Errorf("subtitle %w", ErrNotFound)
+	}
+	return &subtitle, nil
+}
+
+func (db *DbSqlite) DeleteSubtitle(ctx context.Context, id int) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	result, err := db.sqlite.ExecContext(ctx, "DELETE FROM subtitles WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected != 1 {
+		return fmt.Errorf("errors want delete 1 reccord got: %d: %w", rowsAffected, ErrNotFound)
+	}
+	return nil
+}
+
+func (db *DbSqlite) AddAudioTrack(ctx context.Context, track *models.AudioTrack) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	insertSQL := "INSERT INTO audiotracks (movieid, lang, label, role, isdefault) VALUES (?, ?, ?, ?, ?)"
+	res, err := db.sqlite.ExecContext(ctx, insertSQL,
+		track.MovieId, track.Lang, track.Label, track.Role, track.Default)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	track.Id = int(id)
+	return nil
+}
+
+func (db *DbSqlite) GetAudioTracks(ctx context.Context, movieID int) ([]*models.AudioTrack, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT id, movieid, lang, label, role, isdefault FROM audiotracks WHERE movieid = ? ORDER BY isdefault DESC, id", movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tracks []*models.AudioTrack
+	for rows.Next() {
+		track := models.AudioTrack{}
+		err = rows.Scan(&track.Id, &track.MovieId, &track.Lang, &track.Label, &track.Role, &track.Default)
+		if err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, &track)
+	}
+	return tracks, nil
+}
+
+func (db *DbSqlite) DeleteAudioTrack(ctx context.Context, id int) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	result, err := db.sqlite.ExecContext(ctx, "DELETE FROM audiotracks WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected != 1 {
+		return fmt.Errorf("errors want delete 1 reccord got: %d: %w", rowsAffected, ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"goflix/models"
+)
+
+func (db *DbSqlite) GetMovieTranslations(ctx context.Context, movieID int) ([]*models.MovieTranslation, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT movieid, locale, title, details FROM movietranslations WHERE movieid = ? ORDER BY locale", movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var translations []*models.MovieTranslation
+	for rows.Next() {
+		translation := models.MovieTranslation{}
+		err = rows.Scan(&translation.MovieId, &translation.Locale, &translation.Title, &translation.Details)
+		if err != nil {
+			return nil, err
+		}
+		translations = append(translations, &translation)
+	}
+	return translations, rows.Err()
+}
+
+func (db *DbSqlite) SaveMovieTranslation(ctx context.Context, translation *models.MovieTranslation) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	upsertSQL := `INSERT INTO movietranslations (movieid, locale, title, details) VALUES (?, ?, ?, ?)
+		ON CONFLICT(movieid, locale) DO UPDATE SET title = excluded.title, details = excluded.details`
+	_, err := db.sqlite.ExecContext(ctx, upsertSQL, translation.MovieId, translation.Locale, translation.Title, translation.Details)
+	return err
+}
+
+func (db *DbSqlite) DeleteMovieTranslation(ctx context.Context, movieID int, locale string) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	res, err := db.sqlite.ExecContext(ctx, "DELETE FROM movietranslations WHERE movieid = ? AND locale = ?", movieID, locale)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("translation %w", ErrNotFound)
+	}
+	return nil
+}
+
+// TranslateMovies replaces Title and Details by the translation of the most preferred locale
+// of the fallback chain having one, fields left empty in it keep the original text. Locale is
+// set to the locale of that translation.
+func (db *DbSqlite) TranslateMovies(ctx context.Context, movies []*models.Movies, locales []string) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	if len(movies) == 0 || len(locales) == 0 {
+		return nil
+	}
+	// a title may be listed more than once
+	byID := map[int][]*models.Movies{}
+	args := []any{localeList(locales)}
+	for _, movie := range movies {
+		if _, ok := byID[movie.Id]; !ok {
+			args = append(args, movie.Id)
+		}
+		byID[movie.Id] = append(byID[movie.Id], movie)
+	}
+	rows, err := db.sqlite.QueryContext(ctx, `SELECT movieid, locale, title, details FROM movietranslations
+		WHERE instr(?1, ',' || locale || ',') > 0 AND movieid IN (?`+strings.Repeat(", ?", len(args)-2)+`)
+		ORDER BY instr(?1, ',' || locale || ',')`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	// rows come from the most to the least preferred locale, the first one of a title is used
+	translated := map[int]bool{}
+	for rows.Next() {
+		var t models.MovieTranslation
+		err = rows.Scan(&t.MovieId, &t.Locale, &t.Title, &t.Details)
+		if err != nil {
+			return err
+		}
+		if translated[t.MovieId] {
+			continue
+		}
+		translated[t.MovieId] = true
+		for _, movie := range byID[t.MovieId] {
+			if t.Title != "" {
+				movie.Title = t.Title
+			}
+			if t.Details != "" {
+				movie.Details = t.Details
+			}
+			movie.Locale = t.Locale
+		}
+	}
+	return rows.Err()
+}
+
+// localeList joins a fallback chain for the instr based lookups, [fr-ca fr] gives ",fr-ca,fr,".
+func localeList(locales []string) string {
+	return "," + strings.Join(locales, ",") + ","
+}
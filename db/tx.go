@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// conn runs the queries of the storage, the connection pool or the transaction of a unit of work.
+type conn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// WithTx runs fn as a unit of work: the storage given to fn runs every query in one transaction,
+// committed when fn returns nil and rolled back otherwise. Methods doing several writes run in a
+// savepoint of it, and so does a nested WithTx. fn must only use the storage it is given, the
+// database stays locked for writes until it returns, or until the query timeout rolls it back.
+func (db *DbSqlite) WithTx(ctx context.Context, fn func(Storage) error) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	return db.withTx(ctx, func(tx *DbSqlite) error {
+		return fn(tx)
+	})
+}
+
+func (db *DbSqlite) withTx(ctx context.Context, fn func(tx *DbSqlite) error) error {
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	unit := *db
+	unit.sqlite, unit.tx, unit.savepoints = tx, tx.tx, tx.savepoints
+	err = fn(&unit)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// begin starts a transaction, or a savepoint when the storage already runs in one. Cancelling
+// ctx rolls the transaction back.
+func (db *DbSqlite) begin(ctx context.Context) (*txn, error) {
+	if db.tx == nil {
+		tx, err := db.pool.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &txn{conn: tx, ctx: ctx, tx: tx, savepoints: new(int)}, nil
+	}
+	*db.savepoints++
+	t := &txn{conn: db.tx, ctx: ctx, tx: db.tx, savepoints: db.savepoints, savepoint: fmt.Sprintf("sp%d", *db.savepoints)}
+	_, err := t.ExecContext(ctx, "SAVEPOINT "+t.savepoint)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// txn is a transaction or a savepoint of one, Rollback after Commit does nothing so that it can
+// be deferred.
+type txn struct {
+	conn
+	ctx        context.Context
+	tx         *sql.Tx
+	savepoints *int
+	savepoint  string
+	done       bool
+}
+
+func (t *txn) Commit() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	if t.savepoint == "" {
+		return t.tx.Commit()
+	}
+	_, err := t.ExecContext(t.ctx, "RELEASE "+t.savepoint)
+	return err
+}
+
+func (t *txn) Rollback() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	if t.savepoint == "" {
+		return t.tx.Rollback()
+	}
+	_, err := t.ExecContext(t.ctx, "ROLLBACK TO "+t.savepoint)
+	if err != nil {
+		return err
+	}
+	_, err = t.ExecContext(t.ctx, "RELEASE "+t.savepoint)
+	return err
+}
@@ -0,0 +1,279 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"goflix/config"
+	"goflix/models"
+)
+
+const webhookColumns = "id, url, secret, events, enabled, created"
+
+const deliveryColumns = "id, webhookid, eventid, event, payload, status, attempts, nextattempt, lastattempt, responsecode, lasterror, created"
+
+func (db *DbSqlite) GetWebhooks(ctx context.Context) ([]*models.Webhook, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.queryCached(ctx, "SELECT "+webhookColumns+" FROM webhooks ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	webhooks := []*models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (db *DbSqlite) GetWebhook(ctx context.Context, id int) (*models.Webhook, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	webhook, err := scanWebhook(db.sqlite.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("webhook %w", ErrNotFound)
+	}
+	return webhook, err
+}
+
+// SaveWebhook creates the webhook when it has no id and updates it otherwise.
+func (db *DbSqlite) SaveWebhook(ctx context.Context, webhook *models.Webhook) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	events := strings.Join(webhook.Events, ",")
+	if webhook.Id != 0 {
+		res, err := db.sqlite.ExecContext(ctx, "UPDATE webhooks SET url = ?, secret = ?, events = ?, enabled = ? WHERE id = ?",
+			webhook.URL, webhook.Secret, events, webhook.Enabled, webhook.Id)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); n < 1 || err != nil {
+			return fmt.Errorf("webhook %w", ErrNotFound)
+		}
+		return nil
+	}
+	webhook.Created = time.Now().UTC()
+	res, err := db.sqlite.ExecContext(ctx, "INSERT INTO webhooks (url, secret, events, enabled, created) VALUES (?, ?, ?, ?, ?)",
+		webhook.URL, webhook.Secret, events, webhook.Enabled, webhook.Created)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	webhook.Id = int(id)
+	return nil
+}
+
+// DeleteWebhook removes the webhook, its pending deliveries are given up and its logs kept.
+func (db *DbSqlite) DeleteWebhook(ctx context.Context, id int) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); n < 1 || err != nil {
+		return fmt.Errorf("webhook %w", ErrNotFound)
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE webhookdeliveries SET status = ?, nextattempt = NULL, lasterror = 'webhook deleted' WHERE webhookid = ? AND status = ?",
+		config.DELIVERY_FAILED, id, config.DELIVERY_PENDING)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DbSqlite) AddWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	delivery.Created = time.Now().UTC()
+	if delivery.NextAttempt == nil {
+		delivery.NextAttempt = &delivery.Created
+	}
+	res, err := db.sqlite.ExecContext(ctx, "INSERT INTO webhookdeliveries (webhookid, eventid, event, payload, status, nextattempt, created) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		delivery.WebhookId, delivery.EventId, delivery.Event, string(delivery.Payload), delivery.Status, delivery.NextAttempt.UTC(), delivery.Created)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	delivery.Id = int(id)
+	return nil
+}
+
+// GetDueWebhookDeliveries returns the pending deliveries whose next attempt is due, the oldest first.
+func (db *DbSqlite) GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT "+deliveryColumns+" FROM webhookdeliveries WHERE status = ? AND nextattempt <= ? ORDER BY nextattempt, id LIMIT ?",
+		config.DELIVERY_PENDING, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+// GetWebhookDeliveries returns a page of the deliveries of the webhook, the last first, and their total.
+func (db *DbSqlite) GetWebhookDeliveries(ctx context.Context, webhookID int, status string, offset int, limit int) ([]*models.WebhookDelivery, int, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	where := " WHERE webhookid = ?"
+	args := []any{webhookID}
+	if status != "" {
+		where += " AND status = ?"
+		args = append(args, status)
+	}
+	var total int
+	err := db.sqlite.QueryRowContext(ctx, "SELECT COUNT(*) FROM webhookdeliveries"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT "+deliveryColumns+" FROM webhookdeliveries"+where+" ORDER BY id DESC LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	deliveries, err := scanDeliveries(rows)
+	return deliveries, total, err
+}
+
+// GetWebhookDelivery returns the delivery with the log of its attempts.
+func (db *DbSqlite) GetWebhookDelivery(ctx context.Context, id int) (*models.WebhookDelivery, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	rows, err := db.sqlite.QueryContext(ctx, "SELECT "+deliveryColumns+" FROM webhookdeliveries WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, fmt.Errorf("webhook delivery %w", ErrNotFound)
+	}
+	delivery := deliveries[0]
+
+	rows, err = db.sqlite.QueryContext(ctx, "SELECT deliveryid, attempted, responsecode, error, duration FROM webhookattempts WHERE deliveryid = ? ORDER BY id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	delivery.AttemptLog = []*models.WebhookAttempt{}
+	for rows.Next() {
+		attempt := models.WebhookAttempt{}
+		err = rows.Scan(&attempt.DeliveryId, &attempt.Attempted, &attempt.ResponseCode, &attempt.Error, &attempt.Duration)
+		if err != nil {
+			return nil, err
+		}
+		delivery.AttemptLog = append(delivery.AttemptLog, &attempt)
+	}
+	return delivery, rows.Err()
+}
+
+// SaveWebhookAttempt logs the attempt and saves the outcome of the delivery.
+func (db *DbSqlite) SaveWebhookAttempt(ctx context.Context, delivery *models.WebhookDelivery, attempt *models.WebhookAttempt) error {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, "INSERT INTO webhookattempts (deliveryid, attempted, responsecode, error, duration) VALUES (?, ?, ?, ?, ?)",
+		delivery.Id, attempt.Attempted.UTC(), attempt.ResponseCode, attempt.Error, attempt.Duration)
+	if err != nil {
+		return err
+	}
+	err = updateDelivery(ctx, tx, delivery)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RequeueWebhookDelivery sends the delivery again at the next run, with a fresh set of retries.
+func (db *DbSqlite) RequeueWebhookDelivery(ctx context.Context, id int) (*models.WebhookDelivery, error) {
+	ctx, cancel := db.queryTimeout(ctx)
+	defer cancel()
+	delivery, err := db.GetWebhookDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status == config.DELIVERY_PENDING && delivery.Attempts == 0 {
+		return nil, fmt.Errorf("delivery %d was not attempted yet: %w", id, ErrConflict)
+	}
+	now := time.Now().UTC()
+	delivery.Status = config.DELIVERY_PENDING
+	delivery.Attempts = 0
+	delivery.NextAttempt = &now
+	err = updateDelivery(ctx, db.sqlite, delivery)
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func updateDelivery(ctx context.Context, exec execer, delivery *models.WebhookDelivery) error {
+	res, err := exec.ExecContext(ctx, "UPDATE webhookdeliveries SET status = ?, attempts = ?, nextattempt = ?, lastattempt = ?, responsecode = ?, lasterror = ? WHERE id = ?",
+		delivery.Status, delivery.Attempts, delivery.NextAttempt, delivery.LastAttempt, delivery.ResponseCode, delivery.LastError, delivery.Id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); n < 1 || err != nil {
+		return fmt.Errorf("webhook delivery %w", ErrNotFound)
+	}
+	return nil
+}
+
+func scanWebhook(row scanner) (*models.Webhook, error) {
+	webhook := models.Webhook{}
+	var events string
+	err := row.Scan(&webhook.Id, &webhook.URL, &webhook.Secret, &events, &webhook.Enabled, &webhook.Created)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Events = strings.Split(events, ",")
+	return &webhook, nil
+}
+
+func scanDeliveries(rows *sql.Rows) ([]*models.WebhookDelivery, error) {
+	defer rows.Close()
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		delivery := models.WebhookDelivery{}
+		var payload string
+		var next, last sql.NullTime
+		err := rows.Scan(&delivery.Id, &delivery.WebhookId, &delivery.EventId, &delivery.Event, &payload, &delivery.Status,
+			&delivery.Attempts, &next, &last, &delivery.ResponseCode, &delivery.LastError, &delivery.Created)
+		if err != nil {
+			return nil, err
+		}
+		delivery.Payload = []byte(payload)
+		if next.Valid {
+			delivery.NextAttempt = &next.Time
+		}
+		if last.Valid {
+			delivery.LastAttempt = &last.Time
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+	return deliveries, rows.Err()
+}
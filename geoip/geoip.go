@@ -0,0 +1,99 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DB resolves an IP address to a country code from a CSV file of "network,country" lines,
+// e.g. "81.56.0.0/14,FR", as exported from the GeoLite2 country blocks.
+type DB struct {
+	// prefixes group the networks by prefix length, the most specific first, each sorted by
+	// address so that a lookup is a binary search per prefix length.
+	prefixes []prefix
+}
+
+type prefix struct {
+	mask     net.IPMask
+	networks []network
+}
+
+// network addresses are kept in their 16 bytes form, IPv4 ones mapped in IPv6.
+type network struct {
+	ip      net.IP
+	country string
+}
+
+func Load(path string) (*DB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+func Read(r io.Reader) (*DB, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	byLength := map[int][]network{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 2 {
+			return nil, errors.New("geoip: want network,country")
+		}
+		_, ipnet, err := net.ParseCIDR(strings.TrimSpace(record[0]))
+		if err != nil {
+			// header line
+			continue
+		}
+		ones, bits := ipnet.Mask.Size()
+		// IPv4 networks are matched in the IPv4-mapped IPv6 space
+		ones += 8*net.IPv6len - bits
+		byLength[ones] = append(byLength[ones], network{ip: ipnet.IP.To16(), country: strings.ToUpper(strings.TrimSpace(record[1]))})
+	}
+	db := &DB{}
+	for ones, networks := range byLength {
+		// stable, the first line wins when a network is listed twice
+		sort.SliceStable(networks, func(i, j int) bool { return bytes.Compare(networks[i].ip, networks[j].ip) < 0 })
+		db.prefixes = append(db.prefixes, prefix{mask: net.CIDRMask(ones, 8*net.IPv6len), networks: networks})
+	}
+	sort.Slice(db.prefixes, func(i, j int) bool {
+		a, _ := db.prefixes[i].mask.Size()
+		b, _ := db.prefixes[j].mask.Size()
+		return a > b
+	})
+	return db, nil
+}
+
+// Country returns the country of the most specific network containing ip, "" when unknown.
+func (db *DB) Country(ip string) string {
+	if db == nil {
+		return ""
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+	for _, p := range db.prefixes {
+		masked := addr.Mask(p.mask)
+		i := sort.Search(len(p.networks), func(i int) bool { return bytes.Compare(p.networks[i].ip, masked) >= 0 })
+		if i < len(p.networks) && p.networks[i].ip.Equal(masked) {
+			return p.networks[i].country
+		}
+	}
+	return ""
+}
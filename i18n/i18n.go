@@ -0,0 +1,89 @@
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"goflix/config"
+
+	"golang.org/x/text/language"
+)
+
+// ParseAcceptLanguage returns the language tags of an Accept-Language header, best first.
+func ParseAcceptLanguage(header string) []string {
+	type tag struct {
+		name string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = Normalize(name)
+		if name == "" || name == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			tags = append(tags, tag{name, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.name
+	}
+	return names
+}
+
+// Chain expands the preferred tags into a fallback chain: each tag followed by its parent
+// languages. "fr-CA" gives [fr-ca fr], after which comes the original text of a title rather
+// than another language. Invalid tags are left out, the default locale is the chain of a viewer
+// without any valid preference.
+func Chain(preferred ...string) []string {
+	var chain []string
+	seen := map[string]bool{}
+	add := func(tag string) {
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			chain = append(chain, tag)
+		}
+	}
+	for _, tag := range preferred {
+		tag = Normalize(tag)
+		if !Valid(tag) {
+			continue
+		}
+		for tag != "" {
+			add(tag)
+			i := strings.LastIndex(tag, "-")
+			if i < 0 {
+				break
+			}
+			tag = tag[:i]
+		}
+	}
+	if len(chain) == 0 {
+		add(config.DEFAULT_LOCALE)
+	}
+	return chain
+}
+
+// Valid tells whether tag is a well-formed BCP 47 language tag, e.g. "fr" or "pt-BR".
+func Valid(tag string) bool {
+	if tag == "" {
+		return false
+	}
+	_, err := language.Parse(tag)
+	return err == nil
+}
+
+// Normalize lowercases a language tag and uses dashes, "fr_CA" gives "fr-ca".
+func Normalize(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
@@ -0,0 +1,450 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"goflix/catalog"
+	"goflix/config"
+	"goflix/db"
+	"goflix/metadata"
+	"goflix/models"
+	"goflix/utils"
+)
+
+const SCAN_SKIPPED = "skipped"
+
+// libraryPrefix starts the external ids made up from the folder names, for titles without a provider id.
+const libraryPrefix = "library:"
+
+var ErrScanRunning = fmt.Errorf("%w: a library scan is already running", db.ErrConflict)
+
+// running keeps the scheduled scan and the ones started by hand from overlapping.
+var running sync.Mutex
+
+var (
+	yearSuffix   = regexp.MustCompile(`\s*\((\d{4})\)$`)
+	episodeTag   = regexp.MustCompile(`(?i)s(\d{1,3})[ ._-]?e(\d{1,4})`)
+	episodeCross = regexp.MustCompile(`(?i)\b(\d{1,2})x(\d{2,3})\b`)
+)
+
+// Scan walks the Movies and Shows folders of dirs and creates or updates a title for every
+// video file. Unless full is set, files unchanged since the previous scan, nfo sidecars
+// included, are skipped. A file goes to the title it was linked to at the previous scan, else
+// titles are matched by external id, then by title for titles that have none yet; fields the
+// library leaves empty keep their current value. Folders that cannot be read are logged and
+// skipped. Files gone from disk are unlinked, their titles are kept.
+func Scan(ctx context.Context, store db.Storage, dirs []string, full bool) (*models.ScanReport, error) {
+	if !running.TryLock() {
+		return nil, ErrScanRunning
+	}
+	defer running.Unlock()
+
+	known, err := store.GetMediaFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	movies, err := store.GetCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := scanner{
+		full:       full,
+		known:      map[string]*models.MediaFile{},
+		byID:       map[int]*models.Movies{},
+		byExternal: map[string]*models.Movies{},
+		byTitle:    map[string]*models.Movies{},
+		seen:       map[string]bool{},
+		report:     &models.ScanReport{Full: full, Files: []*models.ScanFile{}},
+	}
+	for _, file := range known {
+		s.known[file.Path] = file
+	}
+	for _, movie := range movies {
+		s.byID[movie.Id] = movie
+		if movie.ExternalId != "" {
+			s.byExternal[movie.ExternalId] = movie
+		}
+		s.byTitle[movie.Title] = movie
+	}
+
+	var roots []string
+	for _, dir := range dirs {
+		root, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(root); err != nil {
+			// an unmounted root must not unlink its files
+			continue
+		}
+		roots = append(roots, root)
+		err = s.walk(filepath.Join(root, config.LIBRARY_MOVIES_DIR), s.movie)
+		if err != nil {
+			return nil, err
+		}
+		err = s.walk(filepath.Join(root, config.LIBRARY_SHOWS_DIR), s.episode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows := make([]*models.ImportRow, len(s.pending))
+	for i, p := range s.pending {
+		rows[i] = p.row
+	}
+	_, err = store.ImportMovies(ctx, rows, false, false)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	for _, p := range s.pending {
+		p.result.Action = p.row.Action
+		p.result.Error = p.row.Error
+		p.result.MovieId = p.row.MovieId
+		if p.row.Action == config.IMPORT_FAILED {
+			continue
+		}
+		p.file.MovieId = p.row.MovieId
+		p.file.ScannedAt = now
+		err = store.SaveMediaFile(ctx, p.file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for path := range s.known {
+		// the files of a folder that could not be read are not gone
+		if s.seen[path] || !under(path, roots) || under(path, s.report.Unreadable) {
+			continue
+		}
+		err = store.DeleteMediaFile(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		s.report.Removed++
+	}
+
+	for _, file := range s.report.Files {
+		switch file.Action {
+		case config.IMPORT_CREATED:
+			s.report.Created++
+		case config.IMPORT_UPDATED:
+			s.report.Updated++
+		case config.IMPORT_UNCHANGED:
+			s.report.Unchanged++
+		case SCAN_SKIPPED:
+			s.report.Skipped++
+		case config.IMPORT_FAILED:
+			s.report.Failed++
+		}
+	}
+	return s.report, nil
+}
+
+type scanner struct {
+	full       bool
+	known      map[string]*models.MediaFile
+	byID       map[int]*models.Movies
+	byExternal map[string]*models.Movies
+	byTitle    map[string]*models.Movies
+	seen       map[string]bool
+	pending    []*pending
+	report     *models.ScanReport
+}
+
+type pending struct {
+	file   *models.MediaFile
+	row    *models.ImportRow
+	result *models.ScanFile
+}
+
+// describe builds the title held by a video file from its path and nfo sidecars.
+type describe func(top string, path string) (*models.Movies, error)
+
+// walk calls describe for every video of dir, top being the folder of dir holding the video.
+func (s *scanner) walk(dir string, describe describe) error {
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			s.unreadable(path, err)
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !isVideo(entry.Name()) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			s.unreadable(path, err)
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		top := filepath.Join(dir, strings.Split(rel, string(filepath.Separator))[0])
+		s.add(path, info, top, describe)
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// unreadable reports a folder or file the walk could not read, the scan goes on without it.
+func (s *scanner) unreadable(path string, err error) {
+	log.Printf("library scan: skipping %s: %v", path, err)
+	s.report.Unreadable = append(s.report.Unreadable, path)
+}
+
+func (s *scanner) add(path string, info fs.FileInfo, top string, describe describe) {
+	s.seen[path] = true
+	result := &models.ScanFile{Path: path}
+	s.report.Files = append(s.report.Files, result)
+
+	modTime := info.ModTime()
+	for _, sidecar := range sidecars(path, top) {
+		if sideInfo, err := os.Stat(sidecar); err == nil && sideInfo.ModTime().After(modTime) {
+			modTime = sideInfo.ModTime()
+		}
+	}
+	modTime = modTime.UTC().Truncate(time.Second)
+	known := s.known[path]
+	if !s.full && known != nil && known.Size == info.Size() && known.ModTime.Equal(modTime) {
+		result.Action = SCAN_SKIPPED
+		result.MovieId = known.MovieId
+		return
+	}
+
+	movie, err := describe(top, path)
+	if err == nil {
+		s.merge(path, movie)
+		err = catalog.Validate(movie)
+	}
+	if err != nil {
+		result.Action = config.IMPORT_FAILED
+		result.Error = err.Error()
+		return
+	}
+	result.ExternalId = movie.ExternalId
+	s.pending = append(s.pending, &pending{
+		file:   &models.MediaFile{Path: path, Size: info.Size(), ModTime: modTime},
+		row:    &models.ImportRow{ExternalId: movie.ExternalId, Movie: movie},
+		result: result,
+	})
+}
+
+// merge links the scanned title to the catalog and keeps the current value of the fields the
+// library leaves empty, e.g. a synopsis written by hand or fetched from a metadata provider.
+// The title the file was linked to comes first, so that a title renamed or enriched since
+// the previous scan is not created again.
+func (s *scanner) merge(path string, movie *models.Movies) {
+	var current *models.Movies
+	if file := s.known[path]; file != nil {
+		current = s.byID[file.MovieId]
+	}
+	if current == nil {
+		current = s.byExternal[movie.ExternalId]
+	}
+	if current == nil {
+		if byTitle := s.byTitle[movie.Title]; byTitle != nil && byTitle.ExternalId == "" {
+			current = byTitle
+		}
+	}
+	if current == nil {
+		return
+	}
+	movie.Id = current.Id
+	// a provider id found in the nfo replaces the current one, unless another title has it
+	if current.ExternalId != "" && movie.ExternalId != current.ExternalId {
+		if other := s.byExternal[movie.ExternalId]; strings.HasPrefix(movie.ExternalId, libraryPrefix) || other != nil && other != current {
+			movie.ExternalId = current.ExternalId
+		}
+	}
+	keep := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	keep(&movie.Details, current.Details)
+	keep(&movie.Actors, current.Actors)
+	keep(&movie.Genre, current.Genre)
+	keep(&movie.Poster, current.Poster)
+	keep(&movie.Maturity, current.Maturity)
+	if movie.Rating == 0 {
+		movie.Rating = current.Rating
+	}
+}
+
+// movie describes the videos of Movies/Title (Year)/ and the loose Movies/Title (Year).mkv.
+func (s *scanner) movie(top string, path string) (*models.Movies, error) {
+	folder := filepath.Base(top)
+	if top == path {
+		folder = strings.TrimSuffix(folder, filepath.Ext(folder))
+	}
+	name := strings.TrimSpace(yearSuffix.ReplaceAllString(folder, ""))
+	movie := models.Movies{Title: name, ExternalId: libraryPrefix + metadata.KIND_MOVIE + ":" + folder}
+
+	doc, err := readSidecar(sidecars(path, top))
+	if err != nil {
+		return nil, err
+	}
+	if doc != nil {
+		apply(&movie, doc)
+		if doc.Title != "" {
+			movie.Title = strings.TrimSpace(doc.Title)
+		}
+		if id := doc.tmdbID(); id != "" {
+			movie.ExternalId = metadata.ExternalId(metadata.KIND_MOVIE, id, 0, 0)
+		}
+	}
+	return &movie, nil
+}
+
+// episode describes the videos of Shows/Name/Season NN/, numbered by their nfo or their
+// file name, e.g. "Name S01E02.mkv" or "1x02.mkv".
+func (s *scanner) episode(top string, path string) (*models.Movies, error) {
+	if top == path {
+		return nil, errors.New("episodes belong in a show folder")
+	}
+	show, err := readNFO(filepath.Join(top, "tvshow.nfo"))
+	if err != nil {
+		return nil, err
+	}
+	episode, err := readNFO(strings.TrimSuffix(path, filepath.Ext(path)) + ".nfo")
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(yearSuffix.ReplaceAllString(filepath.Base(top), ""))
+	var movie models.Movies
+	var showID string
+	if show != nil {
+		apply(&movie, show)
+		if show.Title != "" {
+			name = strings.TrimSpace(show.Title)
+		}
+		showID = show.tmdbID()
+	}
+
+	var season, number int
+	var episodeTitle string
+	if episode != nil {
+		apply(&movie, episode)
+		season, number = episode.Season, episode.Episode
+		episodeTitle = strings.TrimSpace(episode.Title)
+	}
+	if number == 0 {
+		season, number = parseEpisode(filepath.Base(path))
+	}
+	if number == 0 {
+		return nil, errors.New("no season and episode number in the file name nor in its nfo")
+	}
+	if season == 0 {
+		// titles without a season are movies in the catalog
+		return nil, errors.New("specials are not supported")
+	}
+
+	movie.Saison, movie.Episode = season, number
+	movie.Title = fmt.Sprintf("%s S%02dE%02d", name, season, number)
+	if episodeTitle != "" {
+		movie.Title += " - " + episodeTitle
+	}
+	movie.ExternalId = fmt.Sprintf("%s%s:%s:s%de%d", libraryPrefix, metadata.KIND_TV, filepath.Base(top), season, number)
+	if showID != "" {
+		movie.ExternalId = metadata.ExternalId(metadata.KIND_TV, showID, season, number)
+	}
+	return &movie, nil
+}
+
+// apply copies the nfo fields set, episodes override their show.
+func apply(movie *models.Movies, doc *nfo) {
+	separator := config.LIST_SEPARATORS[0] + " "
+	if plot := doc.plot(); plot != "" {
+		movie.Details = plot
+	}
+	if actors := doc.actors(); len(actors) > 0 {
+		movie.Actors = strings.Join(actors, separator)
+	}
+	if len(doc.Genres) > 0 {
+		movie.Genre = strings.Join(doc.Genres, separator)
+	}
+	if poster := doc.poster(); poster != "" {
+		movie.Poster = poster
+	}
+	// unknown ratings are left to the editors rather than failing the file
+	if rating := doc.rating(); rating != "" {
+		if _, ok := config.TitleMaturityLevel(rating); ok {
+			movie.Maturity = rating
+		}
+	}
+}
+
+func parseEpisode(name string) (int, int) {
+	match := episodeTag.FindStringSubmatch(name)
+	if match == nil {
+		match = episodeCross.FindStringSubmatch(name)
+	}
+	if match == nil {
+		return 0, 0
+	}
+	season, _ := strconv.Atoi(match[1])
+	episode, _ := strconv.Atoi(match[2])
+	return season, episode
+}
+
+// sidecars lists the nfo files describing a video, the most specific first.
+func sidecars(path string, top string) []string {
+	files := []string{strings.TrimSuffix(path, filepath.Ext(path)) + ".nfo"}
+	if top != path {
+		files = append(files, filepath.Join(top, "movie.nfo"), filepath.Join(top, "tvshow.nfo"))
+	}
+	return files
+}
+
+func readSidecar(files []string) (*nfo, error) {
+	for _, file := range files {
+		if filepath.Base(file) == "tvshow.nfo" {
+			continue
+		}
+		doc, err := readNFO(file)
+		if doc != nil || err != nil {
+			return doc, err
+		}
+	}
+	return nil, nil
+}
+
+func isVideo(name string) bool {
+	// samples and trailers sit next to the feature in many libraries
+	lower := strings.ToLower(name)
+	if strings.Contains(lower, "sample") || strings.Contains(lower, "-trailer") {
+		return false
+	}
+	return utils.Contains(config.LIBRARY_VIDEO_EXTENSIONS, filepath.Ext(lower))
+}
+
+func under(path string, roots []string) bool {
+	for _, root := range roots {
+		if strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
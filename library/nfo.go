@@ -0,0 +1,97 @@
+package library
+
+import (
+	"encoding/xml"
+	"errors"
+	"os"
+	"strings"
+)
+
+// nfo is the subset of the Kodi sidecar formats read by the scanner, it covers the
+// <movie>, <tvshow> and <episodedetails> documents also written by Jellyfin and Emby.
+type nfo struct {
+	Title   string   `xml:"title"`
+	Plot    string   `xml:"plot"`
+	Outline string   `xml:"outline"`
+	Mpaa    string   `xml:"mpaa"`
+	Season  int      `xml:"season"`
+	Episode int      `xml:"episode"`
+	Genres  []string `xml:"genre"`
+	Actors  []struct {
+		Name string `xml:"name"`
+	} `xml:"actor"`
+	UniqueIds []struct {
+		Type  string `xml:"type,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"uniqueid"`
+	TmdbId string `xml:"tmdbid"`
+	Thumbs []struct {
+		Aspect string `xml:"aspect,attr"`
+		Value  string `xml:",chardata"`
+	} `xml:"thumb"`
+}
+
+// readNFO returns nil without error when the sidecar doesn't exist.
+func readNFO(path string) (*nfo, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var doc nfo
+	// Kodi allows a scraper URL after the document, only the first element is read
+	err = xml.NewDecoder(f).Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (n *nfo) plot() string {
+	if n.Plot != "" {
+		return strings.TrimSpace(n.Plot)
+	}
+	return strings.TrimSpace(n.Outline)
+}
+
+func (n *nfo) tmdbID() string {
+	for _, id := range n.UniqueIds {
+		if strings.EqualFold(id.Type, "tmdb") {
+			return strings.TrimSpace(id.Value)
+		}
+	}
+	return strings.TrimSpace(n.TmdbId)
+}
+
+func (n *nfo) actors() []string {
+	var names []string
+	for _, actor := range n.Actors {
+		if name := strings.TrimSpace(actor.Name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// rating reads the certification out of the mpaa forms seen in the wild: "R", "Rated R", "US:R".
+func (n *nfo) rating() string {
+	rating := strings.TrimSpace(n.Mpaa)
+	rating = strings.TrimPrefix(rating, "Rated ")
+	if i := strings.LastIndex(rating, ":"); i >= 0 {
+		rating = rating[i+1:]
+	}
+	return strings.TrimSpace(rating)
+}
+
+func (n *nfo) poster() string {
+	for _, thumb := range n.Thumbs {
+		value := strings.TrimSpace(thumb.Value)
+		if (thumb.Aspect == "" || thumb.Aspect == "poster") && strings.HasPrefix(value, "http") {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Mailer sends plain text emails.
+type Mailer interface {
+	Send(to string, subject string, body string) error
+}
+
+// SMTP sends through a relay, authenticating when a user is set.
+type SMTP struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTP(addr string, from string, user string, password string) *SMTP {
+	m := &SMTP{addr: addr, from: from}
+	if user != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		m.auth = smtp.PlainAuth("", user, password, host)
+	}
+	return m
+}
+
+func (m *SMTP) Send(to string, subject string, body string) error {
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return err
+	}
+	rcpt, err := mail.ParseAddress(to)
+	if err != nil {
+		return err
+	}
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", from.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", rcpt.String())
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return smtp.SendMail(m.addr, m.auth, from.Address, []string{rcpt.Address}, []byte(msg.String()))
+}
+
+// Log writes the emails to the server log instead of sending them, for development.
+type Log struct{}
+
+func NewLog() Log {
+	return Log{}
+}
+
+func (Log) Send(to string, subject string, body string) error {
+	log.Printf("mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
@@ -1,21 +1,48 @@
 package main
 
 import (
+	"context"
+	"goflix/cli"
 	"goflix/db"
 	"goflix/server"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
 
+	ctx := context.Background()
 	var db db.Storage = db.New()
-	err := db.Setup()
+	err := db.Setup(ctx)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer db.Close()
 
+	// goflix import ..., goflix export ...
+	if len(os.Args) > 1 {
+		// an interrupt cancels the command
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		err = cli.Run(ctx, db, os.Args[1:])
+		stop()
+		if err != nil {
+			db.Close()
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// SIGINT or SIGTERM stop the server and its jobs before the database is closed
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	var server server.Server = server.New(db)
-	server.Run()
+	err = server.Run(ctx)
+	if err != nil {
+		stop()
+		db.Close()
+		log.Fatal(err)
+	}
 
 }
@@ -0,0 +1,53 @@
+package metadata
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// newFixtures returns a TMDB client answered from recorded responses in dir instead of the
+// network: "/movie/603" reads dir/movie/603.json and a search for "The Matrix" reads
+// dir/search/movie/the-matrix.json. Missing files answer 404.
+func newFixtures(dir string) *TMDB {
+	client := NewTMDB("http://fixtures", "", "")
+	client.client.Transport = fixtureTransport{dir: dir}
+	return client
+}
+
+type fixtureTransport struct {
+	dir string
+}
+
+func (t fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := strings.Trim(req.URL.Path, "/")
+	if strings.HasPrefix(path, "search/") {
+		path += "/" + slug(req.URL.Query().Get("query"))
+	}
+	status := http.StatusOK
+	f, err := os.Open(filepath.Join(t.dir, filepath.FromSlash(path)+".json"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		status = http.StatusNotFound
+		f, err = os.Open(os.DevNull)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &http.Response{
+		Status:     http.StatusText(status),
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       f,
+		Request:    req,
+	}, nil
+}
+
+func slug(query string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}), "-")
+}
@@ -0,0 +1,143 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"goflix/config"
+	"goflix/models"
+)
+
+const (
+	KIND_MOVIE = "movie"
+	KIND_TV    = "tv"
+)
+
+var KINDS = []string{KIND_MOVIE, KIND_TV}
+
+var ErrNotFound = errors.New("title not found at the metadata provider")
+
+// MetadataProvider searches and fetches title metadata from an external catalog.
+type MetadataProvider interface {
+	Search(kind string, query string) ([]*Result, error)
+	Fetch(kind string, id string) (*Title, error)
+	Episodes(id string, season int) ([]*Episode, error)
+}
+
+type Result struct {
+	Id       string `json:"id"`
+	Kind     string `json:"kind"`
+	Title    string `json:"title"`
+	Year     string `json:"year"`
+	Overview string `json:"overview"`
+	Poster   string `json:"poster"`
+}
+
+type Title struct {
+	Id            string        `json:"id"`
+	Kind          string        `json:"kind"`
+	Title         string        `json:"title"`
+	Year          string        `json:"year"`
+	Overview      string        `json:"overview"`
+	Poster        string        `json:"poster"`
+	Genres        []string      `json:"genres"`
+	Cast          []*CastMember `json:"cast"`
+	Directors     []string      `json:"directors"`
+	Writers       []string      `json:"writers"`
+	Certification string        `json:"certification"`
+	Seasons       int           `json:"seasons"`
+}
+
+type CastMember struct {
+	Name      string `json:"name"`
+	Character string `json:"character"`
+}
+
+type Episode struct {
+	Season   int    `json:"season"`
+	Episode  int    `json:"episode"`
+	Title    string `json:"title"`
+	Overview string `json:"overview"`
+}
+
+// ExternalId is the id stored on titles enriched from a provider, e.g. "tmdb:movie:603",
+// episodes of a show add their number, e.g. "tmdb:tv:1396:s1e2".
+func ExternalId(kind string, id string, season int, episode int) string {
+	externalID := "tmdb:" + kind + ":" + id
+	if kind == KIND_TV && season > 0 {
+		externalID += fmt.Sprintf(":s%de%d", season, episode)
+	}
+	return externalID
+}
+
+// ParseExternalId returns the kind and provider id of an ExternalId, ok is false for other ids.
+func ParseExternalId(externalID string) (kind string, id string, ok bool) {
+	parts := strings.Split(externalID, ":")
+	if len(parts) < 3 || parts[0] != "tmdb" {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// SeriesId returns the ExternalId shared by the episodes of a series, e.g. "tmdb:tv:1396" for
+// "tmdb:tv:1396:s1e2" or "library:tv:Show:s1e2" for a scanned episode; ok is false for other titles.
+func SeriesId(externalID string) (string, bool) {
+	i := strings.LastIndex(externalID, ":s")
+	if i < 0 || !strings.Contains(externalID, ":"+KIND_TV+":") {
+		return "", false
+	}
+	var season, episode int
+	if _, err := fmt.Sscanf(externalID[i+1:], "s%de%d", &season, &episode); err != nil {
+		return "", false
+	}
+	return externalID[:i], true
+}
+
+// episodeSuffix ends the titles of episodes, e.g. " S01E02" or " S01E02 - Pilot".
+var episodeSuffix = regexp.MustCompile(`(?i)\s+s\d+\s*e\d+(\s.*)?$`)
+
+// SeriesTitle returns the title of the series of an episode from its own title, "Show S01E02 - Pilot"
+// gives "Show"; the episodes of a series may also all have the series title.
+func SeriesTitle(title string) string {
+	return strings.TrimSpace(episodeSuffix.ReplaceAllString(title, ""))
+}
+
+// Apply copies the provider metadata into movie. Fields already set are kept unless
+// overwrite is true; episode, when given, describes the episode movie stands for.
+func Apply(movie *models.Movies, title *Title, episode *Episode, overwrite bool) {
+	set := func(field *string, value string) {
+		if value != "" && (overwrite || *field == "") {
+			*field = value
+		}
+	}
+	// episodes are titled on their own, titles are unique in the catalog
+	name, overview := title.Title, title.Overview
+	if episode != nil {
+		if episode.Title != "" {
+			name = episode.Title
+		}
+		if episode.Overview != "" {
+			overview = episode.Overview
+		}
+	}
+	set(&movie.Title, name)
+	set(&movie.Details, overview)
+	set(&movie.Poster, title.Poster)
+	set(&movie.Genre, strings.Join(title.Genres, config.LIST_SEPARATORS[0]+" "))
+	var actors []string
+	for _, member := range title.Cast {
+		actors = append(actors, member.Name)
+	}
+	set(&movie.Actors, strings.Join(actors, config.LIST_SEPARATORS[0]+" "))
+	if level, ok := config.MaturityLevel(config.MATURITY_DEFAULT_REGION, title.Certification); ok {
+		if overwrite || movie.Maturity == "" {
+			movie.Maturity = title.Certification
+			movie.MaturityLevel = level
+		}
+	}
+	if movie.ExternalId == "" {
+		movie.ExternalId = ExternalId(title.Kind, title.Id, movie.Saison, movie.Episode)
+	}
+}
@@ -0,0 +1,216 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"goflix/config"
+)
+
+// TMDB is a MetadataProvider for the TMDB v3 API and the services mimicking it.
+type TMDB struct {
+	baseURL  string
+	apiKey   string
+	language string
+	client   *http.Client
+}
+
+func NewTMDB(baseURL string, apiKey string, language string) *TMDB {
+	return &TMDB{
+		baseURL:  baseURL,
+		apiKey:   apiKey,
+		language: language,
+		client:   &http.Client{Timeout: config.METADATA_TIMEOUT},
+	}
+}
+
+type tmdbCredits struct {
+	Cast []struct {
+		Name      string `json:"name"`
+		Character string `json:"character"`
+		Order     int    `json:"order"`
+	} `json:"cast"`
+	Crew []struct {
+		Name       string `json:"name"`
+		Job        string `json:"job"`
+		Department string `json:"department"`
+	} `json:"crew"`
+}
+
+type tmdbTitle struct {
+	Id           int    `json:"id"`
+	Title        string `json:"title"`
+	Name         string `json:"name"`
+	Overview     string `json:"overview"`
+	ReleaseDate  string `json:"release_date"`
+	FirstAirDate string `json:"first_air_date"`
+	PosterPath   string `json:"poster_path"`
+	Genres       []struct {
+		Name string `json:"name"`
+	} `json:"genres"`
+	NumberOfSeasons int `json:"number_of_seasons"`
+	CreatedBy       []struct {
+		Name string `json:"name"`
+	} `json:"created_by"`
+	Credits      tmdbCredits `json:"credits"`
+	ReleaseDates struct {
+		Results []struct {
+			Country      string `json:"iso_3166_1"`
+			ReleaseDates []struct {
+				Certification string `json:"certification"`
+			} `json:"release_dates"`
+		} `json:"results"`
+	} `json:"release_dates"`
+	ContentRatings struct {
+		Results []struct {
+			Country string `json:"iso_3166_1"`
+			Rating  string `json:"rating"`
+		} `json:"results"`
+	} `json:"content_ratings"`
+}
+
+func (t *TMDB) Search(kind string, query string) ([]*Result, error) {
+	var body struct {
+		Results []*tmdbTitle `json:"results"`
+	}
+	err := t.get("/search/"+kind, url.Values{"query": {query}}, &body)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*Result, 0, len(body.Results))
+	for _, r := range body.Results {
+		title := t.title(kind, r)
+		results = append(results, &Result{
+			Id:       title.Id,
+			Kind:     kind,
+			Title:    title.Title,
+			Year:     title.Year,
+			Overview: title.Overview,
+			Poster:   title.Poster,
+		})
+	}
+	return results, nil
+}
+
+func (t *TMDB) Fetch(kind string, id string) (*Title, error) {
+	appendTo := "credits,release_dates"
+	if kind == KIND_TV {
+		appendTo = "credits,content_ratings"
+	}
+	var body tmdbTitle
+	err := t.get("/"+kind+"/"+url.PathEscape(id), url.Values{"append_to_response": {appendTo}}, &body)
+	if err != nil {
+		return nil, err
+	}
+	return t.title(kind, &body), nil
+}
+
+func (t *TMDB) Episodes(id string, season int) ([]*Episode, error) {
+	var body struct {
+		SeasonNumber int `json:"season_number"`
+		Episodes     []struct {
+			EpisodeNumber int    `json:"episode_number"`
+			Name          string `json:"name"`
+			Overview      string `json:"overview"`
+		} `json:"episodes"`
+	}
+	err := t.get(fmt.Sprintf("/tv/%s/season/%d", url.PathEscape(id), season), nil, &body)
+	if err != nil {
+		return nil, err
+	}
+	episodes := make([]*Episode, 0, len(body.Episodes))
+	for _, e := range body.Episodes {
+		episodes = append(episodes, &Episode{Season: body.SeasonNumber, Episode: e.EpisodeNumber, Title: e.Name, Overview: e.Overview})
+	}
+	return episodes, nil
+}
+
+func (t *TMDB) title(kind string, body *tmdbTitle) *Title {
+	title := Title{
+		Id:       strconv.Itoa(body.Id),
+		Kind:     kind,
+		Title:    body.Title,
+		Overview: body.Overview,
+		Seasons:  body.NumberOfSeasons,
+	}
+	date := body.ReleaseDate
+	if kind == KIND_TV {
+		title.Title = body.Name
+		date = body.FirstAirDate
+	}
+	if len(date) >= 4 {
+		title.Year = date[:4]
+	}
+	if body.PosterPath != "" {
+		title.Poster = config.TMDB_IMAGE_URL + body.PosterPath
+	}
+	for _, genre := range body.Genres {
+		title.Genres = append(title.Genres, genre.Name)
+	}
+	// the API returns the cast ordered by billing
+	for _, member := range body.Credits.Cast {
+		if len(title.Cast) == config.METADATA_CAST_LIMIT {
+			break
+		}
+		title.Cast = append(title.Cast, &CastMember{Name: member.Name, Character: member.Character})
+	}
+	for _, creator := range body.CreatedBy {
+		title.Directors = append(title.Directors, creator.Name)
+	}
+	for _, member := range body.Credits.Crew {
+		switch {
+		case member.Job == "Director":
+			title.Directors = append(title.Directors, member.Name)
+		case member.Department == "Writing":
+			title.Writers = append(title.Writers, member.Name)
+		}
+	}
+	for _, result := range body.ReleaseDates.Results {
+		if result.Country != config.MATURITY_DEFAULT_REGION {
+			continue
+		}
+		for _, release := range result.ReleaseDates {
+			if release.Certification != "" {
+				title.Certification = release.Certification
+				break
+			}
+		}
+	}
+	for _, result := range body.ContentRatings.Results {
+		if result.Country == config.MATURITY_DEFAULT_REGION {
+			title.Certification = result.Rating
+		}
+	}
+	return &title
+}
+
+func (t *TMDB) get(path string, query url.Values, dest any) error {
+	if query == nil {
+		query = url.Values{}
+	}
+	if t.apiKey != "" {
+		query.Set("api_key", t.apiKey)
+	}
+	if t.language != "" {
+		query.Set("language", t.language)
+	}
+	resp, err := t.client.Get(t.baseURL + path + "?" + query.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			StatusMessage string `json:"status_message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return fmt.Errorf("metadata provider answered %d: %s", resp.StatusCode, body.StatusMessage)
+	}
+	return json.NewDecoder(resp.Body).Decode(dest)
+}
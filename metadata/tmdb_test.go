@@ -0,0 +1,43 @@
+package metadata
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTMDBSearch(t *testing.T) {
+	results, err := newFixtures("fixtures").Search(KIND_MOVIE, "The Matrix")
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) == 0 || results[0].Id != "603" || results[0].Title != "Matrix" || results[0].Year != "1999" {
+		t.Fatalf("got %+v, want Matrix (603, 1999) first", results)
+	}
+}
+
+func TestTMDBFetch(t *testing.T) {
+	title, err := newFixtures("fixtures").Fetch(KIND_MOVIE, "603")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if title.Title != "Matrix" || len(title.Cast) == 0 || len(title.Directors) == 0 {
+		t.Fatalf("got %+v, want Matrix with its cast and directors", title)
+	}
+}
+
+func TestTMDBFetchNotFound(t *testing.T) {
+	_, err := newFixtures("fixtures").Fetch(KIND_MOVIE, "1")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestTMDBEpisodes(t *testing.T) {
+	episodes, err := newFixtures("fixtures").Episodes("1396", 1)
+	if err != nil {
+		t.Fatalf("Episodes: %v", err)
+	}
+	if len(episodes) == 0 || episodes[0].Season != 1 || episodes[0].Episode != 1 || episodes[0].Title != "Chute libre" {
+		t.Fatalf("got %+v, want Chute libre as S01E01", episodes)
+	}
+}
@@ -1,9 +1,15 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"goflix/apierr"
+	"goflix/config"
+	"goflix/db"
 	"goflix/models"
 	"net/http"
+	"sync"
 	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
@@ -12,6 +18,12 @@ import (
 
 var secretKey = []byte("secret_key")
 
+const (
+	USER_ID_KEY      = "user_id"
+	USER_ACCOUNT_KEY = "user_account"
+	SUBSCRIPTION_KEY = "subscription"
+)
+
 func GenerateToken(id int, account string) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 	claims := token.Claims.(jwt.MapClaims)
@@ -30,8 +42,7 @@ func JwtMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing token"})
-			c.Abort()
+			apierr.Write(c, apierr.New(http.StatusUnauthorized, apierr.MISSING_TOKEN))
 			return
 		}
 
@@ -40,21 +51,97 @@ func JwtMiddleware() gin.HandlerFunc {
 		})
 
 		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token invalide"})
-			c.Abort()
+			apierr.Write(c, apierr.New(http.StatusUnauthorized, apierr.INVALID_TOKEN))
+			return
+		}
+		user, err := extractUserData(tokenString)
+		if err != nil {
+			apierr.Write(c, apierr.New(http.StatusUnauthorized, apierr.INVALID_TOKEN).WithDetail(err.Error()))
 			return
 		}
+		c.Set(USER_ID_KEY, user.Id)
+		c.Set(USER_ACCOUNT_KEY, user.Account)
 
 		c.Next()
 	}
 }
 
+// Tickets are short-lived single-use credentials for the clients that can only authenticate
+// through the URL, like the browser EventSource: a token in the URL would end in access logs.
+type Tickets struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	tickets map[string]ticket
+}
+
+type ticket struct {
+	user    *models.User
+	expires time.Time
+}
+
+func NewTickets(ttl time.Duration) *Tickets {
+	return &Tickets{ttl: ttl, tickets: map[string]ticket{}}
+}
+
+// Issue returns a new ticket of user and its expiry.
+func (t *Tickets) Issue(user *models.User) (string, time.Time, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	value := hex.EncodeToString(b)
+	now := time.Now()
+	expires := now.Add(t.ttl)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// tickets never used are dropped with the next ones
+	for key, old := range t.tickets {
+		if now.After(old.expires) {
+			delete(t.tickets, key)
+		}
+	}
+	t.tickets[value] = ticket{user: user, expires: expires}
+	return value, expires, nil
+}
+
+// use consumes the ticket, false when unknown, used already or expired.
+func (t *Tickets) use(value string) (*models.User, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	found, ok := t.tickets[value]
+	delete(t.tickets, value)
+	if !ok || time.Now().After(found.expires) {
+		return nil, false
+	}
+	return found.user, true
+}
+
+// TicketMiddleware authenticates with the ticket query parameter, or like JwtMiddleware without one.
+func TicketMiddleware(tickets *Tickets) gin.HandlerFunc {
+	jwtMiddleware := JwtMiddleware()
+	return func(c *gin.Context) {
+		value := c.Query("ticket")
+		if value == "" {
+			jwtMiddleware(c)
+			return
+		}
+		user, ok := tickets.use(value)
+		if !ok {
+			apierr.Write(c, apierr.New(http.StatusUnauthorized, apierr.INVALID_TOKEN))
+			return
+		}
+		c.Set(USER_ID_KEY, user.Id)
+		c.Set(USER_ACCOUNT_KEY, user.Account)
+		c.Next()
+	}
+}
+
 func AdminOnly() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing token"})
-			c.Abort()
+			apierr.Write(c, apierr.New(http.StatusUnauthorized, apierr.MISSING_TOKEN))
 			return
 		}
 
@@ -63,21 +150,20 @@ func AdminOnly() gin.HandlerFunc {
 		})
 
 		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token invalide"})
-			c.Abort()
+			apierr.Write(c, apierr.New(http.StatusUnauthorized, apierr.INVALID_TOKEN))
 			return
 		}
 		user, err := extractUserData(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Data token invalide"})
-			c.Abort()
+			apierr.Write(c, apierr.New(http.StatusUnauthorized, apierr.INVALID_TOKEN).WithDetail(err.Error()))
 			return
 		}
 		if user.Account != "admin" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "acces denied, admin only"})
-			c.Abort()
+			apierr.Write(c, apierr.New(http.StatusForbidden, apierr.ADMIN_ONLY))
 			return
 		}
+		c.Set(USER_ID_KEY, user.Id)
+		c.Set(USER_ACCOUNT_KEY, user.Account)
 		c.Next()
 	}
 }
@@ -88,21 +174,80 @@ func extractUserData(tokenString string) (*models.User, error) {
 	})
 
 	if err != nil || !token.Valid {
-		return nil, fmt.Errorf("token invalide")
+		return nil, fmt.Errorf("invalid token")
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return nil, fmt.Errorf("impossible de récupérer les claims")
+		return nil, fmt.Errorf("cannot read the token claims")
 	}
 	userID, ok := claims["user_id"].(float64)
 	if !ok {
-		return nil, fmt.Errorf("user_id n'est pas présent ou n'est pas de type int64")
+		return nil, fmt.Errorf("user_id claim is missing or not a number")
 	}
 	userAccount, ok := claims["user_account"].(string)
 	if !ok {
-		return nil, fmt.Errorf("user_account n'est pas présent ou n'est pas de type string")
+		return nil, fmt.Errorf("user_account claim is missing or not a string")
 	}
 
 	return &models.User{Id: int(userID), Account: userAccount}, nil
 }
+
+// CurrentUser returns the user authenticated by JwtMiddleware or AdminOnly.
+func CurrentUser(c *gin.Context) (*models.User, bool) {
+	id, ok := c.Get(USER_ID_KEY)
+	if !ok {
+		return nil, false
+	}
+	return &models.User{Id: id.(int), Account: c.GetString(USER_ACCOUNT_KEY)}, true
+}
+
+// ActiveSubscription lets through admins and users whose subscription allows streaming.
+// It must run after JwtMiddleware.
+func ActiveSubscription(store db.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := CurrentUser(c)
+		if !ok {
+			apierr.Write(c, apierr.New(http.StatusUnauthorized, apierr.MISSING_TOKEN))
+			return
+		}
+		if user.Account == "admin" {
+			c.Next()
+			return
+		}
+		sub, err := store.GetSubscriptionByUser(c.Request.Context(), user.Id)
+		if err != nil && !db.IsNotFound(err) {
+			apierr.Write(c, err)
+			return
+		}
+		if err != nil {
+			apierr.Write(c, apierr.New(http.StatusPaymentRequired, apierr.SUBSCRIPTION_REQUIRED))
+			return
+		}
+		if !CanStream(sub, time.Now()) {
+			apierr.Write(c, apierr.New(http.StatusPaymentRequired, apierr.SUBSCRIPTION_INACTIVE, sub.Status))
+			return
+		}
+		c.Set(SUBSCRIPTION_KEY, sub)
+		c.Next()
+	}
+}
+
+func CanStream(sub *models.Subscription, now time.Time) bool {
+	switch sub.Status {
+	case config.SUBSCRIPTION_ACTIVE:
+		return sub.PeriodEnd == nil || now.Before(*sub.PeriodEnd)
+	case config.SUBSCRIPTION_TRIALING:
+		return sub.TrialEnd != nil && now.Before(*sub.TrialEnd)
+	}
+	return false
+}
+
+// CurrentSubscription returns the subscription checked by ActiveSubscription, nil for admins.
+func CurrentSubscription(c *gin.Context) *models.Subscription {
+	sub, ok := c.Get(SUBSCRIPTION_KEY)
+	if !ok {
+		return nil
+	}
+	return sub.(*models.Subscription)
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Event is a viewer action feeding the popularity of a title.
+type Event struct {
+	Id        int       `json:"id"`
+	MovieId   int       `json:"movieid"`
+	UserId    int       `json:"userid"`
+	Kind      string    `json:"kind"`
+	Region    string    `json:"region"`
+	CreatedAt time.Time `json:"createdat"`
+}
+
+// Popularity is the score of a title on a board in a region, Region "*" for all regions.
+type Popularity struct {
+	MovieId int     `json:"movieid"`
+	Region  string  `json:"region"`
+	Score   float64 `json:"score"`
+}
+
+// RankedTitle is a title of a board with its rank, starting at 1.
+type RankedTitle struct {
+	Rank  int     `json:"rank"`
+	Score float64 `json:"score"`
+	Movie *Movies `json:"movie"`
+}
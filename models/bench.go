@@ -0,0 +1,25 @@
+package models
+
+// BenchReport is the outcome of goflix bench, one result per request of each scenario.
+type BenchReport struct {
+	URL         string         `json:"url"`
+	Concurrency int            `json:"concurrency"`
+	Duration    string         `json:"duration"`
+	Results     []*BenchResult `json:"results"`
+}
+
+// BenchResult measures one request of a scenario: throughput is in requests per second, the
+// latencies of the responses in milliseconds, Statuses counts them by HTTP status ("error" when
+// none came back).
+type BenchResult struct {
+	Scenario   string         `json:"scenario"`
+	Request    string         `json:"request"`
+	Requests   int            `json:"requests"`
+	Failed     int            `json:"failed"`
+	Statuses   map[string]int `json:"statuses"`
+	Throughput float64        `json:"throughput"`
+	P50        float64        `json:"p50"`
+	P95        float64        `json:"p95"`
+	P99        float64        `json:"p99"`
+	Max        float64        `json:"max"`
+}
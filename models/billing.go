@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+type Plan struct {
+	Id         int    `json:"id"`
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	Price      int    `json:"price"`
+	Currency   string `json:"currency"`
+	MaxStreams int    `json:"maxstreams"`
+	MaxQuality string `json:"maxquality"`
+	TrialDays  int    `json:"trialdays"`
+}
+
+type Subscription struct {
+	Id          int        `json:"id"`
+	UserId      int        `json:"userid"`
+	PlanId      int        `json:"planid"`
+	Status      string     `json:"status"`
+	ProviderRef string     `json:"providerref"`
+	TrialEnd    *time.Time `json:"trialend,omitempty"`
+	PeriodEnd   *time.Time `json:"periodend,omitempty"`
+	// CancelAtPeriodEnd is set by a cancellation, the subscription gives access until PeriodEnd
+	CancelAtPeriodEnd bool      `json:"cancelatperiodend"`
+	Created           time.Time `json:"created"`
+	Updated           time.Time `json:"updated"`
+	Plan              *Plan     `json:"plan,omitempty"`
+}
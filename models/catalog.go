@@ -0,0 +1,39 @@
+package models
+
+import "goflix/config"
+
+// ImportRow is one line of an import file and its outcome.
+type ImportRow struct {
+	Line       int     `json:"line"`
+	ExternalId string  `json:"externalid,omitempty"`
+	MovieId    int     `json:"movieid,omitempty"`
+	Action     string  `json:"action"`
+	Error      string  `json:"error,omitempty"`
+	Movie      *Movies `json:"-"`
+}
+
+type ImportReport struct {
+	Format    string       `json:"format"`
+	DryRun    bool         `json:"dryrun"`
+	Atomic    bool         `json:"atomic"`
+	Committed bool         `json:"committed"`
+	Created   int          `json:"created"`
+	Updated   int          `json:"updated"`
+	Unchanged int          `json:"unchanged"`
+	Failed    int          `json:"failed"`
+	Rows      []*ImportRow `json:"rows"`
+}
+
+// CreatedIds returns the titles the import created, none unless it was committed.
+func (r *ImportReport) CreatedIds() []int {
+	var ids []int
+	if !r.Committed {
+		return ids
+	}
+	for _, row := range r.Rows {
+		if row.Action == config.IMPORT_CREATED {
+			ids = append(ids, row.MovieId)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,8 @@
+package models
+
+type Genre struct {
+	Id    int               `json:"id"`
+	Slug  string            `json:"slug"`
+	Name  string            `json:"name"`
+	Names map[string]string `json:"names,omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// HomeRow defines a row of the home screen, Genre is the genre slug of genre rows.
+type HomeRow struct {
+	Id       int    `json:"id"`
+	Position int    `json:"position"`
+	Kind     string `json:"kind"`
+	Title    string `json:"title"`
+	Genre    string `json:"genre,omitempty"`
+	Size     int    `json:"size"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// HomeRowPage is a page of the titles of a row for a user.
+type HomeRowPage struct {
+	*HomeRow
+	Page    int       `json:"page"`
+	HasMore bool      `json:"hasmore"`
+	Movies  []*Movies `json:"movies"`
+}
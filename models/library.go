@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"goflix/config"
+)
+
+// MediaFile is a video file of the library linked to the title it holds.
+type MediaFile struct {
+	Id        int       `json:"id"`
+	MovieId   int       `json:"movieid"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"modtime"`
+	ScannedAt time.Time `json:"scannedat"`
+}
+
+// ScanFile is the outcome of a library scan for one video file.
+type ScanFile struct {
+	Path       string `json:"path"`
+	ExternalId string `json:"externalid,omitempty"`
+	MovieId    int    `json:"movieid,omitempty"`
+	Action     string `json:"action"`
+	Error      string `json:"error,omitempty"`
+}
+
+type ScanReport struct {
+	Full      bool        `json:"full"`
+	Created   int         `json:"created"`
+	Updated   int         `json:"updated"`
+	Unchanged int         `json:"unchanged"`
+	Skipped   int         `json:"skipped"`
+	Failed    int         `json:"failed"`
+	Removed   int         `json:"removed"`
+	Files     []*ScanFile `json:"files"`
+	// Unreadable lists the folders and files skipped because they could not be read
+	Unreadable []string `json:"unreadable,omitempty"`
+}
+
+// CreatedIds returns the titles the scan created.
+func (r *ScanReport) CreatedIds() []int {
+	var ids []int
+	for _, file := range r.Files {
+		if file.Action == config.IMPORT_CREATED {
+			ids = append(ids, file.MovieId)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// List is a named list of titles of a user, ShareToken is only set for link lists.
+type List struct {
+	Id         int       `json:"id"`
+	UserId     int       `json:"userid"`
+	Name       string    `json:"name"`
+	Visibility string    `json:"visibility"`
+	ShareToken string    `json:"sharetoken,omitempty"`
+	IsDefault  bool      `json:"isdefault"`
+	Count      int       `json:"count"`
+	Created    time.Time `json:"created"`
+	Updated    time.Time `json:"updated"`
+}
+
+// ListPage is a list with the titles the viewer may see, in the order of the list.
+type ListPage struct {
+	*List
+	Movies []*Movies `json:"movies"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LiveEvent is pushed to the connected clients of a user, Device is the device whose request
+// caused it, if any.
+type LiveEvent struct {
+	Type   string    `json:"type"`
+	Device string    `json:"device,omitempty"`
+	At     time.Time `json:"at"`
+	Data   any       `json:"data"`
+}
@@ -1,12 +1,72 @@
 package models
 
+import "time"
+
 type Movies struct {
-	Id      int    `json:"id"`
+	Id         int    `json:"id"`
+	ExternalId string `json:"externalid,omitempty"`
+	Title      string `json:"title"`
+	Actors     string `json:"actors"`
+	Rating     int    `json:"rating"`
+	Details    string `json:"details"`
+	Genre      string `json:"genre"`
+	Saison     int    `json:"saison"`
+	Episode    int    `json:"episode"`
+	Poster     string `json:"poster,omitempty"`
+
+	Maturity      string `json:"maturity"`
+	MaturityLevel int    `json:"maturitylevel"`
+
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publishat,omitempty"`
+
+	AvailableUntil *time.Time `json:"availableuntil,omitempty"`
+
+	// Locale is the translation used for Title and Details, empty for the original text.
+	Locale string `json:"locale,omitempty"`
+
+	Genres      []*Genre      `json:"genres,omitempty"`
+	Credits     []*Credit     `json:"credits,omitempty"`
+	Subtitles   []*Subtitle   `json:"subtitles,omitempty"`
+	AudioTracks []*AudioTrack `json:"audiotracks,omitempty"`
+}
+
+type MovieTranslation struct {
+	MovieId int    `json:"movieid"`
+	Locale  string `json:"locale"`
 	Title   string `json:"title"`
-	Actors  string `json:"actors"`
-	Rating  int    `json:"rating"`
 	Details string `json:"details"`
-	Genre   string `json:"genre"`
-	Saison  int    `json:"saison"`
-	Episode int    `json:"episode"`
+}
+
+type Subtitle struct {
+	Id      int    `json:"id"`
+	MovieId int    `json:"movieid"`
+	Lang    string `json:"lang"`
+	Label   string `json:"label"`
+	Forced  bool   `json:"forced"`
+	Content string `json:"-"`
+}
+
+type AudioTrack struct {
+	Id      int    `json:"id"`
+	MovieId int    `json:"movieid"`
+	Lang    string `json:"lang"`
+	Label   string `json:"label"`
+	Role    string `json:"role"`
+	Default bool   `json:"default"`
+}
+
+// CatalogFilter describes what a viewer is allowed to see in the catalog.
+type CatalogFilter struct {
+	MaxMaturity int
+	Region      string
+}
+
+// Availability is a licensing window of a title in a country, Country "*" covers every region.
+type Availability struct {
+	Id      int        `json:"id"`
+	MovieId int        `json:"movieid"`
+	Country string     `json:"country"`
+	Starts  time.Time  `json:"starts"`
+	Ends    *time.Time `json:"ends,omitempty"`
 }
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Notification tells a user about a title, Title, Saison and Episode are read from the title
+// and Ends is when a leaving title goes away.
+type Notification struct {
+	Id      int        `json:"id"`
+	UserId  int        `json:"userid"`
+	Kind    string     `json:"kind"`
+	MovieId int        `json:"movieid"`
+	Title   string     `json:"title"`
+	Saison  int        `json:"saison,omitempty"`
+	Episode int        `json:"episode,omitempty"`
+	Ends    *time.Time `json:"ends,omitempty"`
+	InApp   bool       `json:"-"`
+	Email   bool       `json:"-"`
+	Created time.Time  `json:"created"`
+	ReadAt  *time.Time `json:"readat,omitempty"`
+}
+
+// NotificationPreferences tells for each kind of notification which channels are on.
+type NotificationPreferences map[string]map[string]bool
@@ -0,0 +1,17 @@
+package models
+
+type Person struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type Credit struct {
+	Id        int    `json:"id"`
+	PersonId  int    `json:"personid"`
+	Name      string `json:"name"`
+	MovieId   int    `json:"movieid"`
+	Title     string `json:"title"`
+	Role      string `json:"role"`
+	Character string `json:"character,omitempty"`
+	Billing   int    `json:"billing"`
+}
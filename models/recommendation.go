@@ -0,0 +1,21 @@
+package models
+
+// Similarity links a title to one of its nearest neighbours in a recommendation model.
+type Similarity struct {
+	MovieId   int     `json:"movieid"`
+	SimilarId int     `json:"similarid"`
+	Score     float64 `json:"score"`
+}
+
+// Recommendation is a title suggested to a user, Because is the title that led to it.
+type Recommendation struct {
+	Movie   *Movies   `json:"movie"`
+	Score   float64   `json:"score"`
+	Reason  string    `json:"reason"`
+	Because *MovieRef `json:"because,omitempty"`
+}
+
+type MovieRef struct {
+	Id    int    `json:"id"`
+	Title string `json:"title"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Review is the text a user wrote along with their rating of a title, Stars is that rating.
+// Flag tells why a review waits for moderation, Reports counts reports since the last moderation.
+type Review struct {
+	Id             int        `json:"id"`
+	MovieId        int        `json:"movieid"`
+	UserId         int        `json:"userid"`
+	User           string     `json:"user"`
+	Stars          int        `json:"stars"`
+	Body           string     `json:"body"`
+	Spoiler        bool       `json:"spoiler"`
+	Status         string     `json:"status"`
+	Flag           string     `json:"flag,omitempty"`
+	Helpful        int        `json:"helpful"`
+	Reports        int        `json:"reports,omitempty"`
+	Created        time.Time  `json:"created"`
+	Updated        time.Time  `json:"updated"`
+	ModeratedBy    int        `json:"moderatedby,omitempty"`
+	ModeratedAt    *time.Time `json:"moderatedat,omitempty"`
+	ModerationNote string     `json:"moderationnote,omitempty"`
+}
+
+type ReviewReport struct {
+	ReviewId int       `json:"reviewid"`
+	UserId   int       `json:"userid"`
+	Reason   string    `json:"reason"`
+	Created  time.Time `json:"created"`
+}
+
+// ReviewQuery selects reviews, MovieId 0 selects the reviews of every title.
+type ReviewQuery struct {
+	MovieId  int
+	Status   string
+	Spoilers bool
+	Sort     string
+}
@@ -1,5 +1,11 @@
 package models
 
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
 type User struct {
 	Id      int    `json:"id"`
 	User    string `json:"user"`
@@ -21,8 +27,42 @@ type Favorite struct {
 	MoviesID string `json:"moviesid"`
 }
 
+// MovieIds reads the favorite list, stored as "#3|#8|", in the order titles were added.
+func (f *Favorite) MovieIds() []int {
+	var ids []int
+	for _, item := range strings.Split(f.MoviesID, "|") {
+		if id, err := strconv.Atoi(strings.TrimPrefix(item, "#")); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 type Rating struct {
 	MovieId int `json:"movieid"`
 	Stars   int `json:"stars"`
 	UserId  int `json:"userid"`
 }
+
+type StreamSession struct {
+	Id        string     `json:"id"`
+	UserId    int        `json:"userid"`
+	MovieId   int        `json:"movieid"`
+	Device    string     `json:"device"`
+	Started   time.Time  `json:"started"`
+	Heartbeat time.Time  `json:"heartbeat"`
+	Ended     *time.Time `json:"ended,omitempty"`
+	EndReason string     `json:"endreason,omitempty"`
+}
+
+type ParentalControl struct {
+	UserId   int    `json:"userid"`
+	MaxLevel int    `json:"maxlevel"`
+	Pin      string `json:"-"`
+}
+
+type UserSettings struct {
+	UserId   int    `json:"userid"`
+	Region   string `json:"region"`
+	Language string `json:"language"`
+}
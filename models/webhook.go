@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook is an endpoint of a downstream system, Events are the event types it receives.
+type Webhook struct {
+	Id      int       `json:"id"`
+	URL     string    `json:"url"`
+	Secret  string    `json:"secret"`
+	Events  []string  `json:"events"`
+	Enabled bool      `json:"enabled"`
+	Created time.Time `json:"created"`
+}
+
+// WebhookDelivery is an event queued for a webhook. EventId is the same for every webhook
+// receiving the event, receivers use it to ignore redeliveries.
+type WebhookDelivery struct {
+	Id           int               `json:"id"`
+	WebhookId    int               `json:"webhookid"`
+	EventId      string            `json:"eventid"`
+	Event        string            `json:"event"`
+	Payload      json.RawMessage   `json:"payload"`
+	Status       string            `json:"status"`
+	Attempts     int               `json:"attempts"`
+	NextAttempt  *time.Time        `json:"nextattempt,omitempty"`
+	LastAttempt  *time.Time        `json:"lastattempt,omitempty"`
+	ResponseCode int               `json:"responsecode,omitempty"`
+	LastError    string            `json:"lasterror,omitempty"`
+	Created      time.Time         `json:"created"`
+	AttemptLog   []*WebhookAttempt `json:"attemptlog,omitempty"`
+}
+
+// WebhookAttempt logs one try of a delivery, Duration is in milliseconds.
+type WebhookAttempt struct {
+	DeliveryId   int       `json:"deliveryid"`
+	Attempted    time.Time `json:"attempted"`
+	ResponseCode int       `json:"responsecode,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	Duration     int64     `json:"duration"`
+}
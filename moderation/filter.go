@@ -0,0 +1,88 @@
+package moderation
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"goflix/utils"
+)
+
+// Filter finds banned words or phrases in a text. Matching is on whole words, ignoring case,
+// accents and punctuation, so "Merde!" matches "merde" but "merdeux" doesn't.
+type Filter struct {
+	phrases [][]string
+}
+
+func NewFilter(words []string) *Filter {
+	f := &Filter{}
+	seen := map[string]bool{}
+	for _, word := range words {
+		phrase := tokens(word)
+		key := strings.Join(phrase, " ")
+		if len(phrase) > 0 && !seen[key] {
+			seen[key] = true
+			f.phrases = append(f.phrases, phrase)
+		}
+	}
+	return f
+}
+
+func Load(path string) (*Filter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Read(file)
+}
+
+// Read reads one word or phrase per line, lines starting with # are comments.
+func Read(r io.Reader) (*Filter, error) {
+	var words []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewFilter(words), nil
+}
+
+// Check returns the banned phrases found in text, each once.
+func (f *Filter) Check(text string) []string {
+	words := tokens(text)
+	var found []string
+	for _, phrase := range f.phrases {
+		for i := 0; i+len(phrase) <= len(words); i++ {
+			if equal(words[i:i+len(phrase)], phrase) {
+				found = append(found, strings.Join(phrase, " "))
+				break
+			}
+		}
+	}
+	return found
+}
+
+func tokens(text string) []string {
+	slug := utils.Slugify(text)
+	if slug == "" {
+		return nil
+	}
+	return strings.Split(slug, "-")
+}
+
+func equal(a []string, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
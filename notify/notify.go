@@ -0,0 +1,211 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"goflix/config"
+	"goflix/db"
+	"goflix/i18n"
+	"goflix/mailer"
+	"goflix/models"
+)
+
+// Notify sends the notification through the channels the user keeps on for its kind. It returns
+// false when the user turned them all off or was already notified.
+func Notify(ctx context.Context, store db.Storage, notification *models.Notification) (bool, error) {
+	prefs, err := store.GetNotificationPreferences(ctx, notification.UserId)
+	if err != nil {
+		return false, err
+	}
+	notification.InApp = prefs[notification.Kind][config.CHANNEL_IN_APP]
+	notification.Email = prefs[notification.Kind][config.CHANNEL_EMAIL]
+	if !notification.InApp && !notification.Email {
+		return false, nil
+	}
+	return store.AddNotification(ctx, notification)
+}
+
+// LeavingSoon notifies the users having in their list a title which leaves their region within
+// NOTIFY_LEAVING_SOON_DAYS, and returns how many notifications were made.
+func LeavingSoon(ctx context.Context, store db.Storage, now time.Time) (int, error) {
+	lists, err := store.GetAllFavorites(ctx)
+	if err != nil {
+		return 0, err
+	}
+	until := now.AddDate(0, 0, config.NOTIFY_LEAVING_SOON_DAYS)
+	// users of the same region and maturity level see the same titles leaving
+	leaving := map[models.CatalogFilter]map[int]*models.Movies{}
+	notified := 0
+	for userID, movieIDs := range lists {
+		filter, err := userFilter(ctx, store, userID)
+		if err != nil {
+			return notified, err
+		}
+		movies, ok := leaving[*filter]
+		if !ok {
+			list, err := store.GetLeavingSoon(ctx, filter, until)
+			if err != nil {
+				return notified, err
+			}
+			movies = map[int]*models.Movies{}
+			for _, movie := range list {
+				movies[movie.Id] = movie
+			}
+			leaving[*filter] = movies
+		}
+		for _, movieID := range movieIDs {
+			movie, ok := movies[movieID]
+			if !ok {
+				continue
+			}
+			added, err := Notify(ctx, store, &models.Notification{
+				UserId: userID, Kind: config.NOTIFY_LEAVING_SOON, MovieId: movieID, Ends: movie.AvailableUntil,
+			})
+			if err != nil {
+				return notified, err
+			}
+			if added {
+				notified++
+			}
+		}
+	}
+	return notified, nil
+}
+
+// userFilter is the catalog the user sees, as the API shows it to them.
+func userFilter(ctx context.Context, store db.Storage, userID int) (*models.CatalogFilter, error) {
+	control, err := store.GetParentalControl(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := store.GetUserSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	region := settings.Region
+	if region == "" {
+		region = config.DEFAULT_REGION
+	}
+	return &models.CatalogFilter{MaxMaturity: control.MaxLevel, Region: region}, nil
+}
+
+// Digest emails each user the notifications made since their last digest and returns how many
+// emails were sent. A user whose email fails gets it at the next run.
+func Digest(ctx context.Context, store db.Storage, m mailer.Mailer, now time.Time) (int, error) {
+	pending, err := store.GetPendingEmailNotifications(ctx)
+	if err != nil {
+		return 0, err
+	}
+	byUser := map[int][]*models.Notification{}
+	var users []int
+	for _, notification := range pending {
+		if _, ok := byUser[notification.UserId]; !ok {
+			users = append(users, notification.UserId)
+		}
+		byUser[notification.UserId] = append(byUser[notification.UserId], notification)
+	}
+
+	sent := 0
+	for _, userID := range users {
+		notifications := byUser[userID]
+		ids := make([]int, len(notifications))
+		for i, notification := range notifications {
+			ids[i] = notification.Id
+		}
+		user, err := store.GetUser(ctx, userID)
+		if err != nil && !db.IsNotFound(err) {
+			return sent, err
+		}
+		// without an address there is nobody to send them to, they stay in-app only
+		if user != nil && user.Info.Mail != "" {
+			settings, err := store.GetUserSettings(ctx, userID)
+			if err != nil {
+				return sent, err
+			}
+			subject, body := render(catalog(settings.Language), user, notifications)
+			if err := m.Send(user.Info.Mail, subject, body); err != nil {
+				log.Printf("digest for user %d: %v", userID, err)
+				continue
+			}
+			sent++
+		}
+		err = store.MarkNotificationsEmailed(ctx, ids, now)
+		if err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+type texts struct {
+	subject     string
+	greeting    string
+	newEpisode  string
+	episode     string
+	leavingSoon string
+	date        string
+	footer      string
+}
+
+var catalogs = map[string]texts{
+	"en": {
+		subject:     "Your Goflix news (%d)",
+		greeting:    "Hello %s,",
+		newEpisode:  "New episode of %s",
+		episode:     " (season %d, episode %d)",
+		leavingSoon: "%s leaves the catalog on %s",
+		date:        "January 2",
+		footer:      "You receive this digest as your notification preferences ask for it.",
+	},
+	"fr": {
+		subject:     "Vos nouveautés Goflix (%d)",
+		greeting:    "Bonjour %s,",
+		newEpisode:  "Nouvel épisode de %s",
+		episode:     " (saison %d, épisode %d)",
+		leavingSoon: "%s quitte le catalogue le %s",
+		date:        "02/01",
+		footer:      "Vous recevez ce récapitulatif selon vos préférences de notification.",
+	},
+}
+
+func catalog(language string) texts {
+	for _, locale := range i18n.Chain(language) {
+		if t, ok := catalogs[locale]; ok {
+			return t
+		}
+	}
+	return catalogs[config.DEFAULT_LOCALE]
+}
+
+func render(t texts, user *models.User, notifications []*models.Notification) (string, string) {
+	name := user.Info.Firstname
+	if name == "" {
+		name = user.User
+	}
+	var body strings.Builder
+	fmt.Fprintf(&body, t.greeting+"\n\n", name)
+	for _, notification := range notifications {
+		switch notification.Kind {
+		case config.NOTIFY_NEW_EPISODE:
+			body.WriteString("- " + fmt.Sprintf(t.newEpisode, notification.Title))
+			if notification.Saison > 0 {
+				fmt.Fprintf(&body, t.episode, notification.Saison, notification.Episode)
+			}
+		case config.NOTIFY_LEAVING_SOON:
+			ends := ""
+			if notification.Ends != nil {
+				ends = notification.Ends.Format(t.date)
+			}
+			body.WriteString("- " + fmt.Sprintf(t.leavingSoon, notification.Title, ends))
+		default:
+			continue
+		}
+		body.WriteString("\n")
+	}
+	body.WriteString("\n" + t.footer + "\n")
+	return fmt.Sprintf(t.subject, len(notifications)), body.String()
+}
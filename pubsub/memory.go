@@ -0,0 +1,67 @@
+package pubsub
+
+import (
+	"log"
+	"sync"
+)
+
+// Memory is a Broker for a single process. Each subscription buffers a few messages, a
+// subscriber too slow to keep up loses the messages that don't fit.
+type Memory struct {
+	mu     sync.RWMutex
+	topics map[string]map[*memorySubscription]bool
+	buffer int
+}
+
+type memorySubscription struct {
+	broker   *Memory
+	topic    string
+	messages chan []byte
+}
+
+func NewMemory(buffer int) *Memory {
+	return &Memory{topics: map[string]map[*memorySubscription]bool{}, buffer: buffer}
+}
+
+func (m *Memory) Publish(topic string, message []byte) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for sub := range m.topics[topic] {
+		select {
+		case sub.messages <- message:
+		default:
+			log.Printf("pubsub: subscriber of %s too slow, message dropped", topic)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(topic string) (Subscription, error) {
+	sub := &memorySubscription{broker: m, topic: topic, messages: make(chan []byte, m.buffer)}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.topics[topic] == nil {
+		m.topics[topic] = map[*memorySubscription]bool{}
+	}
+	m.topics[topic][sub] = true
+	return sub, nil
+}
+
+func (s *memorySubscription) Messages() <-chan []byte {
+	return s.messages
+}
+
+func (s *memorySubscription) Close() error {
+	m := s.broker
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.topics[s.topic][s] {
+		return nil
+	}
+	delete(m.topics[s.topic], s)
+	if len(m.topics[s.topic]) == 0 {
+		delete(m.topics, s.topic)
+	}
+	close(s.messages)
+	return nil
+}
@@ -0,0 +1,15 @@
+package pubsub
+
+// Broker delivers the messages published on a topic to the subscribers of the topic at that
+// time, nothing is kept for later subscribers. Messages are opaque bytes so that the in-process
+// broker can be replaced by one shared between instances, e.g. on Redis.
+type Broker interface {
+	Publish(topic string, message []byte) error
+	Subscribe(topic string) (Subscription, error)
+}
+
+type Subscription interface {
+	// Messages is closed when the subscription is.
+	Messages() <-chan []byte
+	Close() error
+}
@@ -0,0 +1,139 @@
+package recommend
+
+import (
+	"math"
+	"sort"
+
+	"goflix/config"
+	"goflix/models"
+)
+
+// ItemSimilarities is the item-item collaborative filtering model: the adjusted cosine
+// similarity of every pair of titles rated by at least RECOMMENDATION_MIN_CORATERS users,
+// ratings centered on the mean of their user.
+func ItemSimilarities(ratings []*models.Rating) []*models.Similarity {
+	byUser := map[int][]*models.Rating{}
+	for _, rating := range ratings {
+		byUser[rating.UserId] = append(byUser[rating.UserId], rating)
+	}
+
+	type pair struct{ a, b int }
+	type sums struct {
+		dot, normA, normB float64
+		users             int
+	}
+	pairs := map[pair]*sums{}
+	for _, userRatings := range byUser {
+		mean := 0.0
+		for _, rating := range userRatings {
+			mean += float64(rating.Stars)
+		}
+		mean /= float64(len(userRatings))
+		for i, a := range userRatings {
+			for _, b := range userRatings[i+1:] {
+				key, ca, cb := pair{a.MovieId, b.MovieId}, float64(a.Stars)-mean, float64(b.Stars)-mean
+				if key.a > key.b {
+					key, ca, cb = pair{key.b, key.a}, cb, ca
+				}
+				s := pairs[key]
+				if s == nil {
+					s = &sums{}
+					pairs[key] = s
+				}
+				s.dot += ca * cb
+				s.normA += ca * ca
+				s.normB += cb * cb
+				s.users++
+			}
+		}
+	}
+
+	neighbours := map[int][]*models.Similarity{}
+	for key, s := range pairs {
+		if s.users < config.RECOMMENDATION_MIN_CORATERS || s.normA == 0 || s.normB == 0 {
+			continue
+		}
+		score := s.dot / math.Sqrt(s.normA*s.normB)
+		if score <= 0 {
+			continue
+		}
+		neighbours[key.a] = append(neighbours[key.a], &models.Similarity{MovieId: key.a, SimilarId: key.b, Score: score})
+		neighbours[key.b] = append(neighbours[key.b], &models.Similarity{MovieId: key.b, SimilarId: key.a, Score: score})
+	}
+	return nearest(neighbours)
+}
+
+// ContentSimilarities compares titles by their features, genres and credits, with the cosine
+// similarity of their feature sets.
+func ContentSimilarities(features map[int][]string) []*models.Similarity {
+	index := map[string][]int{}
+	for movieID, list := range features {
+		for _, feature := range list {
+			index[feature] = append(index[feature], movieID)
+		}
+	}
+	neighbours := map[int][]*models.Similarity{}
+	for movieID, list := range features {
+		shared := map[int]int{}
+		for _, feature := range list {
+			for _, other := range index[feature] {
+				if other != movieID {
+					shared[other]++
+				}
+			}
+		}
+		for other, n := range shared {
+			score := float64(n) / math.Sqrt(float64(len(list)*len(features[other])))
+			neighbours[movieID] = append(neighbours[movieID], &models.Similarity{MovieId: movieID, SimilarId: other, Score: score})
+		}
+	}
+	return nearest(neighbours)
+}
+
+// FavoriteSimilarities compares titles by the users having both in their default list, with the
+// cosine similarity of their sets of fans.
+func FavoriteSimilarities(favorites map[int][]int) []*models.Similarity {
+	type pair struct{ a, b int }
+	fans := map[int]int{}
+	together := map[pair]int{}
+	for _, list := range favorites {
+		for i, a := range list {
+			fans[a]++
+			for _, b := range list[i+1:] {
+				key := pair{a, b}
+				if key.a > key.b {
+					key = pair{b, a}
+				}
+				together[key]++
+			}
+		}
+	}
+	neighbours := map[int][]*models.Similarity{}
+	for key, n := range together {
+		if key.a == key.b {
+			continue
+		}
+		score := float64(n) / math.Sqrt(float64(fans[key.a]*fans[key.b]))
+		neighbours[key.a] = append(neighbours[key.a], &models.Similarity{MovieId: key.a, SimilarId: key.b, Score: score})
+		neighbours[key.b] = append(neighbours[key.b], &models.Similarity{MovieId: key.b, SimilarId: key.a, Score: score})
+	}
+	return nearest(neighbours)
+}
+
+// nearest keeps the RECOMMENDATION_NEIGHBORS best neighbours of each title.
+func nearest(neighbours map[int][]*models.Similarity) []*models.Similarity {
+	var similarities []*models.Similarity
+	for _, list := range neighbours {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Score != list[j].Score {
+				return list[i].Score > list[j].Score
+			}
+			return list[i].SimilarId < list[j].SimilarId
+		})
+		if len(list) > config.RECOMMENDATION_NEIGHBORS {
+			list = list[:config.RECOMMENDATION_NEIGHBORS]
+		}
+		similarities = append(similarities, list...)
+	}
+	return similarities
+}
@@ -0,0 +1,166 @@
+package recommend
+
+import (
+	"context"
+	"sort"
+
+	"goflix/config"
+	"goflix/db"
+	"goflix/models"
+)
+
+// Rebuild computes the models from the current ratings, catalog and favorites and replaces the stored ones.
+func Rebuild(ctx context.Context, store db.Storage) error {
+	ratings, err := store.GetAllRatings(ctx)
+	if err != nil {
+		return err
+	}
+	err = store.SaveSimilarities(ctx, config.SIMILARITY_RATINGS, ItemSimilarities(ratings))
+	if err != nil {
+		return err
+	}
+	features, err := store.GetMovieFeatures(ctx)
+	if err != nil {
+		return err
+	}
+	err = store.SaveSimilarities(ctx, config.SIMILARITY_CONTENT, ContentSimilarities(features))
+	if err != nil {
+		return err
+	}
+	favorites, err := store.GetAllFavorites(ctx)
+	if err != nil {
+		return err
+	}
+	return store.SaveSimilarities(ctx, config.SIMILARITY_FAVORITES, FavoriteSimilarities(favorites))
+}
+
+type candidate struct {
+	movieID int
+	score   float64
+	reason  string
+	because int
+
+	// weighted sum of the ratings of the neighbours, the best contribution gives because
+	sum, weights, best float64
+}
+
+// ForUser recommends up to limit titles visible through filter. Titles close to the ones the
+// user rated come first, scored by their predicted rating. Users with too few ratings for the
+// collaborative model get titles sharing genres and credits with the ones they liked, then
+// the most popular titles.
+func ForUser(ctx context.Context, store db.Storage, userID int, filter *models.CatalogFilter, limit int) ([]*models.Recommendation, error) {
+	ratings, err := store.GetRatingByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	stars := map[int]int{}
+	var rated, liked []int
+	for _, rating := range ratings {
+		stars[rating.MovieId] = rating.Stars
+		rated = append(rated, rating.MovieId)
+		if rating.Stars >= config.RECOMMENDATION_LIKED_STARS {
+			liked = append(liked, rating.MovieId)
+		}
+	}
+
+	candidates := map[int]*candidate{}
+	var ranked []*candidate
+	collect := func(similarities []*models.Similarity, reason string) {
+		var added []*candidate
+		for _, similarity := range similarities {
+			if _, ok := stars[similarity.SimilarId]; ok {
+				continue
+			}
+			c := candidates[similarity.SimilarId]
+			if c == nil {
+				c = &candidate{movieID: similarity.SimilarId, reason: reason}
+				candidates[c.movieID] = c
+				added = append(added, c)
+			}
+			if c.reason != reason {
+				continue
+			}
+			contribution := similarity.Score * float64(stars[similarity.MovieId])
+			c.sum += contribution
+			c.weights += similarity.Score
+			if contribution > c.best {
+				c.best, c.because = contribution, similarity.MovieId
+			}
+		}
+		for _, c := range added {
+			c.score = c.sum / c.weights
+			if reason == config.REASON_SIMILAR {
+				// content neighbours rank by closeness, the ratings are all liked ones
+				c.score = c.best
+			}
+		}
+		sort.Slice(added, func(i, j int) bool {
+			if added[i].score != added[j].score {
+				return added[i].score > added[j].score
+			}
+			return added[i].weights > added[j].weights
+		})
+		for _, c := range added {
+			if reason != config.REASON_RATED || c.score >= config.RECOMMENDATION_MIN_SCORE {
+				ranked = append(ranked, c)
+			}
+		}
+	}
+
+	similarities, err := store.GetSimilarities(ctx, config.SIMILARITY_RATINGS, rated)
+	if err != nil {
+		return nil, err
+	}
+	collect(similarities, config.REASON_RATED)
+	if len(ranked) < limit {
+		similarities, err = store.GetSimilarities(ctx, config.SIMILARITY_CONTENT, liked)
+		if err != nil {
+			return nil, err
+		}
+		collect(similarities, config.REASON_SIMILAR)
+	}
+	if len(ranked) < limit {
+		popular, err := store.GetPopularMovies(ctx, filter, limit+len(rated))
+		if err != nil {
+			return nil, err
+		}
+		for _, movie := range popular {
+			if _, ok := stars[movie.Id]; !ok && candidates[movie.Id] == nil {
+				c := &candidate{movieID: movie.Id, reason: config.REASON_POPULAR}
+				candidates[movie.Id] = c
+				ranked = append(ranked, c)
+			}
+		}
+	}
+
+	ids := make([]int, len(ranked))
+	for i, c := range ranked {
+		ids[i] = c.movieID
+	}
+	movies, err := store.GetMoviesByIds(ctx, ids, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(movies) > limit {
+		movies = movies[:limit]
+	}
+	titles, err := store.GetMoviesByIds(ctx, rated, nil)
+	if err != nil {
+		return nil, err
+	}
+	ratedTitles := map[int]string{}
+	for _, movie := range titles {
+		ratedTitles[movie.Id] = movie.Title
+	}
+
+	recommendations := make([]*models.Recommendation, 0, len(movies))
+	for _, movie := range movies {
+		c := candidates[movie.Id]
+		recommendation := models.Recommendation{Movie: movie, Score: c.score, Reason: c.reason}
+		if title, ok := ratedTitles[c.because]; ok {
+			recommendation.Because = &models.MovieRef{Id: c.because, Title: title}
+		}
+		recommendations = append(recommendations, &recommendation)
+	}
+	return recommendations, nil
+}
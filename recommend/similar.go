@@ -0,0 +1,223 @@
+package recommend
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"goflix/config"
+	"goflix/db"
+	"goflix/models"
+)
+
+// Similar finds the titles most like a given one. Results are cached per title until the
+// catalog version changes, or SIMILAR_CACHE_TTL at most for the rating and favorite signals.
+// The lock only guards the caches, the queries run without it and the requests missing the
+// same title wait for the one computing it.
+type Similar struct {
+	mu       sync.Mutex
+	current  *similarIndex
+	entries  map[int]*similarEntry
+	indexing *call[*similarIndex]
+	loading  map[int]*call[[]int]
+}
+
+// similarIndex holds the catalog signals of a catalog version.
+type similarIndex struct {
+	version  int
+	features map[int][]string
+	titles   map[string][]int
+	text     map[int]map[string]float64
+}
+
+type similarEntry struct {
+	ids     []int
+	expires time.Time
+}
+
+// call is a computation in flight, done is closed once val and err are set.
+type call[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+}
+
+func (c *call[T]) wait(ctx context.Context) (T, error) {
+	select {
+	case <-c.done:
+		return c.val, c.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+func NewSimilar() *Similar {
+	return &Similar{entries: map[int]*similarEntry{}, loading: map[int]*call[[]int]{}}
+}
+
+// Get returns up to limit titles like movieID visible through filter, most similar first.
+func (s *Similar) Get(ctx context.Context, store db.Storage, movieID int, filter *models.CatalogFilter, limit int) ([]*models.Movies, error) {
+	ids, err := s.similarIDs(ctx, store, movieID)
+	if err != nil {
+		return nil, err
+	}
+	movies, err := store.GetMoviesByIds(ctx, ids, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(movies) > limit {
+		movies = movies[:limit]
+	}
+	return movies, nil
+}
+
+func (s *Similar) similarIDs(ctx context.Context, store db.Storage, movieID int) ([]int, error) {
+	version, err := store.CatalogVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index, err := s.indexFor(ctx, store, version)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if entry, ok := s.entries[movieID]; ok && s.current == index && time.Now().Before(entry.expires) {
+		s.mu.Unlock()
+		return entry.ids, nil
+	}
+	if c, ok := s.loading[movieID]; ok {
+		s.mu.Unlock()
+		return c.wait(ctx)
+	}
+	c := &call[[]int]{done: make(chan struct{})}
+	s.loading[movieID] = c
+	s.mu.Unlock()
+
+	c.val, c.err = s.compute(ctx, store, index, movieID)
+	s.mu.Lock()
+	delete(s.loading, movieID)
+	// results of a replaced index are not cached
+	if c.err == nil && s.current == index {
+		s.entries[movieID] = &similarEntry{ids: c.val, expires: time.Now().Add(config.SIMILAR_CACHE_TTL)}
+	}
+	s.mu.Unlock()
+	close(c.done)
+	return c.val, c.err
+}
+
+// indexFor returns the index of the catalog version, loading it once when the catalog changed.
+func (s *Similar) indexFor(ctx context.Context, store db.Storage, version int) (*similarIndex, error) {
+	s.mu.Lock()
+	if s.current != nil && s.current.version == version {
+		index := s.current
+		s.mu.Unlock()
+		return index, nil
+	}
+	if s.indexing != nil {
+		c := s.indexing
+		s.mu.Unlock()
+		return c.wait(ctx)
+	}
+	c := &call[*similarIndex]{done: make(chan struct{})}
+	s.indexing = c
+	s.mu.Unlock()
+
+	c.val, c.err = loadIndex(ctx, store, version)
+	s.mu.Lock()
+	s.indexing = nil
+	if c.err == nil {
+		s.current = c.val
+		s.entries = map[int]*similarEntry{}
+	}
+	s.mu.Unlock()
+	close(c.done)
+	return c.val, c.err
+}
+
+// loadIndex loads the catalog signals.
+func loadIndex(ctx context.Context, store db.Storage, version int) (*similarIndex, error) {
+	movies, err := store.GetCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	features, err := store.GetMovieFeatures(ctx)
+	if err != nil {
+		return nil, err
+	}
+	details := map[int]string{}
+	for _, movie := range movies {
+		details[movie.Id] = movie.Details
+	}
+	index := &similarIndex{version: version, features: features, titles: map[string][]int{}, text: tfidf(details)}
+	for movieID, list := range features {
+		for _, feature := range list {
+			index.titles[feature] = append(index.titles[feature], movieID)
+		}
+	}
+	return index, nil
+}
+
+// compute blends the signals: shared genres and credits, close synopses, and the titles
+// rated alike or favorited by the same users.
+func (s *Similar) compute(ctx context.Context, store db.Storage, index *similarIndex, movieID int) ([]int, error) {
+	scores := map[int]float64{}
+
+	features := index.features[movieID]
+	shared := map[int]int{}
+	for _, feature := range features {
+		for _, other := range index.titles[feature] {
+			if other != movieID {
+				shared[other]++
+			}
+		}
+	}
+	for other, n := range shared {
+		scores[other] += config.SIMILAR_WEIGHT_CREDITS * float64(n) / math.Sqrt(float64(len(features)*len(index.features[other])))
+	}
+
+	if vector := index.text[movieID]; vector != nil {
+		for other, otherVector := range index.text {
+			if other != movieID {
+				scores[other] += config.SIMILAR_WEIGHT_TEXT * dot(vector, otherVector)
+			}
+		}
+	}
+
+	similarities, err := store.GetSimilarities(ctx, config.SIMILARITY_RATINGS, []int{movieID})
+	if err != nil {
+		return nil, err
+	}
+	for _, similarity := range similarities {
+		scores[similarity.SimilarId] += config.SIMILAR_WEIGHT_RATINGS * similarity.Score
+	}
+
+	// favorited together, precomputed by the scheduled rebuild
+	favorites, err := store.GetSimilarities(ctx, config.SIMILARITY_FAVORITES, []int{movieID})
+	if err != nil {
+		return nil, err
+	}
+	for _, similarity := range favorites {
+		scores[similarity.SimilarId] += config.SIMILAR_WEIGHT_FAVORITES * similarity.Score
+	}
+
+	ids := make([]int, 0, len(scores))
+	for id, score := range scores {
+		if score > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	if len(ids) > config.SIMILAR_CACHE_SIZE {
+		ids = ids[:config.SIMILAR_CACHE_SIZE]
+	}
+	return ids, nil
+}
@@ -0,0 +1,75 @@
+package recommend
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// stopWords are left out of synopsis comparisons, the catalog is written in French and English.
+var stopWords = map[string]bool{
+	"les": true, "des": true, "une": true, "dans": true, "pour": true, "par": true, "sur": true,
+	"qui": true, "que": true, "est": true, "son": true, "ses": true, "leur": true, "avec": true,
+	"pas": true, "plus": true, "mais": true, "aux": true, "ils": true, "elle": true, "sont": true,
+	"the": true, "and": true, "his": true, "her": true, "their": true, "with": true, "for": true,
+	"from": true, "that": true, "this": true, "who": true, "into": true, "are": true, "was": true,
+}
+
+// tokens splits a text into lowercase words of three letters or more, stop words excluded.
+func tokens(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	kept := words[:0]
+	for _, word := range words {
+		if len([]rune(word)) >= 3 && !stopWords[word] {
+			kept = append(kept, word)
+		}
+	}
+	return kept
+}
+
+// tfidf turns the documents into unit TF-IDF vectors.
+func tfidf(documents map[int]string) map[int]map[string]float64 {
+	counts := map[int]map[string]float64{}
+	documentFrequency := map[string]int{}
+	for id, text := range documents {
+		terms := map[string]float64{}
+		for _, token := range tokens(text) {
+			terms[token]++
+		}
+		for term := range terms {
+			documentFrequency[term]++
+		}
+		counts[id] = terms
+	}
+	vectors := map[int]map[string]float64{}
+	for id, terms := range counts {
+		norm := 0.0
+		for term, count := range terms {
+			weight := count * math.Log(float64(len(documents))/float64(documentFrequency[term]))
+			terms[term] = weight
+			norm += weight * weight
+		}
+		if norm == 0 {
+			continue
+		}
+		norm = math.Sqrt(norm)
+		for term := range terms {
+			terms[term] /= norm
+		}
+		vectors[id] = terms
+	}
+	return vectors
+}
+
+func dot(a map[string]float64, b map[string]float64) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	sum := 0.0
+	for term, weight := range a {
+		sum += weight * b[term]
+	}
+	return sum
+}
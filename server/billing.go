@@ -0,0 +1,261 @@
+package server
+
+import (
+	"context"
+	"goflix/apierr"
+	"goflix/billing"
+	"goflix/config"
+	"goflix/db"
+	"goflix/models"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// * * * PLANS * * *
+
+func (s *Serve) handelGetPlans(c *gin.Context) {
+	plans, err := s.db.GetPlans(c.Request.Context())
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"plans": plans})
+}
+
+func (s *Serve) handelAddPlan(c *gin.Context) {
+	var plan models.Plan
+	err := c.ShouldBindJSON(&plan)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	if plan.Code == "" || plan.Currency == "" || plan.Price < 0 || plan.MaxStreams < 0 || plan.TrialDays < 0 {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PLAN))
+		return
+	}
+	err = s.db.AddPlan(c.Request.Context(), &plan)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// * * * SUBSCRIPTION * * *
+
+func (s *Serve) handelGetSubscription(c *gin.Context) {
+	if user := s.currentUser(c); user != nil {
+		sub, err := s.db.GetSubscriptionByUser(c.Request.Context(), user.Id)
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, sub)
+	}
+}
+
+func (s *Serve) handelSubscribe(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	var body struct {
+		Plan string `json:"plan"`
+	}
+	err := c.ShouldBindJSON(&body)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	plan, err := s.db.GetPlanByCode(c.Request.Context(), body.Plan)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+
+	// reading and saving in one unit of work keeps concurrent requests from both subscribing
+	ctx := c.Request.Context()
+	var sub *models.Subscription
+	status := http.StatusOK
+	err = s.db.WithTx(ctx, func(store db.Storage) error {
+		current, err := store.GetSubscriptionByUser(ctx, user.Id)
+		if err != nil && !db.IsNotFound(err) {
+			return err
+		}
+		// a subscription canceled at the end of its period is replaced by a new one
+		if current != nil && current.Status != config.SUBSCRIPTION_CANCELED && !current.CancelAtPeriodEnd {
+			// an unpaid subscription is settled before it changes plan
+			if current.Status == config.SUBSCRIPTION_PAST_DUE {
+				return apierr.New(http.StatusPaymentRequired, apierr.SUBSCRIPTION_INACTIVE, current.Status)
+			}
+			sub = current
+			if current.PlanId == plan.Id {
+				return nil
+			}
+			err = s.payments.ChangePlan(current.ProviderRef, plan)
+			if err != nil {
+				return &apierr.Error{Status: http.StatusBadGateway, Code: apierr.PAYMENT_PROVIDER, Err: err}
+			}
+			current.PlanId = plan.Id
+			current.Plan = plan
+			return store.SaveSubscription(ctx, current)
+		}
+
+		ref, err := s.payments.CreateSubscription(user, plan)
+		if err != nil {
+			return &apierr.Error{Status: http.StatusBadGateway, Code: apierr.PAYMENT_PROVIDER, Err: err}
+		}
+		now := time.Now().UTC()
+		sub = &models.Subscription{UserId: user.Id, PlanId: plan.Id, ProviderRef: ref, Plan: plan}
+		// the trial is only offered on the first subscription
+		if plan.TrialDays > 0 && current == nil {
+			trialEnd := now.AddDate(0, 0, plan.TrialDays)
+			sub.Status = config.SUBSCRIPTION_TRIALING
+			sub.TrialEnd = &trialEnd
+			sub.PeriodEnd = &trialEnd
+		} else {
+			periodEnd := now.AddDate(0, config.BILLING_PERIOD_MONTHS, 0)
+			sub.Status = config.SUBSCRIPTION_ACTIVE
+			sub.PeriodEnd = &periodEnd
+		}
+		status = http.StatusCreated
+		return store.SaveSubscription(ctx, sub)
+	})
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(status, sub)
+}
+
+// handelCancelSubscription stops the renewals, the subscription keeps giving access until the
+// end of the period already paid, or of the trial.
+func (s *Serve) handelCancelSubscription(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	sub, err := s.db.GetSubscriptionByUser(c.Request.Context(), user.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	if sub.Status == config.SUBSCRIPTION_CANCELED || sub.CancelAtPeriodEnd {
+		apierr.Write(c, apierr.New(http.StatusNotFound, apierr.NOT_FOUND))
+		return
+	}
+	err = s.payments.CancelSubscription(sub.ProviderRef)
+	if err != nil {
+		apierr.Write(c, &apierr.Error{Status: http.StatusBadGateway, Code: apierr.PAYMENT_PROVIDER, Err: err})
+		return
+	}
+	sub.CancelAtPeriodEnd = true
+	err = s.db.SaveSubscription(c.Request.Context(), sub)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "subscription canceled", "subscription": sub})
+}
+
+// * * * BILLING WEBHOOK * * *
+
+func (s *Serve) handelBillingWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	s.writeBillingEvent(c, payload, c.GetHeader(billing.SIGNATURE_HEADER))
+}
+
+func (s *Serve) writeBillingEvent(c *gin.Context, payload []byte, signature string) {
+	body, err := s.ingestBillingEvent(c.Request.Context(), payload, signature)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+func (s *Serve) ingestBillingEvent(ctx context.Context, payload []byte, signature string) (gin.H, error) {
+	event, err := s.payments.VerifyWebhook(payload, signature)
+	if err != nil {
+		return nil, apierr.New(http.StatusUnauthorized, apierr.INVALID_SIGNATURE).WithDetail(err.Error())
+	}
+	// the event is only recorded with the change it makes, a failed change is applied again
+	// when the provider retries
+	var body gin.H
+	err = s.db.WithTx(ctx, func(store db.Storage) error {
+		var err error
+		body, err = applyBillingEvent(ctx, store, event)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func applyBillingEvent(ctx context.Context, store db.Storage, event *billing.Event) (gin.H, error) {
+	sub, err := store.GetSubscriptionByRef(ctx, event.ProviderRef)
+	if err != nil {
+		return nil, err
+	}
+	isNew, err := store.SaveBillingEvent(ctx, event.Id, event.Type, event.ProviderRef)
+	if err != nil {
+		return nil, err
+	}
+	if !isNew {
+		return gin.H{"message": "event already processed"}, nil
+	}
+
+	switch event.Type {
+	case billing.EVENT_PAYMENT_SUCCEEDED:
+		start := time.Now().UTC()
+		if sub.Status == config.SUBSCRIPTION_ACTIVE && sub.PeriodEnd != nil && sub.PeriodEnd.After(start) {
+			start = *sub.PeriodEnd
+		}
+		periodEnd := start.AddDate(0, config.BILLING_PERIOD_MONTHS, 0)
+		sub.Status = config.SUBSCRIPTION_ACTIVE
+		sub.PeriodEnd = &periodEnd
+	case billing.EVENT_PAYMENT_FAILED:
+		sub.Status = config.SUBSCRIPTION_PAST_DUE
+	case billing.EVENT_SUBSCRIPTION_CANCELED:
+		sub.Status = config.SUBSCRIPTION_CANCELED
+	default:
+		return gin.H{"message": "event ignored"}, nil
+	}
+	err = store.SaveSubscription(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{"message": "event processed", "subscription": sub}, nil
+}
+
+// handelSimulateBillingEvent makes the fake provider send a signed event through the webhook ingestion.
+func (s *Serve) handelSimulateBillingEvent(c *gin.Context) {
+	fake, ok := s.payments.(*billing.Fake)
+	if !ok {
+		apierr.Write(c, apierr.New(http.StatusNotFound, apierr.NOT_SIMULATED))
+		return
+	}
+	var body struct {
+		Type        string `json:"type"`
+		ProviderRef string `json:"providerref"`
+	}
+	err := c.ShouldBindJSON(&body)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	payload, signature, err := fake.Emit(body.Type, body.ProviderRef)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	s.writeBillingEvent(c, payload, signature)
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"goflix/apierr"
+	"goflix/catalog"
+	"goflix/config"
+	"goflix/utils"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// * * * CATALOG IMPORT / EXPORT * * *
+
+func (s *Serve) handelImportCatalog(c *gin.Context) {
+	format := s.getCatalogFormat(c)
+	if format == "" {
+		return
+	}
+	var body io.Reader = http.MaxBytesReader(c.Writer, c.Request.Body, config.CATALOG_IMPORT_MAX_MB<<20)
+	if c.ContentType() == "multipart/form-data" {
+		file, err := c.FormFile("file")
+		if err != nil {
+			apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.MISSING_FIELD, "file"))
+			return
+		}
+		if file.Size > config.CATALOG_IMPORT_MAX_MB<<20 {
+			apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.FILE_TOO_LARGE, config.CATALOG_IMPORT_MAX_MB))
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			apierr.Write(c, apierr.BadRequest(err))
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+	dryRun := c.Query("dryrun") == "true"
+	atomic := c.Query("atomic") == "true"
+	report, err := catalog.Import(c.Request.Context(), s.db, format, body, atomic, dryRun)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	s.emitMoviesCreated(report.CreatedIds())
+	if atomic && !dryRun && !report.Committed {
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (s *Serve) handelExportCatalog(c *gin.Context) {
+	format := s.getCatalogFormat(c)
+	if format == "" {
+		return
+	}
+	movies, err := s.db.GetCatalog(c.Request.Context())
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	contentType := "text/csv; charset=utf-8"
+	if format == config.CATALOG_FORMAT_JSONL {
+		contentType = "application/x-ndjson"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="catalog.`+format+`"`)
+	c.Status(http.StatusOK)
+	err = catalog.Write(format, c.Writer, movies)
+	if err != nil {
+		c.Error(err)
+	}
+}
+
+// getCatalogFormat reads the format query parameter, csv by default.
+func (s *Serve) getCatalogFormat(c *gin.Context) string {
+	format := c.DefaultQuery("format", config.CATALOG_FORMAT_CSV)
+	if !utils.Contains(config.CATALOG_FORMATS, format) {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_CHOICE, "format", config.CATALOG_FORMATS))
+		return ""
+	}
+	return format
+}
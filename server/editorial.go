@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"goflix/apierr"
+	"goflix/config"
+	"goflix/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// * * * EDITORIAL * * *
+
+func (s *Serve) handelGetEditorialMovies(c *gin.Context) {
+	if !s.isEditor(c) {
+		return
+	}
+	status := c.DefaultQuery("status", config.STATUS_DRAFT)
+	if _, ok := config.EDITORIAL_TRANSITIONS[status]; !ok {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_CHOICE, "status", config.EDITORIAL_STATUSES))
+		return
+	}
+	movies, err := s.db.GetMoviesByStatus(c.Request.Context(), status)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"movies": movies})
+}
+
+func (s *Serve) handelMovieTransition(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil || !s.isEditor(c) {
+		return
+	}
+	id, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	var body struct {
+		Status    string     `json:"status"`
+		PublishAt *time.Time `json:"publishat"`
+	}
+	err = c.ShouldBindJSON(&body)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	movie, err := s.db.GetMoviesById(c.Request.Context(), id, nil)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	roles, ok := config.EDITORIAL_TRANSITIONS[movie.Status][body.Status]
+	if !ok {
+		apierr.Write(c, apierr.New(http.StatusConflict, apierr.INVALID_TRANSITION, movie.Status, body.Status))
+		return
+	}
+	if !utils.Contains(roles, user.Account) {
+		apierr.Write(c, apierr.New(http.StatusForbidden, apierr.FORBIDDEN_STATUS, user.Account, body.Status))
+		return
+	}
+	var publishAt *time.Time
+	switch body.Status {
+	case config.STATUS_SCHEDULED:
+		if body.PublishAt == nil || !body.PublishAt.After(time.Now()) {
+			apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.PUBLISH_IN_PAST))
+			return
+		}
+		publishAt = body.PublishAt
+	case config.STATUS_PUBLISHED:
+		now := time.Now()
+		publishAt = &now
+	}
+	err = s.db.UpdateMovieStatus(c.Request.Context(), id, movie.Status, body.Status, publishAt)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	movie.Status = body.Status
+	s.notifyNewEpisodes(c, movie)
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("movie %s", body.Status)})
+}
+
+func (s *Serve) isEditor(c *gin.Context) bool {
+	user := s.currentUser(c)
+	if user == nil {
+		return false
+	}
+	if user.Account != config.ROLE_ADMIN && user.Account != config.ROLE_EDITOR {
+		apierr.Write(c, apierr.New(http.StatusForbidden, apierr.EDITOR_ONLY))
+		return false
+	}
+	return true
+}
+
+func (s *Serve) publishScheduled(ctx context.Context) error {
+	movies, err := s.db.PublishScheduled(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(movies) > 0 {
+		log.Printf("%d scheduled titles published", len(movies))
+	}
+	s.notifyNewEpisodes(nil, movies...)
+	return nil
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"goflix/apierr"
+	"goflix/config"
+	"goflix/metadata"
+	"goflix/models"
+	"goflix/notify"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// * * * LIVE EVENTS * * *
+
+// handelEvents streams the live events of the user as Server-Sent Events until the client leaves.
+func (s *Serve) handelEvents(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	sub, err := s.broker.Subscribe(userTopic(user.Id))
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	keepalive := time.NewTicker(config.LIVE_KEEPALIVE)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-keepalive.C:
+			fmt.Fprint(c.Writer, ": keepalive\n\n")
+		case message, ok := <-sub.Messages():
+			if !ok {
+				return
+			}
+			var event models.LiveEvent
+			if err := json.Unmarshal(message, &event); err != nil {
+				log.Printf("live event: %v", err)
+				continue
+			}
+			c.SSEvent(event.Type, string(message))
+		}
+		c.Writer.Flush()
+	}
+}
+
+// handelEventsTicket issues a single-use ticket opening the event stream as GET /events?ticket=.
+func (s *Serve) handelEventsTicket(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	ticket, expires, err := s.tickets.Issue(user)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ticket": ticket, "expires": expires.UTC()})
+}
+
+// publishLive pushes an event to the connected clients of the user, c is the request causing it
+// if any. Clients not connected miss it, failures are only logged.
+func (s *Serve) publishLive(c *gin.Context, userID int, kind string, data any) {
+	event := models.LiveEvent{Type: kind, At: time.Now().UTC(), Data: data}
+	if c != nil {
+		event.Device = c.GetHeader(config.DEVICE_HEADER)
+	}
+	message, err := json.Marshal(event)
+	if err == nil {
+		err = s.broker.Publish(userTopic(userID), message)
+	}
+	if err != nil {
+		log.Printf("publishing %s event: %v", kind, err)
+	}
+}
+
+func (s *Serve) publishListChanged(c *gin.Context, list *models.List, action string, movieID int) {
+	data := gin.H{"listid": list.Id, "action": action}
+	if movieID != 0 {
+		data["movieid"] = movieID
+	}
+	s.publishLive(c, list.UserId, config.LIVE_LIST_CHANGED, data)
+}
+
+func (s *Serve) publishStreamKicked(c *gin.Context, session *models.StreamSession, reason string) {
+	s.publishLive(c, session.UserId, config.LIVE_STREAM_KICKED, gin.H{
+		"sessionid": session.Id, "movieid": session.MovieId, "device": session.Device, "reason": reason,
+	})
+}
+
+// notifyNewEpisodes tells the users having an episode of the series in their list that another
+// one was published, live and through their notifications. Episodes are grouped by their series
+// external id, or by the series title for the ones without, e.g. entered by hand. The titles are
+// published already, so the notifications don't depend on the request, c is nil for the scheduled ones.
+func (s *Serve) notifyNewEpisodes(c *gin.Context, movies ...*models.Movies) {
+	ctx := context.Background()
+	for _, movie := range movies {
+		if movie.Saison == 0 || movie.Status != config.STATUS_PUBLISHED {
+			continue
+		}
+		var fans []int
+		var err error
+		if seriesID, ok := metadata.SeriesId(movie.ExternalId); ok {
+			fans, err = s.db.GetSeriesFans(ctx, seriesID)
+		} else {
+			fans, err = s.db.GetSeriesFansByTitle(ctx, metadata.SeriesTitle(movie.Title))
+		}
+		if err != nil {
+			log.Printf("new episode %d: %v", movie.Id, err)
+			continue
+		}
+		for _, userID := range fans {
+			s.publishLive(c, userID, config.LIVE_NEW_EPISODE, gin.H{
+				"movieid": movie.Id, "title": movie.Title, "saison": movie.Saison, "episode": movie.Episode,
+			})
+			_, err = notify.Notify(ctx, s.db, &models.Notification{UserId: userID, Kind: config.NOTIFY_NEW_EPISODE, MovieId: movie.Id})
+			if err != nil {
+				log.Printf("new episode %d: %v", movie.Id, err)
+			}
+		}
+	}
+}
+
+func userTopic(userID int) string {
+	return fmt.Sprintf("user:%d", userID)
+}
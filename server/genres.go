@@ -0,0 +1,140 @@
+package server
+
+import (
+	"goflix/apierr"
+	"goflix/models"
+	"goflix/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// * * * GENRES * * *
+
+func (s *Serve) handelGetGenres(c *gin.Context) {
+	genres, err := s.db.GetGenres(c.Request.Context(), s.locales(c))
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"genres": genres})
+}
+
+func (s *Serve) handelGetGenreTitles(c *gin.Context) {
+	page, limit, err := s.getPage(c)
+	if err != nil {
+		return
+	}
+	genre, err := s.db.GetGenre(c.Request.Context(), c.Param("slug"), s.locales(c))
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	filter, err := s.catalogFilter(c)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	titles, total, err := s.db.GetTitlesByGenre(c.Request.Context(), genre.Slug, filter, (page-1)*limit, limit)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	err = s.translate(c, titles...)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	genre.Names = nil
+	c.JSON(http.StatusOK, gin.H{"genre": genre, "page": page, "limit": limit, "total": total, "titles": titles})
+}
+
+func (s *Serve) handelAddGenre(c *gin.Context) {
+	if genre := s.decodeGenreJSON(c); genre != nil {
+		err := s.db.SaveGenre(c.Request.Context(), genre)
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, genre)
+	}
+}
+
+func (s *Serve) handelUpdateGenre(c *gin.Context) {
+	genre := s.decodeGenreJSON(c)
+	if genre == nil {
+		return
+	}
+	current, err := s.db.GetGenre(c.Request.Context(), c.Param("slug"), nil)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	if genre.Slug == "" {
+		genre.Slug = current.Slug
+	}
+	if genre.Name == "" {
+		genre.Name = current.Name
+	}
+	err = s.db.UpdateGenre(c.Request.Context(), current.Slug, genre)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "genre updated"})
+}
+
+func (s *Serve) handelMergeGenre(c *gin.Context) {
+	var body struct {
+		Into string `json:"into"`
+	}
+	err := c.ShouldBindJSON(&body)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	err = s.db.MergeGenres(c.Request.Context(), c.Param("slug"), body.Into)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "genres merged"})
+}
+
+func (s *Serve) handelSetMovieGenres(c *gin.Context) {
+	id, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	if _, err := s.db.GetMoviesById(c.Request.Context(), id, nil); err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	var body struct {
+		Genres []string `json:"genres"`
+	}
+	err = c.ShouldBindJSON(&body)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	err = s.db.SetMovieGenres(c.Request.Context(), id, body.Genres)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "genres updated"})
+}
+
+func (s *Serve) decodeGenreJSON(c *gin.Context) *models.Genre {
+	var genre models.Genre
+	err := c.ShouldBindJSON(&genre)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return nil
+	}
+	if genre.Slug != "" {
+		genre.Slug = utils.Slugify(genre.Slug)
+	}
+	return &genre
+}
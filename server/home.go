@@ -0,0 +1,272 @@
+package server
+
+import (
+	"goflix/activity"
+	"goflix/apierr"
+	"goflix/config"
+	"goflix/models"
+	"goflix/recommend"
+	"goflix/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// * * * HOME * * *
+
+func (s *Serve) handelGetHome(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	filter, err := s.catalogFilter(c)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	rows, err := s.db.GetHomeRows(c.Request.Context(), true)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	pages := []*models.HomeRowPage{}
+	for _, row := range rows {
+		page, err := s.homeRowPage(c, user, filter, row, 1, row.Size)
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		// empty rows are left out, e.g. continue watching for a new user
+		if len(page.Movies) > 0 {
+			pages = append(pages, page)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"rows": pages})
+}
+
+func (s *Serve) handelGetHomeRow(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	row := s.getHomeRow(c)
+	if row == nil {
+		return
+	}
+	if !row.Enabled {
+		apierr.Write(c, apierr.New(http.StatusNotFound, apierr.NOT_FOUND))
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "page"))
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(row.Size)))
+	if err != nil || limit < 1 || limit > config.PAGE_MAX_LIMIT {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "limit"))
+		return
+	}
+	filter, err := s.catalogFilter(c)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	rowPage, err := s.homeRowPage(c, user, filter, row, page, limit)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rowPage)
+}
+
+// homeRowPage fetches one title more than the page to tell whether another page follows.
+func (s *Serve) homeRowPage(c *gin.Context, user *models.User, filter *models.CatalogFilter, row *models.HomeRow, page int, limit int) (*models.HomeRowPage, error) {
+	offset := (page - 1) * limit
+	movies, err := s.homeRowTitles(c, user, filter, row, offset, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	rowPage := models.HomeRowPage{HomeRow: row, Page: page, Movies: movies}
+	if len(movies) > limit {
+		rowPage.HasMore = true
+		rowPage.Movies = movies[:limit]
+	}
+	if rowPage.Movies == nil {
+		rowPage.Movies = []*models.Movies{}
+	}
+	err = s.translate(c, rowPage.Movies...)
+	if err != nil {
+		return nil, err
+	}
+	return &rowPage, nil
+}
+
+func (s *Serve) homeRowTitles(c *gin.Context, user *models.User, filter *models.CatalogFilter, row *models.HomeRow, offset int, limit int) ([]*models.Movies, error) {
+	switch row.Kind {
+	case config.ROW_CONTINUE_WATCHING:
+		return s.db.GetRecentlyWatched(c.Request.Context(), user.Id, filter, offset, limit)
+	case config.ROW_MY_LIST:
+		list, err := s.db.GetDefaultList(c.Request.Context(), user.Id)
+		if err != nil {
+			return nil, err
+		}
+		ids, err := s.db.GetListMovieIds(c.Request.Context(), list.Id)
+		if err != nil {
+			return nil, err
+		}
+		movies, err := s.db.GetMoviesByIds(c.Request.Context(), ids, filter)
+		return pageOf(movies, offset, limit), err
+	case config.ROW_RECOMMENDED:
+		recommendations, err := recommend.ForUser(c.Request.Context(), s.db, user.Id, filter, offset+limit)
+		if err != nil {
+			return nil, err
+		}
+		movies := make([]*models.Movies, len(recommendations))
+		for i, recommendation := range recommendations {
+			movies[i] = recommendation.Movie
+		}
+		return pageOf(movies, offset, limit), nil
+	case config.ROW_TRENDING, config.ROW_TOP10:
+		size := offset + limit
+		if row.Kind == config.ROW_TOP10 && size > config.TOP10_SIZE {
+			size = config.TOP10_SIZE
+		}
+		titles, err := activity.Board(c.Request.Context(), s.db, row.Kind, s.viewerRegion(c), filter, size)
+		if err != nil {
+			return nil, err
+		}
+		movies := make([]*models.Movies, len(titles))
+		for i, title := range titles {
+			movies[i] = title.Movie
+		}
+		return pageOf(movies, offset, limit), nil
+	case config.ROW_NEW_RELEASES:
+		return s.db.GetNewReleases(c.Request.Context(), filter, offset, limit)
+	case config.ROW_GENRE:
+		movies, _, err := s.db.GetTitlesByGenre(c.Request.Context(), row.Genre, filter, offset, limit)
+		return movies, err
+	}
+	return nil, nil
+}
+
+func pageOf(movies []*models.Movies, offset int, limit int) []*models.Movies {
+	if offset >= len(movies) {
+		return nil
+	}
+	movies = movies[offset:]
+	if len(movies) > limit {
+		movies = movies[:limit]
+	}
+	return movies
+}
+
+func (s *Serve) handelGetHomeRows(c *gin.Context) {
+	rows, err := s.db.GetHomeRows(c.Request.Context(), false)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rows": rows})
+}
+
+func (s *Serve) handelAddHomeRow(c *gin.Context) {
+	rows, err := s.db.GetHomeRows(c.Request.Context(), false)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	row := models.HomeRow{Position: len(rows) + 1, Size: config.HOME_ROW_DEFAULT_SIZE, Enabled: true}
+	if !s.decodeHomeRowJSON(c, &row) {
+		return
+	}
+	row.Id = 0
+	err = s.db.SaveHomeRow(c.Request.Context(), &row)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, row)
+}
+
+func (s *Serve) handelUpdateHomeRow(c *gin.Context) {
+	row := s.getHomeRow(c)
+	if row == nil {
+		return
+	}
+	id := row.Id
+	if !s.decodeHomeRowJSON(c, row) {
+		return
+	}
+	row.Id = id
+	err := s.db.SaveHomeRow(c.Request.Context(), row)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, row)
+}
+
+func (s *Serve) handelDeleteHomeRow(c *gin.Context) {
+	row := s.getHomeRow(c)
+	if row == nil {
+		return
+	}
+	err := s.db.DeleteHomeRow(c.Request.Context(), row.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "home row deleted"})
+}
+
+// decodeHomeRowJSON reads the body over row, fields left out keep their value.
+func (s *Serve) decodeHomeRowJSON(c *gin.Context, row *models.HomeRow) bool {
+	err := c.ShouldBindJSON(row)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return false
+	}
+	row.Title = strings.TrimSpace(row.Title)
+	if row.Title == "" {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.MISSING_FIELD, "title"))
+		return false
+	}
+	if !utils.Contains(config.ROW_KINDS, row.Kind) {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_CHOICE, "kind", config.ROW_KINDS))
+		return false
+	}
+	if row.Size < 1 || row.Size > config.PAGE_MAX_LIMIT {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "size"))
+		return false
+	}
+	if row.Kind != config.ROW_GENRE {
+		row.Genre = ""
+		return true
+	}
+	if row.Genre == "" {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.MISSING_FIELD, "genre"))
+		return false
+	}
+	if _, err := s.db.GetGenre(c.Request.Context(), row.Genre, nil); err != nil {
+		apierr.Write(c, err)
+		return false
+	}
+	return true
+}
+
+func (s *Serve) getHomeRow(c *gin.Context) *models.HomeRow {
+	id, err := strconv.Atoi(c.Param("rowID"))
+	if err != nil {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "rowID"))
+		return nil
+	}
+	row, err := s.db.GetHomeRow(c.Request.Context(), id)
+	if err != nil {
+		apierr.Write(c, err)
+		return nil
+	}
+	return row
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"goflix/config"
+	"log"
+	"time"
+)
+
+// every runs job in the background at each interval until ctx is done, a running job gets ctx too.
+func (s *Serve) every(ctx context.Context, interval time.Duration, name string, job func(ctx context.Context) error) {
+	s.jobs.Add(1)
+	go func() {
+		defer s.jobs.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := job(ctx); err != nil && ctx.Err() == nil {
+					log.Printf("job %s: %v", name, err)
+				}
+			}
+		}
+	}()
+}
+
+func (s *Serve) startJobs(ctx context.Context) {
+	s.every(ctx, config.PUBLISH_SCHEDULER_INTERVAL, "publish scheduled", s.publishScheduled)
+	s.every(ctx, config.LIBRARY_SCAN_INTERVAL, "library scan", s.scanLibrary)
+	s.every(ctx, config.ACTIVITY_AGGREGATE_INTERVAL, "activity", s.aggregateActivity)
+	s.every(ctx, config.RECOMMENDATION_REBUILD_INTERVAL, "recommendations", s.rebuildRecommendations)
+	s.every(ctx, config.WEBHOOK_DELIVERY_INTERVAL, "webhooks", s.deliverWebhooks)
+	s.every(ctx, config.NOTIFY_LEAVING_SOON_INTERVAL, "leaving soon", s.notifyLeavingSoon)
+	s.every(ctx, config.NOTIFY_DIGEST_INTERVAL, "notification digest", s.sendDigests)
+	// the model is not worth waiting hours for after a restart
+	s.jobs.Add(1)
+	go func() {
+		defer s.jobs.Done()
+		if err := s.rebuildRecommendations(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("job recommendations: %v", err)
+		}
+	}()
+}
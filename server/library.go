@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"goflix/apierr"
+	"goflix/config"
+	"goflix/library"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// * * * LIBRARY * * *
+
+func (s *Serve) handelScanLibrary(c *gin.Context) {
+	report, err := library.Scan(c.Request.Context(), s.db, config.LIBRARY_DIRS, c.Query("full") == "true")
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	s.emitMoviesCreated(report.CreatedIds())
+	c.JSON(http.StatusOK, report)
+}
+
+func (s *Serve) handelGetMediaFiles(c *gin.Context) {
+	movieID, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	files, err := s.db.GetMediaFilesByMovie(c.Request.Context(), movieID)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"files": files})
+}
+
+func (s *Serve) scanLibrary(ctx context.Context) error {
+	report, err := library.Scan(ctx, s.db, config.LIBRARY_DIRS, false)
+	if errors.Is(err, library.ErrScanRunning) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	s.emitMoviesCreated(report.CreatedIds())
+	if report.Created+report.Updated+report.Failed+report.Removed > 0 {
+		log.Printf("library scanned: %d created, %d updated, %d failed, %d removed",
+			report.Created, report.Updated, report.Failed, report.Removed)
+	}
+	return nil
+}
@@ -0,0 +1,345 @@
+package server
+
+import (
+	"goflix/apierr"
+	"goflix/config"
+	"goflix/db"
+	"goflix/models"
+	"goflix/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// * * * LISTS * * *
+
+func (s *Serve) handelGetLists(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	// the default list shows up even before its first title
+	_, err := s.db.GetDefaultList(c.Request.Context(), user.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	lists, err := s.db.GetLists(c.Request.Context(), user.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"lists": lists})
+}
+
+func (s *Serve) handelAddList(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	list := models.List{UserId: user.Id, Visibility: config.LIST_PRIVATE}
+	if !s.decodeListJSON(c, &list) {
+		return
+	}
+	// counting and creating in one unit of work keeps concurrent requests under the limit
+	err := s.db.WithTx(c.Request.Context(), func(store db.Storage) error {
+		_, err := store.GetDefaultList(c.Request.Context(), user.Id)
+		if err != nil {
+			return err
+		}
+		lists, err := store.GetLists(c.Request.Context(), user.Id)
+		if err != nil {
+			return err
+		}
+		if len(lists) >= config.LIST_MAX_PER_USER {
+			return apierr.New(http.StatusConflict, apierr.TOO_MANY_LISTS, config.LIST_MAX_PER_USER)
+		}
+		return store.SaveList(c.Request.Context(), &list)
+	})
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	s.publishListChanged(c, &list, config.LIST_CREATED, 0)
+	c.JSON(http.StatusOK, list)
+}
+
+func (s *Serve) handelGetList(c *gin.Context) {
+	list := s.getOwnList(c)
+	if list == nil {
+		return
+	}
+	s.writeListPage(c, list)
+}
+
+func (s *Serve) handelUpdateList(c *gin.Context) {
+	list := s.getOwnList(c)
+	if list == nil {
+		return
+	}
+	if !s.decodeListJSON(c, list) {
+		return
+	}
+	err := s.db.SaveList(c.Request.Context(), list)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	s.publishListChanged(c, list, config.LIST_UPDATED, 0)
+	c.JSON(http.StatusOK, list)
+}
+
+func (s *Serve) handelDeleteList(c *gin.Context) {
+	list := s.getOwnList(c)
+	if list == nil {
+		return
+	}
+	if list.IsDefault {
+		apierr.Write(c, apierr.New(http.StatusConflict, apierr.DEFAULT_LIST))
+		return
+	}
+	err := s.db.DeleteList(c.Request.Context(), list.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	s.publishListChanged(c, list, config.LIST_DELETED, 0)
+	c.JSON(http.StatusOK, gin.H{"message": "list deleted"})
+}
+
+func (s *Serve) handelAddListItem(c *gin.Context) {
+	list := s.getOwnList(c)
+	if list == nil {
+		return
+	}
+	var item struct {
+		MovieId  int `json:"movieid"`
+		Position int `json:"position"`
+	}
+	err := c.ShouldBindJSON(&item)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	filter, err := s.catalogFilter(c)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	_, err = s.db.GetMoviesById(c.Request.Context(), item.MovieId, filter)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	added, err := s.db.AddListItem(c.Request.Context(), list.Id, item.MovieId, item.Position)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	if added && list.IsDefault {
+		s.recordEvent(c, config.EVENT_FAVORITE, list.UserId, item.MovieId)
+	}
+	if added {
+		s.publishListChanged(c, list, config.LIST_ITEM_ADDED, item.MovieId)
+	}
+	s.writeListPage(c, list)
+}
+
+func (s *Serve) handelReorderList(c *gin.Context) {
+	list := s.getOwnList(c)
+	if list == nil {
+		return
+	}
+	var order struct {
+		MovieIds []int `json:"movieids"`
+	}
+	err := c.ShouldBindJSON(&order)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	err = s.db.ReorderList(c.Request.Context(), list.Id, order.MovieIds)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	s.publishListChanged(c, list, config.LIST_REORDERED, 0)
+	s.writeListPage(c, list)
+}
+
+func (s *Serve) handelRemoveListItem(c *gin.Context) {
+	list := s.getOwnList(c)
+	if list == nil {
+		return
+	}
+	movieID, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	err = s.db.RemoveListItem(c.Request.Context(), list.Id, movieID)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	s.publishListChanged(c, list, config.LIST_ITEM_REMOVED, movieID)
+	s.writeListPage(c, list)
+}
+
+// handelGetPublicList shows a public list of any user, link lists are only reachable by their token.
+func (s *Serve) handelGetPublicList(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	list := s.getList(c)
+	if list == nil {
+		return
+	}
+	if list.UserId != user.Id && list.Visibility != config.LIST_PUBLIC {
+		apierr.Write(c, apierr.New(http.StatusNotFound, apierr.NOT_FOUND))
+		return
+	}
+	s.writeListPage(c, list)
+}
+
+func (s *Serve) handelGetSharedList(c *gin.Context) {
+	list, err := s.db.GetListByToken(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	if list.Visibility != config.LIST_LINK {
+		apierr.Write(c, apierr.New(http.StatusNotFound, apierr.NOT_FOUND))
+		return
+	}
+	s.writeListPage(c, list)
+}
+
+func (s *Serve) handelGetUserLists(c *gin.Context) {
+	userID, err := s.getUserID(c)
+	if err != nil {
+		return
+	}
+	lists, err := s.db.GetLists(c.Request.Context(), userID)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	public := []*models.List{}
+	for _, list := range lists {
+		if list.Visibility == config.LIST_PUBLIC {
+			public = append(public, list)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"lists": public})
+}
+
+// writeListPage answers with the list and the titles the viewer may see, the share token is
+// only shown to the owner.
+func (s *Serve) writeListPage(c *gin.Context, list *models.List) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	filter, err := s.catalogFilter(c)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	list, err = s.db.GetList(c.Request.Context(), list.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	ids, err := s.db.GetListMovieIds(c.Request.Context(), list.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	movies, err := s.db.GetMoviesByIds(c.Request.Context(), ids, filter)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	if movies == nil {
+		movies = []*models.Movies{}
+	}
+	err = s.translate(c, movies...)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	if list.UserId != user.Id {
+		list.ShareToken = ""
+	}
+	c.JSON(http.StatusOK, models.ListPage{List: list, Movies: movies})
+}
+
+// decodeListJSON reads the name and visibility of the list, fields left out keep their value.
+// Link lists get a share token, leaving link visibility revokes it.
+func (s *Serve) decodeListJSON(c *gin.Context, list *models.List) bool {
+	input := models.List{Name: list.Name, Visibility: list.Visibility}
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return false
+	}
+	list.Name = strings.TrimSpace(input.Name)
+	list.Visibility = input.Visibility
+	if list.Name == "" {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.MISSING_FIELD, "name"))
+		return false
+	}
+	if utf8.RuneCountInString(list.Name) > config.LIST_NAME_MAX_LENGTH {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "name"))
+		return false
+	}
+	if !utils.Contains(config.LIST_VISIBILITIES, list.Visibility) {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_CHOICE, "visibility", config.LIST_VISIBILITIES))
+		return false
+	}
+	if list.Visibility != config.LIST_LINK {
+		list.ShareToken = ""
+	} else if list.ShareToken == "" {
+		list.ShareToken, err = utils.RandomToken(config.LIST_SHARE_TOKEN_SIZE)
+		if err != nil {
+			apierr.Write(c, err)
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Serve) getList(c *gin.Context) *models.List {
+	id, err := strconv.Atoi(c.Param("listID"))
+	if err != nil {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "listID"))
+		return nil
+	}
+	list, err := s.db.GetList(c.Request.Context(), id)
+	if err != nil {
+		apierr.Write(c, err)
+		return nil
+	}
+	return list
+}
+
+// getOwnList reads the list of the url, lists of other users are reported as not found.
+func (s *Serve) getOwnList(c *gin.Context) *models.List {
+	user := s.currentUser(c)
+	if user == nil {
+		return nil
+	}
+	list := s.getList(c)
+	if list == nil {
+		return nil
+	}
+	if list.UserId != user.Id {
+		apierr.Write(c, apierr.New(http.StatusNotFound, apierr.NOT_FOUND))
+		return nil
+	}
+	return list
+}
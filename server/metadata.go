@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"goflix/apierr"
+	"goflix/config"
+	"goflix/metadata"
+	"goflix/models"
+	"goflix/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// * * * METADATA * * *
+
+func (s *Serve) handelSearchMetadata(c *gin.Context) {
+	if !s.metadataEnabled(c) {
+		return
+	}
+	kind := c.DefaultQuery("kind", metadata.KIND_MOVIE)
+	if !utils.Contains(metadata.KINDS, kind) {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_CHOICE, "kind", metadata.KINDS))
+		return
+	}
+	query := strings.TrimSpace(c.Query("query"))
+	if query == "" {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.MISSING_FIELD, "query"))
+		return
+	}
+	results, err := s.metadata.Search(kind, query)
+	if err != nil {
+		apierr.Write(c, metadataError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+type enrichRequest struct {
+	ProviderId string `json:"providerid"`
+	Kind       string `json:"kind"`
+	Overwrite  bool   `json:"overwrite"`
+}
+
+func (s *Serve) handelEnrichMovie(c *gin.Context) {
+	if !s.metadataEnabled(c) {
+		return
+	}
+	movieID, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	movie, err := s.db.GetMoviesById(c.Request.Context(), movieID, nil)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	var req enrichRequest
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	// titles already enriched can be refreshed without repeating the provider id
+	if req.ProviderId == "" {
+		if kind, id, ok := metadata.ParseExternalId(movie.ExternalId); ok {
+			req.Kind, req.ProviderId = kind, id
+		}
+	}
+	if req.ProviderId == "" {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.MISSING_FIELD, "providerid"))
+		return
+	}
+	if req.Kind == "" {
+		req.Kind = metadata.KIND_MOVIE
+		if movie.Saison > 0 {
+			req.Kind = metadata.KIND_TV
+		}
+	}
+	if !utils.Contains(metadata.KINDS, req.Kind) {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_CHOICE, "kind", metadata.KINDS))
+		return
+	}
+
+	title, err := s.metadata.Fetch(req.Kind, req.ProviderId)
+	if err != nil {
+		apierr.Write(c, metadataError(err))
+		return
+	}
+	var episode *metadata.Episode
+	if req.Kind == metadata.KIND_TV && movie.Saison > 0 && movie.Episode > 0 {
+		episodes, err := s.metadata.Episodes(req.ProviderId, movie.Saison)
+		if err != nil && !errors.Is(err, metadata.ErrNotFound) {
+			apierr.Write(c, metadataError(err))
+			return
+		}
+		for _, e := range episodes {
+			if e.Episode == movie.Episode {
+				episode = e
+			}
+		}
+	}
+
+	metadata.Apply(movie, title, episode, req.Overwrite)
+	err = s.db.UpdateMovie(c.Request.Context(), movie)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	err = s.addCrewCredits(c.Request.Context(), movie, title)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	movie, err = s.db.GetMoviesById(c.Request.Context(), movieID, nil)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	movie.Credits, err = s.db.GetCreditsByMovie(c.Request.Context(), movieID)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, movie)
+}
+
+// addCrewCredits credits the provider directors and writers the title doesn't credit yet,
+// actors follow the actors list saved with the movie.
+func (s *Serve) addCrewCredits(ctx context.Context, movie *models.Movies, title *metadata.Title) error {
+	credits, err := s.db.GetCreditsByMovie(ctx, movie.Id)
+	if err != nil {
+		return err
+	}
+	credited := map[string]bool{}
+	for _, credit := range credits {
+		credited[credit.Role+":"+credit.Name] = true
+	}
+	crew := map[string][]string{config.CREDIT_DIRECTOR: title.Directors, config.CREDIT_WRITER: title.Writers}
+	for _, role := range []string{config.CREDIT_DIRECTOR, config.CREDIT_WRITER} {
+		for _, name := range crew[role] {
+			if credited[role+":"+name] {
+				continue
+			}
+			person := models.Person{Name: name}
+			err = s.db.SavePerson(ctx, &person)
+			if err != nil {
+				return err
+			}
+			err = s.db.AddCredit(ctx, &models.Credit{PersonId: person.Id, MovieId: movie.Id, Role: role})
+			if err != nil {
+				return err
+			}
+			credited[role+":"+name] = true
+		}
+	}
+	return nil
+}
+
+// metadataEnabled answers 503 when no metadata provider is configured.
+func (s *Serve) metadataEnabled(c *gin.Context) bool {
+	if s.metadata == nil {
+		apierr.Write(c, apierr.New(http.StatusServiceUnavailable, apierr.METADATA_DISABLED, config.TMDB_API_KEY_ENV))
+		return false
+	}
+	return true
+}
+
+func metadataError(err error) error {
+	if errors.Is(err, metadata.ErrNotFound) {
+		return apierr.New(http.StatusNotFound, apierr.METADATA_NOT_FOUND)
+	}
+	return &apierr.Error{Status: http.StatusBadGateway, Code: apierr.METADATA_PROVIDER, Err: err}
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"goflix/apierr"
+	"goflix/config"
+	"goflix/mailer"
+	"goflix/models"
+	"goflix/notify"
+	"goflix/utils"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newMailer sends the digests through SMTP when a relay is configured, to the log otherwise.
+func newMailer() mailer.Mailer {
+	if addr := os.Getenv(config.SMTP_ADDR_ENV); addr != "" {
+		return mailer.NewSMTP(addr, config.MAIL_FROM, os.Getenv(config.SMTP_USER_ENV), os.Getenv(config.SMTP_PASSWORD_ENV))
+	}
+	log.Printf("%s not set, emails are written to the log", config.SMTP_ADDR_ENV)
+	return mailer.NewLog()
+}
+
+// * * * NOTIFICATIONS * * *
+
+func (s *Serve) handelGetNotifications(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	page, limit, err := s.getPage(c)
+	if err != nil {
+		return
+	}
+	notifications, total, unread, err := s.db.GetNotifications(c.Request.Context(), user.Id, c.Query("unread") == "true", (page-1)*limit, limit)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"page": page, "limit": limit, "total": total, "unread": unread, "notifications": notifications})
+}
+
+// handelReadNotification marks a notification as read, or as unread again with DELETE.
+func (s *Serve) handelReadNotification(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("notificationID"))
+	if err != nil {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "notificationID"))
+		return
+	}
+	read := c.Request.Method != http.MethodDelete
+	err = s.db.MarkNotificationRead(c.Request.Context(), user.Id, id, read)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "read": read})
+}
+
+func (s *Serve) handelReadAllNotifications(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	marked, err := s.db.MarkAllNotificationsRead(c.Request.Context(), user.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
+
+func (s *Serve) handelGetNotificationPreferences(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	prefs, err := s.db.GetNotificationPreferences(c.Request.Context(), user.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// handelSaveNotificationPreferences turns channels on or off per kind, those left out are kept.
+func (s *Serve) handelSaveNotificationPreferences(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	var prefs models.NotificationPreferences
+	err := c.ShouldBindJSON(&prefs)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	for kind, channels := range prefs {
+		if !utils.Contains(config.NOTIFY_KINDS, kind) {
+			apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_CHOICE, "kind", config.NOTIFY_KINDS))
+			return
+		}
+		for channel := range channels {
+			if !utils.Contains(config.NOTIFY_CHANNELS, channel) {
+				apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_CHOICE, "channel", config.NOTIFY_CHANNELS))
+				return
+			}
+		}
+	}
+	err = s.db.SaveNotificationPreferences(c.Request.Context(), user.Id, prefs)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	s.handelGetNotificationPreferences(c)
+}
+
+func (s *Serve) handelSendDigests(c *gin.Context) {
+	sent, err := notify.Digest(c.Request.Context(), s.db, s.mailer, time.Now())
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sent": sent})
+}
+
+func (s *Serve) handelNotifyLeavingSoon(c *gin.Context) {
+	notified, err := notify.LeavingSoon(c.Request.Context(), s.db, time.Now())
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"notified": notified})
+}
+
+func (s *Serve) sendDigests(ctx context.Context) error {
+	sent, err := notify.Digest(ctx, s.db, s.mailer, time.Now())
+	if sent > 0 {
+		log.Printf("%d notification digests sent", sent)
+	}
+	return err
+}
+
+func (s *Serve) notifyLeavingSoon(ctx context.Context) error {
+	_, err := notify.LeavingSoon(ctx, s.db, time.Now())
+	return err
+}
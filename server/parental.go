@@ -0,0 +1,165 @@
+package server
+
+import (
+	"goflix/apierr"
+	"goflix/config"
+	"goflix/middleware"
+	"goflix/models"
+	"goflix/utils"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// * * * PARENTAL CONTROLS * * *
+
+func (s *Serve) handelGetMaturityScales(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"scales": config.MATURITY_SCALES})
+}
+
+func (s *Serve) handelGetParentalControl(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	control, err := s.db.GetParentalControl(c.Request.Context(), user.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, parentalResponse(control))
+}
+
+func (s *Serve) handelUpdateParentalControl(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	var body struct {
+		// MaxMaturity is kept when absent, an empty one lifts the restriction
+		MaxMaturity *string `json:"maxmaturity"`
+		Pin         string  `json:"pin"`
+		NewPin      string  `json:"newpin"`
+	}
+	err := c.ShouldBindJSON(&body)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	control, err := s.db.GetParentalControl(c.Request.Context(), user.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	if control.Pin != "" {
+		lockedUntil, err := s.db.TakePinAttempt(c.Request.Context(), user.Id)
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		if lockedUntil != nil {
+			wait := time.Until(*lockedUntil)
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			apierr.Write(c, apierr.New(http.StatusTooManyRequests, apierr.PIN_LOCKED, int(math.Ceil(wait.Minutes()))))
+			return
+		}
+		err = utils.CompareHashAndPassword([]byte(body.Pin), []byte(control.Pin))
+		if err != nil {
+			apierr.Write(c, apierr.New(http.StatusForbidden, apierr.WRONG_PIN))
+			return
+		}
+		err = s.db.ResetPinAttempts(c.Request.Context(), user.Id)
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+	}
+
+	if body.MaxMaturity != nil && *body.MaxMaturity == "" {
+		control.MaxLevel = config.MATURITY_UNRATED_LEVEL
+	} else if body.MaxMaturity != nil {
+		level, ok := config.MaturityLevel(config.MATURITY_DEFAULT_REGION, *body.MaxMaturity)
+		if !ok {
+			apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.UNKNOWN_MATURITY))
+			return
+		}
+		control.MaxLevel = level
+	}
+	if body.NewPin != "" {
+		if len(body.NewPin) < config.PIN_MIN_DIGITS {
+			apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.PIN_TOO_SHORT, config.PIN_MIN_DIGITS))
+			return
+		}
+		if strings.Trim(body.NewPin, "0123456789") != "" {
+			apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.PIN_NOT_DIGITS))
+			return
+		}
+		hash, err := utils.HashPasswd([]byte(body.NewPin))
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		control.Pin = string(hash)
+	}
+	err = s.db.SaveParentalControl(c.Request.Context(), control)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, parentalResponse(control))
+}
+
+func (s *Serve) handelUpdateMaturity(c *gin.Context) {
+	id, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	var body struct {
+		Maturity string `json:"maturity"`
+	}
+	err = c.ShouldBindJSON(&body)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	level, ok := config.TitleMaturityLevel(body.Maturity)
+	if !ok {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.UNKNOWN_MATURITY))
+		return
+	}
+	err = s.db.UpdateMaturity(c.Request.Context(), id, body.Maturity, level)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "maturity updated"})
+}
+
+// catalogFilter restricts the catalog to the published titles the current user may see in their region.
+// Admins are not capped by parental controls, they find the drafts and scheduled titles on the editorial routes.
+func (s *Serve) catalogFilter(c *gin.Context) (*models.CatalogFilter, error) {
+	filter := &models.CatalogFilter{MaxMaturity: config.MATURITY_UNRATED_LEVEL, Region: s.viewerRegion(c)}
+	user, ok := middleware.CurrentUser(c)
+	if !ok || user.Account == "admin" {
+		return filter, nil
+	}
+	control, err := s.db.GetParentalControl(c.Request.Context(), user.Id)
+	if err != nil {
+		return nil, err
+	}
+	filter.MaxMaturity = control.MaxLevel
+	return filter, nil
+}
+
+func parentalResponse(control *models.ParentalControl) gin.H {
+	return gin.H{
+		"userid":      control.UserId,
+		"maxlevel":    control.MaxLevel,
+		"maxmaturity": config.MaturityRating(config.MATURITY_DEFAULT_REGION, control.MaxLevel),
+		"pinset":      control.Pin != "",
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"goflix/apierr"
+	"goflix/config"
+	"goflix/models"
+	"goflix/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// * * * PEOPLE * * *
+
+func (s *Serve) handelGetPerson(c *gin.Context) {
+	if id, err := s.getPersonID(c); err == nil {
+		person, err := s.db.GetPerson(c.Request.Context(), id)
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, person)
+	}
+}
+
+func (s *Serve) handelGetPersonCredits(c *gin.Context) {
+	id, err := s.getPersonID(c)
+	if err != nil {
+		return
+	}
+	if _, err := s.db.GetPerson(c.Request.Context(), id); err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	filter, err := s.catalogFilter(c)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	credits, err := s.db.GetCreditsByPerson(c.Request.Context(), id, filter)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"credits": credits})
+}
+
+func (s *Serve) handelAddCredit(c *gin.Context) {
+	movieID, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	movie, err := s.db.GetMoviesById(c.Request.Context(), movieID, nil)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	var credit models.Credit
+	err = c.ShouldBindJSON(&credit)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	if !utils.Contains(config.CREDIT_ROLES, credit.Role) {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_CHOICE, "role", config.CREDIT_ROLES))
+		return
+	}
+	if credit.PersonId == 0 {
+		person := models.Person{Name: credit.Name}
+		err = s.db.SavePerson(c.Request.Context(), &person)
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		credit.PersonId = person.Id
+	}
+	person, err := s.db.GetPerson(c.Request.Context(), credit.PersonId)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	credit.Name = person.Name
+	credit.MovieId = movieID
+	credit.Title = movie.Title
+	err = s.db.AddCredit(c.Request.Context(), &credit)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, credit)
+}
+
+func (s *Serve) handelDeleteCredit(c *gin.Context) {
+	movieID, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("creditID"))
+	if err != nil {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "creditID"))
+		return
+	}
+	err = s.db.DeleteCredit(c.Request.Context(), movieID, id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "credit deleted"})
+}
+
+func (s *Serve) getPersonID(c *gin.Context) (int, error) {
+	id, err := strconv.Atoi(c.Param("personID"))
+	if err != nil {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "personID"))
+		return 0, err
+	}
+	return id, nil
+}
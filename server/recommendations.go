@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"goflix/apierr"
+	"goflix/config"
+	"goflix/models"
+	"goflix/recommend"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// * * * RECOMMENDATIONS * * *
+
+func (s *Serve) handelGetRecommendations(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(config.RECOMMENDATION_LIMIT)))
+	if err != nil || limit < 1 || limit > config.PAGE_MAX_LIMIT {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "limit"))
+		return
+	}
+	filter, err := s.catalogFilter(c)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	recommendations, err := recommend.ForUser(c.Request.Context(), s.db, user.Id, filter, limit)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	movies := make([]*models.Movies, len(recommendations))
+	for i, recommendation := range recommendations {
+		movies[i] = recommendation.Movie
+	}
+	err = s.translate(c, movies...)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recommendations": recommendations})
+}
+
+func (s *Serve) handelGetSimilar(c *gin.Context) {
+	movieID, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	filter, err := s.catalogFilter(c)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	// titles hidden from the viewer have no similar titles either
+	_, err = s.db.GetMoviesById(c.Request.Context(), movieID, filter)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(config.SIMILAR_LIMIT)))
+	if err != nil || limit < 1 || limit > config.SIMILAR_CACHE_SIZE {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "limit"))
+		return
+	}
+	movies, err := s.similar.Get(c.Request.Context(), s.db, movieID, filter, limit)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	err = s.translate(c, movies...)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"similar": movies})
+}
+
+func (s *Serve) handelRebuildRecommendations(c *gin.Context) {
+	err := s.rebuildRecommendations(c.Request.Context())
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "recommendations rebuilt"})
+}
+
+func (s *Serve) rebuildRecommendations(ctx context.Context) error {
+	start := time.Now()
+	err := recommend.Rebuild(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	log.Printf("recommendations rebuilt in %s", time.Since(start).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"goflix/apierr"
+	"goflix/config"
+	"goflix/i18n"
+	"goflix/middleware"
+	"goflix/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// * * * SETTINGS * * *
+
+func (s *Serve) handelGetSettings(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	settings, err := s.db.GetUserSettings(c.Request.Context(), user.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"settings": settings, "region": s.viewerRegion(c)})
+}
+
+func (s *Serve) handelUpdateSettings(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	var settings models.UserSettings
+	err := c.ShouldBindJSON(&settings)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	settings.UserId = user.Id
+	settings.Region = strings.ToUpper(settings.Region)
+	if settings.Region != "" && len(settings.Region) != 2 {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_REGION))
+		return
+	}
+	settings.Language = i18n.Normalize(settings.Language)
+	if settings.Language != "" && !i18n.Valid(settings.Language) {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "language"))
+		return
+	}
+	err = s.db.SaveUserSettings(c.Request.Context(), &settings)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// regionKey keeps the region of the viewer in the request context once resolved.
+const regionKey = "viewerRegion"
+
+// viewerRegion resolves the region from the profile setting, then the GeoIP database, then the default.
+// A request asks for it several times, it is resolved on the first call only.
+func (s *Serve) viewerRegion(c *gin.Context) string {
+	if region, ok := c.Get(regionKey); ok {
+		return region.(string)
+	}
+	region := s.resolveRegion(c)
+	c.Set(regionKey, region)
+	return region
+}
+
+func (s *Serve) resolveRegion(c *gin.Context) string {
+	if user, ok := middleware.CurrentUser(c); ok {
+		settings, err := s.db.GetUserSettings(c.Request.Context(), user.Id)
+		if err == nil && settings.Region != "" {
+			return settings.Region
+		}
+	}
+	if country := s.geoip.Country(c.ClientIP()); country != "" {
+		return country
+	}
+	return config.DEFAULT_REGION
+}
+
+// * * * AVAILABILITY * * *
+
+func (s *Serve) handelGetAvailability(c *gin.Context) {
+	if id, err := s.getMovieID(c); err == nil {
+		windows, err := s.db.GetAvailability(c.Request.Context(), id)
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"availability": windows})
+	}
+}
+
+func (s *Serve) handelAddAvailability(c *gin.Context) {
+	id, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	if _, err := s.db.GetMoviesById(c.Request.Context(), id, nil); err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	var window models.Availability
+	err = c.ShouldBindJSON(&window)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	window.MovieId = id
+	window.Country = strings.ToUpper(window.Country)
+	if window.Country != config.ALL_REGIONS && len(window.Country) != 2 {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_COUNTRY))
+		return
+	}
+	if window.Ends != nil && !window.Ends.After(window.Starts) {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_WINDOW))
+		return
+	}
+	err = s.db.AddAvailability(c.Request.Context(), &window)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, window)
+}
+
+func (s *Serve) handelDeleteAvailability(c *gin.Context) {
+	movieID, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("windowID"))
+	if err != nil {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "windowID"))
+		return
+	}
+	err = s.db.DeleteAvailability(c.Request.Context(), movieID, id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "availability deleted"})
+}
+
+func (s *Serve) handelGetLeavingSoon(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(config.LEAVING_SOON_DAYS)))
+	if err != nil || days < 1 || days > config.LEAVING_SOON_MAX_DAYS {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "days"))
+		return
+	}
+	filter, err := s.catalogFilter(c)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	movies, err := s.db.GetLeavingSoon(c.Request.Context(), filter, time.Now().AddDate(0, 0, days))
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	err = s.translate(c, movies...)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"region": filter.Region, "movies": movies})
+}
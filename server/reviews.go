@@ -0,0 +1,365 @@
+package server
+
+import (
+	"goflix/apierr"
+	"goflix/config"
+	"goflix/db"
+	"goflix/models"
+	"goflix/moderation"
+	"goflix/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newProfanityFilter reads the banned words file, the built-in list is used without it.
+func newProfanityFilter() *moderation.Filter {
+	filter, err := moderation.Load(config.PROFANITY_WORDS_PATH)
+	if err != nil {
+		log.Printf("profanity filter uses the built-in words: %v", err)
+		return moderation.NewFilter(config.PROFANITY_WORDS)
+	}
+	return filter
+}
+
+// * * * REVIEWS * * *
+
+func (s *Serve) handelGetReviews(c *gin.Context) {
+	movieID, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	page, limit, err := s.getPage(c)
+	if err != nil {
+		return
+	}
+	query := models.ReviewQuery{MovieId: movieID, Status: config.REVIEW_APPROVED, Spoilers: c.DefaultQuery("spoilers", "true") != "false"}
+	if !s.reviewSort(c, &query, config.REVIEW_SORT_RECENT) {
+		return
+	}
+	filter, err := s.catalogFilter(c)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	_, err = s.db.GetMoviesById(c.Request.Context(), movieID, filter)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	reviews, total, err := s.db.GetReviews(c.Request.Context(), &query, (page-1)*limit, limit)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	// moderation stays between authors and moderators
+	for _, review := range reviews {
+		review.Reports, review.ModeratedBy, review.ModeratedAt, review.ModerationNote = 0, 0, nil, ""
+	}
+	c.JSON(http.StatusOK, gin.H{"page": page, "limit": limit, "total": total, "reviews": reviews})
+}
+
+func (s *Serve) handelGetMyReview(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	movieID, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	review, err := s.db.GetReviewByUser(c.Request.Context(), movieID, user.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, review)
+}
+
+// handelSaveReview rates the title and writes or rewrites the review of the user.
+func (s *Serve) handelSaveReview(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	movieID, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	var input struct {
+		Stars   int    `json:"stars"`
+		Body    string `json:"body"`
+		Spoiler bool   `json:"spoiler"`
+	}
+	err = c.ShouldBindJSON(&input)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	input.Body = strings.TrimSpace(input.Body)
+	if input.Stars < config.RATING_MIN_STARS || input.Stars > config.RATING_MAX_STARS {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "stars"))
+		return
+	}
+	if input.Body == "" {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.MISSING_FIELD, "body"))
+		return
+	}
+	if utf8.RuneCountInString(input.Body) > config.REVIEW_MAX_LENGTH {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.REVIEW_TOO_LONG, config.REVIEW_MAX_LENGTH))
+		return
+	}
+	filter, err := s.catalogFilter(c)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	_, err = s.db.GetMoviesById(c.Request.Context(), movieID, filter)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+
+	// the rating and the review are saved together, and a moderator's decision made meanwhile
+	// is not overwritten
+	var review *models.Review
+	rating := models.Rating{MovieId: movieID, Stars: input.Stars, UserId: user.Id}
+	err = s.db.WithTx(c.Request.Context(), func(store db.Storage) error {
+		review, err = store.GetReviewByUser(c.Request.Context(), movieID, user.Id)
+		if db.IsNotFound(err) {
+			review, err = &models.Review{MovieId: movieID, UserId: user.Id}, nil
+		}
+		if err != nil {
+			return err
+		}
+		review.Body = input.Body
+		review.Spoiler = input.Spoiler
+		review.Status, review.Flag = s.reviewStatus(review)
+		err = store.SaveRating(c.Request.Context(), &rating)
+		if err != nil {
+			return err
+		}
+		return store.SaveReview(c.Request.Context(), review)
+	})
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	s.recordEvent(c, config.EVENT_RATING, user.Id, movieID)
+	s.emitWebhook(config.WEBHOOK_RATING_SAVED, &rating)
+	review, err = s.db.GetReview(c.Request.Context(), review.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, review)
+}
+
+// reviewStatus decides whether a new or rewritten review is published or waits for a moderator.
+// Rewriting a review doesn't get it out of the queue nor undo a moderator's decision.
+func (s *Serve) reviewStatus(review *models.Review) (string, string) {
+	switch {
+	case len(s.profanity.Check(review.Body)) > 0:
+		return config.REVIEW_PENDING, config.REVIEW_FLAG_PROFANITY
+	case review.Status == config.REVIEW_REJECTED || review.Status == config.REVIEW_HIDDEN:
+		return config.REVIEW_PENDING, config.REVIEW_FLAG_EDITED
+	case review.Status == config.REVIEW_PENDING && review.Flag != config.REVIEW_FLAG_PROFANITY:
+		return review.Status, review.Flag
+	case config.REVIEW_PREMODERATION:
+		return config.REVIEW_PENDING, ""
+	}
+	return config.REVIEW_APPROVED, ""
+}
+
+func (s *Serve) handelDeleteMyReview(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	movieID, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	review, err := s.db.GetReviewByUser(c.Request.Context(), movieID, user.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	err = s.db.DeleteReview(c.Request.Context(), review.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "review deleted"})
+}
+
+func (s *Serve) handelVoteReview(c *gin.Context) {
+	user, review := s.getOthersReview(c)
+	if review == nil {
+		return
+	}
+	err := s.db.VoteReview(c.Request.Context(), review.Id, user.Id, c.Request.Method != http.MethodDelete)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	review, err = s.db.GetReview(c.Request.Context(), review.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": review.Id, "helpful": review.Helpful})
+}
+
+func (s *Serve) handelReportReview(c *gin.Context) {
+	user, review := s.getOthersReview(c)
+	if review == nil {
+		return
+	}
+	report := models.ReviewReport{ReviewId: review.Id, UserId: user.Id}
+	err := c.ShouldBindJSON(&report)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	report.ReviewId, report.UserId = review.Id, user.Id
+	report.Reason = strings.TrimSpace(report.Reason)
+	if report.Reason == "" {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.MISSING_FIELD, "reason"))
+		return
+	}
+	_, err = s.db.ReportReview(c.Request.Context(), &report)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "review reported"})
+}
+
+// getOthersReview reads a published review of another user, for votes and reports.
+func (s *Serve) getOthersReview(c *gin.Context) (*models.User, *models.Review) {
+	user := s.currentUser(c)
+	if user == nil {
+		return nil, nil
+	}
+	review := s.getReview(c)
+	if review == nil {
+		return nil, nil
+	}
+	if review.Status != config.REVIEW_APPROVED {
+		apierr.Write(c, apierr.New(http.StatusNotFound, apierr.NOT_FOUND))
+		return nil, nil
+	}
+	if review.UserId == user.Id {
+		apierr.Write(c, apierr.New(http.StatusForbidden, apierr.OWN_REVIEW))
+		return nil, nil
+	}
+	return user, review
+}
+
+// * * * MODERATION * * *
+
+// handelGetModerationQueue lists the pending reviews, the oldest first, or those of another status.
+func (s *Serve) handelGetModerationQueue(c *gin.Context) {
+	page, limit, err := s.getPage(c)
+	if err != nil {
+		return
+	}
+	query := models.ReviewQuery{Status: c.DefaultQuery("status", config.REVIEW_PENDING), Spoilers: true}
+	if !utils.Contains(config.REVIEW_STATUSES, query.Status) {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_CHOICE, "status", config.REVIEW_STATUSES))
+		return
+	}
+	if movieID := c.Query("movieid"); movieID != "" {
+		query.MovieId, err = strconv.Atoi(movieID)
+		if err != nil {
+			apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "movieid"))
+			return
+		}
+	}
+	if !s.reviewSort(c, &query, config.REVIEW_SORT_OLDEST) {
+		return
+	}
+	reviews, total, err := s.db.GetReviews(c.Request.Context(), &query, (page-1)*limit, limit)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"page": page, "limit": limit, "total": total, "reviews": reviews})
+}
+
+func (s *Serve) handelGetReviewReports(c *gin.Context) {
+	review := s.getReview(c)
+	if review == nil {
+		return
+	}
+	reports, err := s.db.GetReviewReports(c.Request.Context(), review.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"review": review, "reports": reports})
+}
+
+func (s *Serve) handelModerateReview(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	review := s.getReview(c)
+	if review == nil {
+		return
+	}
+	var decision struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+	}
+	err := c.ShouldBindJSON(&decision)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	status, ok := config.REVIEW_ACTION_STATUS[decision.Action]
+	if !ok {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_CHOICE, "action", config.REVIEW_ACTIONS))
+		return
+	}
+	err = s.db.ModerateReview(c.Request.Context(), review.Id, status, user.Id, strings.TrimSpace(decision.Note))
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	review, err = s.db.GetReview(c.Request.Context(), review.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, review)
+}
+
+func (s *Serve) reviewSort(c *gin.Context, query *models.ReviewQuery, sort string) bool {
+	query.Sort = c.DefaultQuery("sort", sort)
+	if !utils.Contains(config.REVIEW_SORTS, query.Sort) {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_CHOICE, "sort", config.REVIEW_SORTS))
+		return false
+	}
+	return true
+}
+
+func (s *Serve) getReview(c *gin.Context) *models.Review {
+	id, err := strconv.Atoi(c.Param("reviewID"))
+	if err != nil {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "reviewID"))
+		return nil
+	}
+	review, err := s.db.GetReview(c.Request.Context(), id)
+	if err != nil {
+		apierr.Write(c, err)
+		return nil
+	}
+	return review
+}
@@ -1,37 +1,140 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"goflix/apierr"
+	"goflix/billing"
+	"goflix/config"
 	"goflix/db"
+	"goflix/geoip"
+	"goflix/mailer"
+	"goflix/metadata"
 	"goflix/middleware"
 	"goflix/models"
+	"goflix/moderation"
+	"goflix/pubsub"
+	"goflix/recommend"
+	"goflix/webhooks"
+	"log"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Server interface {
-	Run()
+	// Run serves until ctx is done, then waits for the requests in flight and the jobs.
+	Run(ctx context.Context) error
 }
 
 type Serve struct {
-	router *gin.Engine
-	db     db.Storage
+	router   *gin.Engine
+	db       db.Storage
+	payments billing.PaymentProvider
+	geoip    *geoip.DB
+	// metadata is nil when no provider is configured
+	metadata metadata.MetadataProvider
+	similar  *recommend.Similar
+	// profanity holds back reviews using banned words for moderation
+	profanity *moderation.Filter
+	broker    pubsub.Broker
+	// tickets open the event stream of the clients that cannot send the token in a header
+	tickets  *middleware.Tickets
+	webhooks *webhooks.Dispatcher
+	// billingEvents is set when the secret verifying the payment provider events is configured
+	billingEvents bool
+	// mailer sends the notification digests
+	mailer mailer.Mailer
+	// jobs waits for the background jobs on shutdown, they get the jobs context canceled by it
+	jobs    sync.WaitGroup
+	jobsCtx context.Context
 }
 
 func New(db db.Storage) Server {
 	gin.SetMode(gin.ReleaseMode)
+	geo, err := geoip.Load(config.GEOIP_DB_PATH)
+	if err != nil {
+		log.Printf("geoip disabled: %v", err)
+	}
+	secret := os.Getenv(config.BILLING_WEBHOOK_SECRET_ENV)
+	if secret == "" {
+		log.Printf("%s not set, payment provider events are not received", config.BILLING_WEBHOOK_SECRET_ENV)
+	}
 	return &Serve{
-		router: gin.Default(),
-		db:     db,
+		router:    newRouter(),
+		db:        db,
+		payments:  billing.NewFake(secret),
+		geoip:     geo,
+		metadata:  newMetadataProvider(),
+		similar:   recommend.NewSimilar(),
+		profanity: newProfanityFilter(),
+		broker:    pubsub.NewMemory(config.LIVE_BUFFER),
+		tickets:   middleware.NewTickets(config.LIVE_TICKET_TTL),
+		webhooks:  webhooks.NewDispatcher(db),
+		mailer:    newMailer(),
+		jobsCtx:   context.Background(),
+
+		billingEvents: secret != "",
+	}
+}
+
+// newRouter only trusts X-Forwarded-For from the configured proxies, clients could pick their region otherwise.
+func newRouter() *gin.Engine {
+	router := gin.Default()
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv(config.TRUSTED_PROXIES_ENV), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	err := router.SetTrustedProxies(proxies)
+	if err != nil {
+		log.Fatalf("%s: %v", config.TRUSTED_PROXIES_ENV, err)
 	}
+	return router
 }
 
-func (s *Serve) Run() {
+// newMetadataProvider talks to TMDB when an API key is configured, enrichment is disabled otherwise.
+func newMetadataProvider() metadata.MetadataProvider {
+	if key := os.Getenv(config.TMDB_API_KEY_ENV); key != "" {
+		return metadata.NewTMDB(config.TMDB_API_URL, key, config.TMDB_LANGUAGE)
+	}
+	log.Printf("%s not set, metadata enrichment disabled", config.TMDB_API_KEY_ENV)
+	return nil
+}
+
+func (s *Serve) Run(ctx context.Context) error {
 	s.routes()
-	s.router.Run(":4123")
+	jobs, stopJobs := context.WithCancel(ctx)
+	defer stopJobs()
+	s.jobsCtx = jobs
+	s.startJobs(jobs)
+
+	srv := &http.Server{Addr: config.SERVER_ADDR, Handler: s.router}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+	select {
+	case err := <-errs:
+		// the server could not start, the jobs stop before the database closes
+		stopJobs()
+		s.jobs.Wait()
+		return err
+	case <-ctx.Done():
+	}
+	log.Printf("shutting down")
+	stopJobs()
+	shutdown, cancel := context.WithTimeout(context.Background(), config.SHUTDOWN_TIMEOUT)
+	defer cancel()
+	err := srv.Shutdown(shutdown)
+	s.jobs.Wait()
+	return err
 }
 
 func (s *Serve) routes() {
@@ -39,10 +142,18 @@ func (s *Serve) routes() {
 	s.router.GET("/", s.handelHello)
 	s.router.POST("/login", s.handelLogin)
 	s.router.POST("/users", s.handelAddUsers)
+	s.router.GET("/plans", s.handelGetPlans)
+	if s.billingEvents {
+		s.router.POST("/billing/webhook", s.handelBillingWebhook)
+	}
+	// browsers cannot send headers with EventSource, they open the stream with a ticket
+	s.router.GET("/events", middleware.TicketMiddleware(s.tickets), s.handelEvents)
 
 	// Routes for connected user
 	s.router.Use(middleware.JwtMiddleware())
 
+	s.router.POST("/events/ticket", s.handelEventsTicket)
+
 	s.router.GET("/users/:userID", s.handelGetUsers)
 	s.router.DELETE("/users/:userID", s.handelDeleteUsers)
 	s.router.PUT("/users/:userID", s.handelUpdateUsers)
@@ -50,19 +161,132 @@ func (s *Serve) routes() {
 	s.router.GET("/series/", s.handelGetListSeries)
 	s.router.GET("/movies", s.handelGetListMovies)
 	s.router.GET("/movies/:movieID", s.handelGetmovie)
+	s.router.GET("/movies/:movieID/similar", s.handelGetSimilar)
+	s.router.GET("/movies/:movieID/reviews", s.handelGetReviews)
+	s.router.GET("/movies/:movieID/review", s.handelGetMyReview)
+	s.router.PUT("/movies/:movieID/review", s.handelSaveReview)
+	s.router.DELETE("/movies/:movieID/review", s.handelDeleteMyReview)
+	s.router.PUT("/reviews/:reviewID/helpful", s.handelVoteReview)
+	s.router.DELETE("/reviews/:reviewID/helpful", s.handelVoteReview)
+	s.router.POST("/reviews/:reviewID/report", s.handelReportReview)
+	s.router.GET("/movies/:movieID/master.m3u8", middleware.ActiveSubscription(s.db), s.handelGetMasterPlaylist)
+	s.router.GET("/movies/:movieID/subtitles/:subtitleID", s.handelGetSubtitle)
+	s.router.GET("/movies/:movieID/subtitles/:subtitleID/playlist.m3u8", s.handelGetSubtitlePlaylist)
 
 	s.router.POST("/ratings", s.handelSaveRatingsUsers)
 	s.router.GET("/ratings/:userID", s.handelGetRatingsUsers)
+	s.router.GET("/me/recommendations", s.handelGetRecommendations)
+	s.router.GET("/me/home", s.handelGetHome)
+	s.router.GET("/me/home/rows/:rowID", s.handelGetHomeRow)
+	s.router.GET("/me/notifications", s.handelGetNotifications)
+	s.router.POST("/me/notifications/read", s.handelReadAllNotifications)
+	s.router.PUT("/me/notifications/:notificationID/read", s.handelReadNotification)
+	s.router.DELETE("/me/notifications/:notificationID/read", s.handelReadNotification)
+	s.router.GET("/me/notification-preferences", s.handelGetNotificationPreferences)
+	s.router.PUT("/me/notification-preferences", s.handelSaveNotificationPreferences)
 
 	s.router.POST("//favorites", s.handelSaveFavoriteUsers)
 	s.router.GET("/favorites/:userID", s.handelGetFavoriteUsers)
 	s.router.DELETE("/favorites/:userID/:favoriteID", s.handelDeleteFavoriteUsers)
 
+	s.router.GET("/me/lists", s.handelGetLists)
+	s.router.POST("/me/lists", s.handelAddList)
+	s.router.GET("/me/lists/:listID", s.handelGetList)
+	s.router.PUT("/me/lists/:listID", s.handelUpdateList)
+	s.router.DELETE("/me/lists/:listID", s.handelDeleteList)
+	s.router.POST("/me/lists/:listID/items", s.handelAddListItem)
+	s.router.PUT("/me/lists/:listID/items", s.handelReorderList)
+	s.router.DELETE("/me/lists/:listID/items/:movieID", s.handelRemoveListItem)
+	s.router.GET("/lists/:listID", s.handelGetPublicList)
+	s.router.GET("/shared/lists/:token", s.handelGetSharedList)
+	s.router.GET("/users/:userID/lists", s.handelGetUserLists)
+
+	s.router.GET("/browse/leaving-soon", s.handelGetLeavingSoon)
+	s.router.GET("/browse/trending", s.handelGetTrending)
+	s.router.GET("/browse/top10", s.handelGetTop10)
+	s.router.GET("/me/settings", s.handelGetSettings)
+	s.router.PUT("/me/settings", s.handelUpdateSettings)
+
+	s.router.GET("/genres", s.handelGetGenres)
+	s.router.GET("/genres/:slug/titles", s.handelGetGenreTitles)
+
+	s.router.GET("/people/:personID", s.handelGetPerson)
+	s.router.GET("/people/:personID/credits", s.handelGetPersonCredits)
+
+	s.router.GET("/editorial/movies", s.handelGetEditorialMovies)
+	s.router.POST("/movies/:movieID/status", s.handelMovieTransition)
+
+	s.router.GET("/maturity", s.handelGetMaturityScales)
+	s.router.GET("/me/parental", s.handelGetParentalControl)
+	s.router.PUT("/me/parental", s.handelUpdateParentalControl)
+
+	s.router.POST("/streams", middleware.ActiveSubscription(s.db), s.handelStartStream)
+	s.router.GET("/streams", s.handelGetStreams)
+	s.router.PUT("/streams/:sessionID/heartbeat", s.handelHeartbeatStream)
+	s.router.DELETE("/streams/:sessionID", s.handelStopStream)
+
+	s.router.GET("/me/subscription", s.handelGetSubscription)
+	s.router.POST("/me/subscription", s.handelSubscribe)
+	s.router.DELETE("/me/subscription", s.handelCancelSubscription)
+
 	// Routes for admin user only
 	s.router.Use(middleware.AdminOnly())
 
 	s.router.POST("/movies/", s.handelAddMovies)
 	s.router.DELETE("/movies/:movieID", s.handelDeleteMovies)
+	s.router.PUT("/movies/:movieID/maturity", s.handelUpdateMaturity)
+	s.router.GET("/movies/:movieID/availability", s.handelGetAvailability)
+	s.router.POST("/movies/:movieID/availability", s.handelAddAvailability)
+	s.router.DELETE("/movies/:movieID/availability/:windowID", s.handelDeleteAvailability)
+	s.router.PUT("/movies/:movieID/genres", s.handelSetMovieGenres)
+	s.router.POST("/genres", s.handelAddGenre)
+	s.router.PUT("/genres/:slug", s.handelUpdateGenre)
+	s.router.POST("/genres/:slug/merge", s.handelMergeGenre)
+	s.router.PUT("/genres/:slug/translations/:locale", s.handelSaveGenreTranslation)
+	s.router.DELETE("/genres/:slug/translations/:locale", s.handelDeleteGenreTranslation)
+	s.router.GET("/movies/:movieID/translations", s.handelGetMovieTranslations)
+	s.router.PUT("/movies/:movieID/translations/:locale", s.handelSaveMovieTranslation)
+	s.router.DELETE("/movies/:movieID/translations/:locale", s.handelDeleteMovieTranslation)
+
+	s.router.POST("/movies/:movieID/credits", s.handelAddCredit)
+	s.router.DELETE("/movies/:movieID/credits/:creditID", s.handelDeleteCredit)
+	s.router.POST("/movies/:movieID/subtitles", s.handelAddSubtitle)
+	s.router.DELETE("/movies/:movieID/subtitles/:subtitleID", s.handelDeleteSubtitle)
+	s.router.POST("/movies/:movieID/audiotracks", s.handelAddAudioTrack)
+	s.router.DELETE("/movies/:movieID/audiotracks/:trackID", s.handelDeleteAudioTrack)
+
+	s.router.POST("/admin/catalog/import", s.handelImportCatalog)
+	s.router.GET("/admin/catalog/export", s.handelExportCatalog)
+	s.router.GET("/admin/metadata/search", s.handelSearchMetadata)
+	s.router.POST("/movies/:movieID/enrich", s.handelEnrichMovie)
+	s.router.POST("/admin/library/scan", s.handelScanLibrary)
+	s.router.GET("/movies/:movieID/files", s.handelGetMediaFiles)
+	s.router.POST("/admin/recommendations/rebuild", s.handelRebuildRecommendations)
+	s.router.POST("/admin/activity/aggregate", s.handelAggregateActivity)
+	s.router.GET("/admin/home/rows", s.handelGetHomeRows)
+	s.router.POST("/admin/home/rows", s.handelAddHomeRow)
+	s.router.PUT("/admin/home/rows/:rowID", s.handelUpdateHomeRow)
+	s.router.DELETE("/admin/home/rows/:rowID", s.handelDeleteHomeRow)
+	s.router.GET("/admin/reviews", s.handelGetModerationQueue)
+	s.router.GET("/admin/reviews/:reviewID/reports", s.handelGetReviewReports)
+	s.router.POST("/admin/reviews/:reviewID/moderate", s.handelModerateReview)
+	s.router.GET("/admin/webhooks", s.handelGetWebhooks)
+	s.router.POST("/admin/webhooks", s.handelAddWebhook)
+	s.router.PUT("/admin/webhooks/:webhookID", s.handelUpdateWebhook)
+	s.router.DELETE("/admin/webhooks/:webhookID", s.handelDeleteWebhook)
+	s.router.GET("/admin/webhooks/:webhookID/deliveries", s.handelGetWebhookDeliveries)
+	s.router.GET("/admin/webhook-deliveries/:deliveryID", s.handelGetWebhookDelivery)
+	s.router.POST("/admin/webhook-deliveries/:deliveryID/redeliver", s.handelRedeliverWebhook)
+	s.router.POST("/admin/notifications/leaving-soon", s.handelNotifyLeavingSoon)
+	s.router.POST("/admin/notifications/digest", s.handelSendDigests)
+
+	s.router.POST("/plans", s.handelAddPlan)
+	if s.billingEvents {
+		s.router.POST("/admin/billing/simulate", s.handelSimulateBillingEvent)
+	}
+
+	s.router.GET("/admin/streams", s.handelGetAllStreams)
+	s.router.DELETE("/admin/streams/:sessionID", s.handelKillStream)
 
 }
 
@@ -74,20 +298,21 @@ func (s *Serve) handelHello(c *gin.Context) {
 
 func (s *Serve) handelAddUsers(c *gin.Context) {
 	if user := s.decodeUserJSON(c); user != nil {
-		err := s.db.SaveUser(user)
+		err := s.db.SaveUser(c.Request.Context(), user)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			apierr.Write(c, err)
 			return
 		}
+		s.emitWebhook(config.WEBHOOK_USER_CREATED, gin.H{"id": user.Id, "user": user.User, "account": user.Account})
 		c.JSON(http.StatusOK, gin.H{"message": "user saved"})
 	}
 }
 
 func (s *Serve) handelGetUsers(c *gin.Context) {
 	if id, err := s.getUserID(c); err == nil {
-		user, err := s.db.GetUser(id)
+		user, err := s.db.GetUser(c.Request.Context(), id)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			apierr.Write(c, err)
 			return
 		}
 		c.JSON(http.StatusOK, user)
@@ -96,9 +321,9 @@ func (s *Serve) handelGetUsers(c *gin.Context) {
 
 func (s *Serve) handelDeleteUsers(c *gin.Context) {
 	if id, err := s.getUserID(c); err == nil {
-		err := s.db.DeleteUser(id)
+		err := s.db.DeleteUser(c.Request.Context(), id)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			apierr.Write(c, err)
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{"message": "user deleted"})
@@ -110,9 +335,9 @@ func (s *Serve) handelUpdateUsers(c *gin.Context) {
 		if id, err := s.getUserID(c); err == nil {
 			user.Id = id
 		}
-		err := s.db.UpdateUser(user)
+		err := s.db.UpdateUser(c.Request.Context(), user)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			apierr.Write(c, err)
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{"message": "user updated"})
@@ -121,19 +346,23 @@ func (s *Serve) handelUpdateUsers(c *gin.Context) {
 
 func (s *Serve) handelLogin(c *gin.Context) {
 	if user := s.decodeUserJSON(c); user != nil {
-		err := s.db.GetID(user)
+		err := s.db.GetID(c.Request.Context(), user)
+		if db.IsNotFound(err) || errors.Is(err, db.ErrWrongPassword) {
+			apierr.Write(c, apierr.New(http.StatusUnauthorized, apierr.INVALID_CREDENTIALS))
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			apierr.Write(c, err)
 			return
 		}
-		user, err := s.db.GetUser(user.Id)
+		user, err := s.db.GetUser(c.Request.Context(), user.Id)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			apierr.Write(c, err)
 			return
 		}
 		token, err := middleware.GenerateToken(user.Id, user.Account)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			apierr.Write(c, err)
 			return
 		} else {
 			c.JSON(http.StatusOK, gin.H{"token": token})
@@ -145,9 +374,9 @@ func (s *Serve) handelLogin(c *gin.Context) {
 
 func (s *Serve) handelGetRatingsUsers(c *gin.Context) {
 	if userId, err := s.getUserID(c); err == nil {
-		result, err := s.db.GetRatingByUser(userId)
+		result, err := s.db.GetRatingByUser(c.Request.Context(), userId)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			apierr.Write(c, err)
 			return
 		}
 		c.JSON(http.StatusOK, result)
@@ -157,11 +386,13 @@ func (s *Serve) handelGetRatingsUsers(c *gin.Context) {
 func (s *Serve) handelSaveRatingsUsers(c *gin.Context) {
 
 	if ranting := s.decodeRatingJSON(c); ranting != nil {
-		err := s.db.SaveRating(ranting)
+		err := s.db.SaveRating(c.Request.Context(), ranting)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			apierr.Write(c, err)
 			return
 		}
+		s.recordEvent(c, config.EVENT_RATING, ranting.UserId, ranting.MovieId)
+		s.emitWebhook(config.WEBHOOK_RATING_SAVED, ranting)
 		c.JSON(http.StatusOK, gin.H{"message": "ranting saved"})
 	}
 }
@@ -169,9 +400,21 @@ func (s *Serve) decodeRatingJSON(c *gin.Context) *models.Rating {
 	var rating models.Rating
 	err := c.ShouldBindJSON(&rating)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.Write(c, apierr.BadRequest(err))
 		return nil
 	}
+	if rating.Stars < config.RATING_MIN_STARS || rating.Stars > config.RATING_MAX_STARS {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "stars"))
+		return nil
+	}
+	// ratings are given for oneself unless a user id is sent
+	if rating.UserId == 0 {
+		user := s.currentUser(c)
+		if user == nil {
+			return nil
+		}
+		rating.UserId = user.Id
+	}
 	return &rating
 }
 
@@ -181,35 +424,42 @@ func (s *Serve) handelGetFavoriteUsers(c *gin.Context) {
 	var favorite models.Favorite
 	var err error
 	if favorite.UserId, err = s.getUserID(c); err == nil {
-		err := s.db.GetFavoriteByUser(&favorite)
+		err := s.db.GetFavoriteByUser(c.Request.Context(), &favorite)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			apierr.Write(c, err)
 			return
 		}
 		c.JSON(http.StatusOK, favorite)
 	}
 }
 
+// handelSaveFavoriteUsers adds a title to the default list of the user, at the top.
 func (s *Serve) handelSaveFavoriteUsers(c *gin.Context) {
 
 	if favorite := s.decodeFavoriteJSON(c); favorite != nil {
-		newFavor := favorite.MoviesID
-		err := s.db.GetFavoriteByUser(favorite)
+		movieID, err := strconv.Atoi(favorite.MoviesID)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "moviesid"))
 			return
 		}
-		// delete if exist
-		favorite.MoviesID = strings.Replace(favorite.MoviesID, fmt.Sprintf("#%s|", newFavor), "", -1)
-
-		// add new
-		favorite.MoviesID += fmt.Sprintf("#%s|", newFavor)
-
-		err = s.db.SaveFavorite(favorite)
+		var list *models.List
+		var added bool
+		err = s.db.WithTx(c.Request.Context(), func(store db.Storage) error {
+			list, err = store.GetDefaultList(c.Request.Context(), favorite.UserId)
+			if err != nil {
+				return err
+			}
+			added, err = store.AddListItem(c.Request.Context(), list.Id, movieID, 1)
+			return err
+		})
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			apierr.Write(c, err)
 			return
 		}
+		if added {
+			s.recordEvent(c, config.EVENT_FAVORITE, favorite.UserId, movieID)
+			s.publishListChanged(c, list, config.LIST_ITEM_ADDED, movieID)
+		}
 		c.JSON(http.StatusOK, gin.H{"message": "favorite saved"})
 	}
 }
@@ -217,25 +467,35 @@ func (s *Serve) handelSaveFavoriteUsers(c *gin.Context) {
 func (s *Serve) handelDeleteFavoriteUsers(c *gin.Context) {
 	userId, err := s.getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
 	}
-	favoriteId := s.getFavoritesID(c)
-
-	var favorite models.Favorite
-	favorite.UserId = userId
-	err = s.db.GetFavoriteByUser(&favorite)
+	movieID, err := strconv.Atoi(s.getFavoritesID(c))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "favoriteID"))
 		return
 	}
-	// delete
-	favorite.MoviesID = strings.Replace(favorite.MoviesID, fmt.Sprintf("#%s|", favoriteId), "", -1)
-
-	err = s.db.SaveFavorite(&favorite)
+	var list *models.List
+	removed := false
+	err = s.db.WithTx(c.Request.Context(), func(store db.Storage) error {
+		list, err = store.GetDefaultList(c.Request.Context(), userId)
+		if err != nil {
+			return err
+		}
+		err = store.RemoveListItem(c.Request.Context(), list.Id, movieID)
+		// removing a title that is not a favorite is not an error
+		if db.IsNotFound(err) {
+			return nil
+		}
+		removed = err == nil
+		return err
+	})
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		apierr.Write(c, err)
 		return
 	}
+	if removed {
+		s.publishListChanged(c, list, config.LIST_ITEM_REMOVED, movieID)
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "favorite deleted"})
 
 }
@@ -244,7 +504,7 @@ func (s *Serve) decodeFavoriteJSON(c *gin.Context) *models.Favorite {
 	var favorite models.Favorite
 	err := c.ShouldBindJSON(&favorite)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.Write(c, apierr.BadRequest(err))
 		return nil
 	}
 	return &favorite
@@ -256,7 +516,7 @@ func (s *Serve) decodeUserJSON(c *gin.Context) *models.User {
 	var user models.User
 	err := c.ShouldBindJSON(&user)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.Write(c, apierr.BadRequest(err))
 		return nil
 	}
 	return &user
@@ -264,7 +524,7 @@ func (s *Serve) decodeUserJSON(c *gin.Context) *models.User {
 func (s *Serve) getUserID(c *gin.Context) (int, error) {
 	id, err := strconv.Atoi(c.Param("userID"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "userID"))
 		return 0, err
 	}
 	return id, nil
@@ -276,48 +536,103 @@ func (s *Serve) getFavoritesID(c *gin.Context) string {
 // * * * MOVIE * * *
 
 func (s *Serve) handelGetListSeries(c *gin.Context) {
-	series, err := s.db.GetSeries()
+	filter, err := s.catalogFilter(c)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		apierr.Write(c, err)
+		return
+	}
+	series, err := s.db.GetSeries(c.Request.Context(), filter)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	err = s.translate(c, series...)
+	if err != nil {
+		apierr.Write(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"series": series})
 }
 func (s *Serve) handelGetListMovies(c *gin.Context) {
-	movies, err := s.db.GetMovies()
+	filter, err := s.catalogFilter(c)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	movies, err := s.db.GetMovies(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		apierr.Write(c, err)
+		return
+	}
+	err = s.translate(c, movies...)
+	if err != nil {
+		apierr.Write(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"movie": movies})
 }
 func (s *Serve) handelGetmovie(c *gin.Context) {
 	if id, err := s.getMovieID(c); err == nil {
-		user, err := s.db.GetMoviesById(id)
+		filter, err := s.catalogFilter(c)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			apierr.Write(c, err)
 			return
 		}
-		c.JSON(http.StatusOK, user)
+		movie, err := s.db.GetMoviesById(c.Request.Context(), id, filter)
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		err = s.translate(c, movie)
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		err = s.loadTracks(c.Request.Context(), movie)
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		movie.Credits, err = s.db.GetCreditsByMovie(c.Request.Context(), movie.Id)
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		movie.Genres, err = s.db.GetGenresByMovie(c.Request.Context(), movie.Id, s.locales(c))
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, movie)
 	}
 }
 func (s *Serve) handelAddMovies(c *gin.Context) {
 	if movie := s.decodeMovieJSON(c); movie != nil {
-		err := s.db.AddMovie(movie)
+		level, ok := config.TitleMaturityLevel(movie.Maturity)
+		if !ok {
+			apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.UNKNOWN_MATURITY))
+			return
+		}
+		movie.MaturityLevel = level
+		movie.Status = config.STATUS_DRAFT
+		movie.PublishAt = nil
+		err := s.db.AddMovie(c.Request.Context(), movie)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			apierr.Write(c, err)
 			return
 		}
+		s.emitWebhook(config.WEBHOOK_MOVIE_CREATED, movie)
 		c.JSON(http.StatusOK, gin.H{"message": "movie saved"})
 	}
 }
 func (s *Serve) handelDeleteMovies(c *gin.Context) {
 	if id, err := s.getMovieID(c); err == nil {
-		err := s.db.DeleteMovieByID(id)
+		err := s.db.DeleteMovieByID(c.Request.Context(), id)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			apierr.Write(c, err)
 			return
 		}
+		s.emitWebhook(config.WEBHOOK_MOVIE_DELETED, gin.H{"id": id})
 		c.JSON(http.StatusOK, gin.H{"message": "movie deleted"})
 	}
 }
@@ -328,7 +643,7 @@ func (s *Serve) decodeMovieJSON(c *gin.Context) *models.Movies {
 	var movie models.Movies
 	err := c.ShouldBindJSON(&movie)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.Write(c, apierr.BadRequest(err))
 		return nil
 	}
 	return &movie
@@ -336,8 +651,23 @@ func (s *Serve) decodeMovieJSON(c *gin.Context) *models.Movies {
 func (s *Serve) getMovieID(c *gin.Context) (int, error) {
 	movieID, err := strconv.Atoi(c.Param("movieID"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID"})
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "movieID"))
 		return 0, err
 	}
 	return movieID, nil
 }
+
+// getPage reads the page and limit query parameters, page starts at 1.
+func (s *Serve) getPage(c *gin.Context) (int, int, error) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "page"))
+		return 0, 0, fmt.Errorf("invalid page")
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(config.PAGE_DEFAULT_LIMIT)))
+	if err != nil || limit < 1 || limit > config.PAGE_MAX_LIMIT {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "limit"))
+		return 0, 0, fmt.Errorf("invalid limit")
+	}
+	return page, limit, nil
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"goflix/config"
+	"goflix/db"
+	"goflix/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// benchMovies is the size of the catalog the benchmarks read and rate.
+const benchMovies = 100
+
+// newTestServer starts the API on a fresh database in a temporary file, with a catalog of
+// benchMovies published titles, and returns the token of a premium user.
+func newTestServer(b testing.TB) (*httptest.Server, string) {
+	b.Helper()
+	gin.DefaultWriter = io.Discard
+	store := db.NewFile(filepath.Join(b.TempDir(), "bench.db"))
+	ctx := context.Background()
+	err := store.Setup(ctx)
+	if err != nil {
+		b.Fatalf("Setup: %v", err)
+	}
+	b.Cleanup(store.Close)
+	level, _ := config.TitleMaturityLevel("PG")
+	for i := 1; i <= benchMovies; i++ {
+		err = store.AddMovie(ctx, &models.Movies{Title: fmt.Sprint("movie ", i), Maturity: "PG", MaturityLevel: level, Status: config.STATUS_PUBLISHED})
+		if err != nil {
+			b.Fatalf("AddMovie: %v", err)
+		}
+	}
+	s := New(store).(*Serve)
+	s.routes()
+	srv := httptest.NewServer(s.router)
+	b.Cleanup(srv.Close)
+
+	post(b, srv.URL+"/users", "", `{"user":"bench","pswd":"pw","account":"premium","Info":{"mail":"bench@example.com","cell":1}}`)
+	var login struct {
+		Token string `json:"token"`
+	}
+	err = json.Unmarshal(post(b, srv.URL+"/login", "", `{"user":"bench","pswd":"pw"}`), &login)
+	if err != nil || login.Token == "" {
+		b.Fatalf("login: %v", err)
+	}
+	return srv, login.Token
+}
+
+func post(b testing.TB, url string, token string, body string) []byte {
+	b.Helper()
+	res, err := do(newRequest(http.MethodPost, url, token, body))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return res
+}
+
+func newRequest(method string, url string, token string, body string) *http.Request {
+	req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	return req
+}
+
+// do sends the request and returns the body of its response, an error unless it is a 200.
+// It doesn't fail the benchmark itself, the parallel goroutines cannot stop it.
+func do(req *http.Request) ([]byte, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s: status %d: %s", req.Method, req.URL.Path, resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+func BenchmarkGetMovies(b *testing.B) {
+	srv, token := newTestServer(b)
+	var res struct {
+		Movies []*models.Movies `json:"movie"`
+	}
+	body, err := do(newRequest(http.MethodGet, srv.URL+"/movies", token, ""))
+	if err != nil {
+		b.Fatal(err)
+	}
+	err = json.Unmarshal(body, &res)
+	if err != nil || len(res.Movies) != benchMovies {
+		b.Fatalf("got %d movies (%v), want %d", len(res.Movies), err, benchMovies)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, err := do(newRequest(http.MethodGet, srv.URL+"/movies", token, ""))
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkPostRatings(b *testing.B) {
+	srv, token := newTestServer(b)
+	var seed int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		rnd := rand.New(rand.NewSource(atomic.AddInt64(&seed, 1)))
+		for pb.Next() {
+			body := fmt.Sprintf(`{"movieid":%d,"stars":%d}`, 1+rnd.Intn(benchMovies), 1+rnd.Intn(5))
+			_, err := do(newRequest(http.MethodPost, srv.URL+"/ratings", token, body))
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// TestAddListLimitConcurrent creates lists at once past LIST_MAX_PER_USER, only the free ones
+// are created.
+func TestAddListLimitConcurrent(t *testing.T) {
+	srv, token := newTestServer(t)
+	const free, workers = 3, 50
+	// the first list created makes the default one too
+	for i := 2; i <= config.LIST_MAX_PER_USER-free; i++ {
+		post(t, srv.URL+"/me/lists", token, fmt.Sprintf(`{"name":"list %d","visibility":"private"}`, i))
+	}
+	statuses := make([]int, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := http.DefaultClient.Do(newRequest(http.MethodPost, srv.URL+"/me/lists", token, fmt.Sprintf(`{"name":"new %d","visibility":"private"}`, i)))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+			statuses[i] = resp.StatusCode
+		}(i)
+	}
+	wg.Wait()
+	created := 0
+	for i, status := range statuses {
+		switch status {
+		case http.StatusOK:
+			created++
+		case http.StatusConflict:
+		default:
+			t.Errorf("request %d: status %d", i, status)
+		}
+	}
+	if created != free {
+		t.Errorf("created %d lists, want %d", created, free)
+	}
+	body, err := do(newRequest(http.MethodGet, srv.URL+"/me/lists", token, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res struct {
+		Lists []*models.List `json:"lists"`
+	}
+	err = json.Unmarshal(body, &res)
+	if err != nil || len(res.Lists) != config.LIST_MAX_PER_USER {
+		t.Fatalf("got %d lists (%v), want %d", len(res.Lists), err, config.LIST_MAX_PER_USER)
+	}
+}
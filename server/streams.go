@@ -0,0 +1,150 @@
+package server
+
+import (
+	"errors"
+	"goflix/apierr"
+	"goflix/config"
+	"goflix/db"
+	"goflix/middleware"
+	"goflix/models"
+	"goflix/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// * * * STREAMS * * *
+
+func (s *Serve) handelStartStream(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+	var session models.StreamSession
+	err := c.ShouldBindJSON(&session)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	filter, err := s.catalogFilter(c)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	if _, err := s.db.GetMoviesById(c.Request.Context(), session.MovieId, filter); err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	session.Id, err = utils.RandomToken(16)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	session.UserId = user.Id
+
+	active, err := s.db.StartStreamSession(c.Request.Context(), &session, s.maxStreams(c))
+	if errors.Is(err, db.ErrStreamLimit) {
+		apierr.Write(c, apierr.New(http.StatusConflict, apierr.STREAM_LIMIT).With("sessions", active))
+		return
+	}
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	s.recordEvent(c, config.EVENT_PLAY, user.Id, session.MovieId)
+	c.JSON(http.StatusCreated, session)
+}
+
+func (s *Serve) handelHeartbeatStream(c *gin.Context) {
+	if session := s.getOwnStreamSession(c); session != nil {
+		err := s.db.HeartbeatStreamSession(c.Request.Context(), session.Id)
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "stream alive"})
+	}
+}
+
+func (s *Serve) handelStopStream(c *gin.Context) {
+	if session := s.getOwnStreamSession(c); session != nil {
+		err := s.db.StopStreamSession(c.Request.Context(), session.Id, config.STREAM_END_STOPPED)
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		// the session may be playing on another device
+		s.publishStreamKicked(c, session, config.STREAM_END_STOPPED)
+		c.JSON(http.StatusOK, gin.H{"message": "stream stopped"})
+	}
+}
+
+func (s *Serve) handelGetStreams(c *gin.Context) {
+	if user := s.currentUser(c); user != nil {
+		sessions, err := s.db.GetActiveStreamSessions(c.Request.Context(), user.Id)
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+	}
+}
+
+func (s *Serve) handelGetAllStreams(c *gin.Context) {
+	sessions, err := s.db.GetActiveStreamSessions(c.Request.Context(), 0)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+func (s *Serve) handelKillStream(c *gin.Context) {
+	session, err := s.db.GetStreamSession(c.Request.Context(), c.Param("sessionID"))
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	err = s.db.StopStreamSession(c.Request.Context(), session.Id, config.STREAM_END_KILLED)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	s.publishStreamKicked(c, session, config.STREAM_END_KILLED)
+	c.JSON(http.StatusOK, gin.H{"message": "stream killed"})
+}
+
+func (s *Serve) getOwnStreamSession(c *gin.Context) *models.StreamSession {
+	user := s.currentUser(c)
+	if user == nil {
+		return nil
+	}
+	session, err := s.db.GetStreamSession(c.Request.Context(), c.Param("sessionID"))
+	if err != nil {
+		apierr.Write(c, err)
+		return nil
+	}
+	// the sessions of others are not found either
+	if session.UserId != user.Id {
+		apierr.Write(c, apierr.New(http.StatusNotFound, apierr.NOT_FOUND))
+		return nil
+	}
+	return session
+}
+
+// maxStreams reads the limit from the plan checked by ActiveSubscription, 0 is unlimited.
+func (s *Serve) maxStreams(c *gin.Context) int {
+	if sub := middleware.CurrentSubscription(c); sub != nil {
+		return sub.Plan.MaxStreams
+	}
+	return 0
+}
+
+func (s *Serve) currentUser(c *gin.Context) *models.User {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		apierr.Write(c, apierr.New(http.StatusUnauthorized, apierr.MISSING_TOKEN))
+		return nil
+	}
+	return user
+}
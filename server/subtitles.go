@@ -0,0 +1,319 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"goflix/apierr"
+	"goflix/config"
+	"goflix/middleware"
+	"goflix/models"
+	"goflix/utils"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// * * * SUBTITLES * * *
+
+func (s *Serve) handelAddSubtitle(c *gin.Context) {
+	movieID, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	if _, err := s.db.GetMoviesById(c.Request.Context(), movieID, nil); err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	lang := c.PostForm("lang")
+	if lang == "" {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.MISSING_FIELD, "lang"))
+		return
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.MISSING_FIELD, "file"))
+		return
+	}
+	if file.Size > config.SUBTITLE_MAX_MB<<20 {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.FILE_TOO_LARGE, config.SUBTITLE_MAX_MB))
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+
+	format := c.PostForm("format")
+	if format == "" {
+		format, err = utils.DetectSubtitleFormat(file.Filename, data)
+		if err != nil {
+			apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_SUBTITLE).WithDetail(err.Error()))
+			return
+		}
+	}
+	vtt, err := utils.ToWebVTT(format, data)
+	if err != nil {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_SUBTITLE).WithDetail(err.Error()))
+		return
+	}
+
+	subtitle := models.Subtitle{
+		MovieId: movieID,
+		Lang:    quotable(lang),
+		Label:   quotable(c.DefaultPostForm("label", lang)),
+		Forced:  c.PostForm("forced") == "true",
+		Content: vtt,
+	}
+	err = s.db.AddSubtitle(c.Request.Context(), &subtitle)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, subtitle)
+}
+
+func (s *Serve) handelGetSubtitle(c *gin.Context) {
+	if subtitle := s.getVisibleSubtitle(c); subtitle != nil {
+		c.Data(http.StatusOK, "text/vtt; charset=utf-8", []byte(subtitle.Content))
+	}
+}
+
+func (s *Serve) handelGetSubtitlePlaylist(c *gin.Context) {
+	if subtitle := s.getVisibleSubtitle(c); subtitle != nil {
+		duration := utils.WebVTTDuration(subtitle.Content).Seconds()
+		var b strings.Builder
+		b.WriteString("#EXTM3U\n")
+		b.WriteString("#EXT-X-VERSION:3\n")
+		fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(duration)))
+		b.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+		b.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n", duration)
+		fmt.Fprintf(&b, "/movies/%d/subtitles/%d\n", subtitle.MovieId, subtitle.Id)
+		b.WriteString("#EXT-X-ENDLIST\n")
+		c.Data(http.StatusOK, "application/vnd.apple.mpegurl", []byte(b.String()))
+	}
+}
+
+func (s *Serve) handelDeleteSubtitle(c *gin.Context) {
+	if subtitle := s.getMovieSubtitle(c); subtitle != nil {
+		err := s.db.DeleteSubtitle(c.Request.Context(), subtitle.Id)
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "subtitle deleted"})
+	}
+}
+
+// getVisibleSubtitle is getMovieSubtitle for the titles the user may see, like the master playlist.
+func (s *Serve) getVisibleSubtitle(c *gin.Context) *models.Subtitle {
+	movieID, err := s.getMovieID(c)
+	if err != nil {
+		return nil
+	}
+	filter, err := s.catalogFilter(c)
+	if err != nil {
+		apierr.Write(c, err)
+		return nil
+	}
+	if _, err := s.db.GetMoviesById(c.Request.Context(), movieID, filter); err != nil {
+		apierr.Write(c, err)
+		return nil
+	}
+	return s.getMovieSubtitle(c)
+}
+
+func (s *Serve) getMovieSubtitle(c *gin.Context) *models.Subtitle {
+	movieID, err := s.getMovieID(c)
+	if err != nil {
+		return nil
+	}
+	id, err := strconv.Atoi(c.Param("subtitleID"))
+	if err != nil {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "subtitleID"))
+		return nil
+	}
+	subtitle, err := s.db.GetSubtitle(c.Request.Context(), id)
+	if err != nil {
+		apierr.Write(c, err)
+		return nil
+	}
+	if subtitle.MovieId != movieID {
+		apierr.Write(c, apierr.New(http.StatusNotFound, apierr.NOT_FOUND))
+		return nil
+	}
+	return subtitle
+}
+
+// * * * AUDIO TRACKS * * *
+
+func (s *Serve) handelAddAudioTrack(c *gin.Context) {
+	movieID, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	if _, err := s.db.GetMoviesById(c.Request.Context(), movieID, nil); err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	var track models.AudioTrack
+	err = c.ShouldBindJSON(&track)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	if track.Lang == "" {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.MISSING_FIELD, "lang"))
+		return
+	}
+	if track.Role == "" {
+		track.Role = config.AUDIO_ROLE_MAIN
+	}
+	if !utils.Contains(config.AUDIO_ROLES, track.Role) {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_CHOICE, "role", config.AUDIO_ROLES))
+		return
+	}
+	track.Lang = quotable(track.Lang)
+	track.Label = quotable(track.Label)
+	if track.Label == "" {
+		track.Label = track.Lang
+	}
+	track.MovieId = movieID
+	err = s.db.AddAudioTrack(c.Request.Context(), &track)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, track)
+}
+
+func (s *Serve) handelDeleteAudioTrack(c *gin.Context) {
+	movieID, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("trackID"))
+	if err != nil {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "trackID"))
+		return
+	}
+	tracks, err := s.db.GetAudioTracks(c.Request.Context(), movieID)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	for _, track := range tracks {
+		if track.Id == id {
+			err = s.db.DeleteAudioTrack(c.Request.Context(), id)
+			if err != nil {
+				apierr.Write(c, err)
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "audio track deleted"})
+			return
+		}
+	}
+	apierr.Write(c, apierr.New(http.StatusNotFound, apierr.NOT_FOUND))
+}
+
+// * * * HLS * * *
+
+func (s *Serve) handelGetMasterPlaylist(c *gin.Context) {
+	movieID, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	filter, err := s.catalogFilter(c)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	movie, err := s.db.GetMoviesById(c.Request.Context(), movieID, filter)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	err = s.loadTracks(c.Request.Context(), movie)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	renditions := config.HLS_RENDITIONS
+	if sub := middleware.CurrentSubscription(c); sub != nil {
+		renditions = config.RenditionsUpTo(sub.Plan.MaxQuality)
+	}
+	c.Data(http.StatusOK, "application/vnd.apple.mpegurl", []byte(masterPlaylist(movie, renditions)))
+}
+
+func (s *Serve) loadTracks(ctx context.Context, movie *models.Movies) error {
+	var err error
+	movie.Subtitles, err = s.db.GetSubtitles(ctx, movie.Id)
+	if err != nil {
+		return err
+	}
+	movie.AudioTracks, err = s.db.GetAudioTracks(ctx, movie.Id)
+	return err
+}
+
+func masterPlaylist(movie *models.Movies, renditions []config.Rendition) string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	b.WriteString("#EXT-X-VERSION:6\n")
+	b.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+
+	hasDefault := false
+	for _, track := range movie.AudioTracks {
+		if track.Default {
+			hasDefault = true
+		}
+	}
+	for i, track := range movie.AudioTracks {
+		isDefault := track.Default || (!hasDefault && i == 0)
+		autoselect := track.Role != config.AUDIO_ROLE_COMMENTARY
+		fmt.Fprintf(&b, `#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",NAME="%s",LANGUAGE="%s",DEFAULT=%s,AUTOSELECT=%s`,
+			quotable(track.Label), quotable(track.Lang), yesNo(isDefault), yesNo(autoselect || isDefault))
+		if track.Role == config.AUDIO_ROLE_DESCRIPTION {
+			b.WriteString(`,CHARACTERISTICS="public.accessibility.describes-video"`)
+		}
+		fmt.Fprintf(&b, ",URI=\"%s/%d/audio/%d/index.m3u8\"\n", config.HLS_MEDIA_URL, movie.Id, track.Id)
+	}
+	for _, subtitle := range movie.Subtitles {
+		fmt.Fprintf(&b, `#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="%s",LANGUAGE="%s",DEFAULT=NO,AUTOSELECT=YES,FORCED=%s,URI="/movies/%d/subtitles/%d/playlist.m3u8"`+"\n",
+			quotable(subtitle.Label), quotable(subtitle.Lang), yesNo(subtitle.Forced), movie.Id, subtitle.Id)
+	}
+	for _, rendition := range renditions {
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%s", rendition.Bandwidth, rendition.Resolution)
+		if len(movie.AudioTracks) > 0 {
+			b.WriteString(`,AUDIO="audio"`)
+		}
+		if len(movie.Subtitles) > 0 {
+			b.WriteString(`,SUBTITLES="subs"`)
+		}
+		fmt.Fprintf(&b, "\n%s/%d/%s/index.m3u8\n", config.HLS_MEDIA_URL, movie.Id, rendition.Name)
+	}
+	return b.String()
+}
+
+// quotable removes what an HLS quoted-string cannot hold: double quotes, carriage returns and line
+// feeds. Values are cleaned when saved, and again when written for the ones saved before.
+func quotable(value string) string {
+	return strings.NewReplacer(`"`, "", "\r", "", "\n", "").Replace(value)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "YES"
+	}
+	return "NO"
+}
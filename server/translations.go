@@ -0,0 +1,145 @@
+package server
+
+import (
+	"fmt"
+	"goflix/apierr"
+	"goflix/i18n"
+	"goflix/middleware"
+	"goflix/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// * * * TRANSLATIONS * * *
+
+func (s *Serve) handelGetMovieTranslations(c *gin.Context) {
+	if id, err := s.getMovieID(c); err == nil {
+		translations, err := s.db.GetMovieTranslations(c.Request.Context(), id)
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"translations": translations})
+	}
+}
+
+func (s *Serve) handelSaveMovieTranslation(c *gin.Context) {
+	id, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	locale, err := s.getLocale(c)
+	if err != nil {
+		return
+	}
+	if _, err := s.db.GetMoviesById(c.Request.Context(), id, nil); err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	var translation models.MovieTranslation
+	err = c.ShouldBindJSON(&translation)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	if translation.Title == "" && translation.Details == "" {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.MISSING_FIELD, "title/details"))
+		return
+	}
+	translation.MovieId = id
+	translation.Locale = locale
+	err = s.db.SaveMovieTranslation(c.Request.Context(), &translation)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, translation)
+}
+
+func (s *Serve) handelDeleteMovieTranslation(c *gin.Context) {
+	id, err := s.getMovieID(c)
+	if err != nil {
+		return
+	}
+	if locale, err := s.getLocale(c); err == nil {
+		err := s.db.DeleteMovieTranslation(c.Request.Context(), id, locale)
+		if err != nil {
+			apierr.Write(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "translation deleted"})
+	}
+}
+
+func (s *Serve) handelSaveGenreTranslation(c *gin.Context) {
+	locale, err := s.getLocale(c)
+	if err != nil {
+		return
+	}
+	var body struct {
+		Name string `json:"name"`
+	}
+	err = c.ShouldBindJSON(&body)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return
+	}
+	if body.Name == "" {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.MISSING_FIELD, "name"))
+		return
+	}
+	err = s.db.SaveGenreName(c.Request.Context(), c.Param("slug"), locale, body.Name)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "translation saved"})
+}
+
+func (s *Serve) handelDeleteGenreTranslation(c *gin.Context) {
+	locale, err := s.getLocale(c)
+	if err != nil {
+		return
+	}
+	err = s.db.DeleteGenreName(c.Request.Context(), c.Param("slug"), locale)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "translation deleted"})
+}
+
+// getLocale reads the locale path parameter, a BCP 47 language tag.
+func (s *Serve) getLocale(c *gin.Context) (string, error) {
+	locale := i18n.Normalize(c.Param("locale"))
+	if !i18n.Valid(locale) {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "locale"))
+		return "", fmt.Errorf("invalid locale")
+	}
+	return locale, nil
+}
+
+// locales resolves the fallback chain of the viewer from the lang query parameter,
+// then the profile language, then the Accept-Language header.
+func (s *Serve) locales(c *gin.Context) []string {
+	var preferred []string
+	if lang := c.Query("lang"); lang != "" {
+		preferred = append(preferred, lang)
+	}
+	if user, ok := middleware.CurrentUser(c); ok {
+		settings, err := s.db.GetUserSettings(c.Request.Context(), user.Id)
+		if err == nil && settings.Language != "" {
+			preferred = append(preferred, settings.Language)
+		}
+	}
+	preferred = append(preferred, i18n.ParseAcceptLanguage(c.GetHeader("Accept-Language"))...)
+	return i18n.Chain(preferred...)
+}
+
+// translate localizes the titles of a response and tells caches the headers it depends on,
+// the profile language comes with the Authorization header.
+func (s *Serve) translate(c *gin.Context, movies ...*models.Movies) error {
+	c.Header("Vary", "Accept-Language, Authorization")
+	return s.db.TranslateMovies(c.Request.Context(), movies, s.locales(c))
+}
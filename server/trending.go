@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"goflix/activity"
+	"goflix/apierr"
+	"goflix/config"
+	"goflix/models"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// * * * TRENDING * * *
+
+func (s *Serve) handelGetTrending(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(config.TRENDING_LIMIT)))
+	if err != nil || limit < 1 || limit > config.PAGE_MAX_LIMIT {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "limit"))
+		return
+	}
+	s.writeBoard(c, config.BOARD_TRENDING, limit)
+}
+
+func (s *Serve) handelGetTop10(c *gin.Context) {
+	s.writeBoard(c, config.BOARD_TOP10, config.TOP10_SIZE)
+}
+
+func (s *Serve) writeBoard(c *gin.Context, board string, limit int) {
+	filter, err := s.catalogFilter(c)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	region := s.viewerRegion(c)
+	titles, err := activity.Board(c.Request.Context(), s.db, board, region, filter, limit)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	movies := make([]*models.Movies, len(titles))
+	for i, title := range titles {
+		movies[i] = title.Movie
+	}
+	err = s.translate(c, movies...)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"region": region, board: titles})
+}
+
+func (s *Serve) handelAggregateActivity(c *gin.Context) {
+	err := s.aggregateActivity(c.Request.Context())
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "activity aggregated"})
+}
+
+// recordEvent counts a viewer action towards the popularity of a title, failing to record it
+// doesn't fail the action.
+func (s *Serve) recordEvent(c *gin.Context, kind string, userID int, movieID int) {
+	event := models.Event{MovieId: movieID, UserId: userID, Kind: kind, Region: s.viewerRegion(c), CreatedAt: time.Now()}
+	if err := s.db.AddEvent(c.Request.Context(), &event); err != nil {
+		log.Printf("recording %s event: %v", kind, err)
+	}
+}
+
+func (s *Serve) aggregateActivity(ctx context.Context) error {
+	return activity.Aggregate(ctx, s.db, time.Now().UTC())
+}
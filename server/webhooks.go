@@ -0,0 +1,222 @@
+package server
+
+import (
+	"context"
+	"goflix/apierr"
+	"goflix/config"
+	"goflix/models"
+	"goflix/utils"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// * * * WEBHOOKS * * *
+
+func (s *Serve) handelGetWebhooks(c *gin.Context) {
+	webhooks, err := s.db.GetWebhooks(c.Request.Context())
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
+
+func (s *Serve) handelAddWebhook(c *gin.Context) {
+	webhook := models.Webhook{Enabled: true}
+	if !s.decodeWebhookJSON(c, &webhook) {
+		return
+	}
+	webhook.Id = 0
+	err := s.db.SaveWebhook(c.Request.Context(), &webhook)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, webhook)
+}
+
+func (s *Serve) handelUpdateWebhook(c *gin.Context) {
+	webhook := s.getWebhook(c)
+	if webhook == nil {
+		return
+	}
+	id := webhook.Id
+	if !s.decodeWebhookJSON(c, webhook) {
+		return
+	}
+	webhook.Id = id
+	err := s.db.SaveWebhook(c.Request.Context(), webhook)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, webhook)
+}
+
+func (s *Serve) handelDeleteWebhook(c *gin.Context) {
+	webhook := s.getWebhook(c)
+	if webhook == nil {
+		return
+	}
+	err := s.db.DeleteWebhook(c.Request.Context(), webhook.Id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "webhook deleted"})
+}
+
+// handelGetWebhookDeliveries lists the deliveries of the webhook, the last first, optionally of a status.
+func (s *Serve) handelGetWebhookDeliveries(c *gin.Context) {
+	webhook := s.getWebhook(c)
+	if webhook == nil {
+		return
+	}
+	page, limit, err := s.getPage(c)
+	if err != nil {
+		return
+	}
+	status := c.Query("status")
+	if status != "" && !utils.Contains(config.DELIVERY_STATUSES, status) {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_CHOICE, "status", config.DELIVERY_STATUSES))
+		return
+	}
+	deliveries, total, err := s.db.GetWebhookDeliveries(c.Request.Context(), webhook.Id, status, (page-1)*limit, limit)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"page": page, "limit": limit, "total": total, "deliveries": deliveries})
+}
+
+func (s *Serve) handelGetWebhookDelivery(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("deliveryID"))
+	if err != nil {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "deliveryID"))
+		return
+	}
+	delivery, err := s.db.GetWebhookDelivery(c.Request.Context(), id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// handelRedeliverWebhook sends a delivery again right away, with a fresh set of retries.
+func (s *Serve) handelRedeliverWebhook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("deliveryID"))
+	if err != nil {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "deliveryID"))
+		return
+	}
+	delivery, err := s.db.RequeueWebhookDelivery(c.Request.Context(), id)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	s.sendWebhooks()
+	c.JSON(http.StatusOK, delivery)
+}
+
+// decodeWebhookJSON reads the body over webhook, fields left out keep their value. A secret is
+// generated when none is set.
+func (s *Serve) decodeWebhookJSON(c *gin.Context, webhook *models.Webhook) bool {
+	err := c.ShouldBindJSON(webhook)
+	if err != nil {
+		apierr.Write(c, apierr.BadRequest(err))
+		return false
+	}
+	webhook.URL = strings.TrimSpace(webhook.URL)
+	if webhook.URL == "" {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.MISSING_FIELD, "url"))
+		return false
+	}
+	if u, err := url.Parse(webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "url"))
+		return false
+	}
+	if len(webhook.Events) == 0 {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.MISSING_FIELD, "events"))
+		return false
+	}
+	for _, event := range webhook.Events {
+		if event != config.WEBHOOK_ALL_EVENTS && !utils.Contains(config.WEBHOOK_EVENTS, event) {
+			apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_CHOICE, "events", config.WEBHOOK_EVENTS))
+			return false
+		}
+	}
+	webhook.Secret = strings.TrimSpace(webhook.Secret)
+	if webhook.Secret == "" {
+		token, err := utils.RandomToken(config.WEBHOOK_SECRET_SIZE)
+		if err != nil {
+			apierr.Write(c, err)
+			return false
+		}
+		webhook.Secret = "whsec_" + token
+	}
+	return true
+}
+
+func (s *Serve) getWebhook(c *gin.Context) *models.Webhook {
+	id, err := strconv.Atoi(c.Param("webhookID"))
+	if err != nil {
+		apierr.Write(c, apierr.New(http.StatusBadRequest, apierr.INVALID_PARAM, "webhookID"))
+		return nil
+	}
+	webhook, err := s.db.GetWebhook(c.Request.Context(), id)
+	if err != nil {
+		apierr.Write(c, err)
+		return nil
+	}
+	return webhook
+}
+
+// emitWebhook queues the event for the subscribed webhooks and sends it without waiting for the
+// next run. The request causing it never fails because of it, failures are only logged, and its
+// change is saved already so the event is queued even when the client goes away.
+func (s *Serve) emitWebhook(event string, data any) {
+	queued, err := s.webhooks.Enqueue(context.Background(), event, data)
+	if err != nil {
+		log.Printf("webhook %s: %v", event, err)
+	}
+	if queued > 0 {
+		s.sendWebhooks()
+	}
+}
+
+// emitMoviesCreated is emitWebhook for the titles created by an import or a library scan.
+func (s *Serve) emitMoviesCreated(ids []int) {
+	if len(ids) == 0 {
+		return
+	}
+	queued, err := s.webhooks.MoviesCreated(context.Background(), ids)
+	if err != nil {
+		log.Printf("webhook %s: %v", config.WEBHOOK_MOVIE_CREATED, err)
+	}
+	if queued > 0 {
+		s.sendWebhooks()
+	}
+}
+
+// sendWebhooks sends the due deliveries in the background, as one of the jobs so that shutdown
+// waits for it.
+func (s *Serve) sendWebhooks() {
+	s.jobs.Add(1)
+	go func() {
+		defer s.jobs.Done()
+		if err := s.deliverWebhooks(s.jobsCtx); err != nil && s.jobsCtx.Err() == nil {
+			log.Printf("job webhooks: %v", err)
+		}
+	}()
+}
+
+func (s *Serve) deliverWebhooks(ctx context.Context) error {
+	return s.webhooks.Deliver(ctx, time.Now())
+}
@@ -0,0 +1,256 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SUBTITLE_SRT    = "srt"
+	SUBTITLE_WEBVTT = "vtt"
+)
+
+type Cue struct {
+	Id       string
+	Start    time.Duration
+	End      time.Duration
+	Settings string
+	Text     string
+}
+
+var cueTiming = regexp.MustCompile(`^(\S+)\s+-->\s+(\S+)(.*)$`)
+var srtTimestamp = regexp.MustCompile(`^(\d{2,}):([0-5]\d):([0-5]\d),(\d{3})$`)
+var vttTimestamp = regexp.MustCompile(`^(?:(\d{2,}):)?([0-5]\d):([0-5]\d)\.(\d{3})$`)
+
+// DetectSubtitleFormat guesses the format from the file name, then from the content.
+func DetectSubtitleFormat(filename string, data []byte) (string, error) {
+	switch {
+	case strings.HasSuffix(strings.ToLower(filename), ".srt"):
+		return SUBTITLE_SRT, nil
+	case strings.HasSuffix(strings.ToLower(filename), ".vtt"):
+		return SUBTITLE_WEBVTT, nil
+	case bytes.HasPrefix(bytes.TrimPrefix(data, []byte("\ufeff")), []byte("WEBVTT")):
+		return SUBTITLE_WEBVTT, nil
+	case len(bytes.TrimSpace(data)) > 0:
+		return SUBTITLE_SRT, nil
+	}
+	return "", errors.New("unknown subtitle format")
+}
+
+// ToWebVTT parses a SRT or WebVTT file, validates every cue and returns it as WebVTT.
+func ToWebVTT(format string, data []byte) (string, error) {
+	var cues []*Cue
+	var err error
+	switch format {
+	case SUBTITLE_SRT:
+		cues, err = ParseSRT(data)
+	case SUBTITLE_WEBVTT:
+		cues, err = ParseWebVTT(data)
+	default:
+		return "", fmt.Errorf("unsupported subtitle format: %s", format)
+	}
+	if err != nil {
+		return "", err
+	}
+	return FormatWebVTT(cues), nil
+}
+
+func ParseSRT(data []byte) ([]*Cue, error) {
+	var cues []*Cue
+	for i, block := range splitBlocks(data) {
+		lines := block.lines
+		if len(lines) > 0 && !strings.Contains(lines[0], "-->") {
+			if _, err := strconv.Atoi(lines[0]); err != nil {
+				return nil, fmt.Errorf("line %d: invalid cue number %q", block.line, lines[0])
+			}
+			lines = lines[1:]
+		}
+		if len(lines) == 0 {
+			return nil, fmt.Errorf("line %d: missing cue timing", block.line)
+		}
+		cue, err := parseTiming(lines[0], parseSRTTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", block.line, err)
+		}
+		cue.Id = strconv.Itoa(i + 1)
+		cue.Text, err = cueText(block.line, lines[1:])
+		if err != nil {
+			return nil, err
+		}
+		cues = append(cues, cue)
+	}
+	if len(cues) == 0 {
+		return nil, errors.New("subtitle file has no cue")
+	}
+	return cues, nil
+}
+
+func ParseWebVTT(data []byte) ([]*Cue, error) {
+	blocks := splitBlocks(data)
+	if len(blocks) == 0 || !isWebVTTHeader(blocks[0].lines[0]) {
+		return nil, errors.New("missing WEBVTT header")
+	}
+	var cues []*Cue
+	for _, block := range blocks[1:] {
+		lines := block.lines
+		if strings.HasPrefix(lines[0], "NOTE") || lines[0] == "STYLE" || lines[0] == "REGION" {
+			continue
+		}
+		var id string
+		if !strings.Contains(lines[0], "-->") {
+			id = lines[0]
+			lines = lines[1:]
+		}
+		if len(lines) == 0 {
+			return nil, fmt.Errorf("line %d: missing cue timing", block.line)
+		}
+		cue, err := parseTiming(lines[0], parseWebVTTTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", block.line, err)
+		}
+		cue.Id = id
+		cue.Text, err = cueText(block.line, lines[1:])
+		if err != nil {
+			return nil, err
+		}
+		cues = append(cues, cue)
+	}
+	if len(cues) == 0 {
+		return nil, errors.New("subtitle file has no cue")
+	}
+	return cues, nil
+}
+
+func FormatWebVTT(cues []*Cue) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for _, cue := range cues {
+		b.WriteString("\n")
+		if cue.Id != "" {
+			b.WriteString(cue.Id + "\n")
+		}
+		fmt.Fprintf(&b, "%s --> %s%s\n", formatWebVTTTimestamp(cue.Start), formatWebVTTTimestamp(cue.End), cue.Settings)
+		if cue.Text != "" {
+			b.WriteString(cue.Text + "\n")
+		}
+	}
+	return b.String()
+}
+
+// WebVTTDuration returns the end time of the last cue of a WebVTT document.
+func WebVTTDuration(vtt string) time.Duration {
+	cues, err := ParseWebVTT([]byte(vtt))
+	if err != nil {
+		return 0
+	}
+	var end time.Duration
+	for _, cue := range cues {
+		if cue.End > end {
+			end = cue.End
+		}
+	}
+	return end
+}
+
+type block struct {
+	line  int
+	lines []string
+}
+
+func splitBlocks(data []byte) []block {
+	var blocks []block
+	var current *block
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			current = nil
+			continue
+		}
+		if current == nil {
+			blocks = append(blocks, block{line: n})
+			current = &blocks[len(blocks)-1]
+		}
+		current.lines = append(current.lines, line)
+	}
+	return blocks
+}
+
+func isWebVTTHeader(line string) bool {
+	return line == "WEBVTT" || strings.HasPrefix(line, "WEBVTT ") || strings.HasPrefix(line, "WEBVTT\t")
+}
+
+// cueText joins the text lines of a cue, an arrow in them would be read back as a cue timing.
+func cueText(line int, lines []string) (string, error) {
+	for _, text := range lines {
+		if strings.Contains(text, "-->") {
+			return "", fmt.Errorf("line %d: cue text cannot contain \"-->\"", line)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func parseTiming(line string, parse func(string) (time.Duration, error)) (*Cue, error) {
+	m := cueTiming.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return nil, fmt.Errorf("malformed cue timing %q", line)
+	}
+	start, err := parse(m[1])
+	if err != nil {
+		return nil, err
+	}
+	end, err := parse(m[2])
+	if err != nil {
+		return nil, err
+	}
+	if end <= start {
+		return nil, fmt.Errorf("cue ends before it starts %q", line)
+	}
+	return &Cue{Start: start, End: end, Settings: m[3]}, nil
+}
+
+func parseSRTTimestamp(s string) (time.Duration, error) {
+	m := srtTimestamp.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("malformed timestamp %q", s)
+	}
+	return timestamp(m[1], m[2], m[3], m[4]), nil
+}
+
+func parseWebVTTTimestamp(s string) (time.Duration, error) {
+	m := vttTimestamp.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("malformed timestamp %q", s)
+	}
+	return timestamp(m[1], m[2], m[3], m[4]), nil
+}
+
+func timestamp(h, m, s, ms string) time.Duration {
+	hours, _ := strconv.Atoi(h)
+	minutes, _ := strconv.Atoi(m)
+	seconds, _ := strconv.Atoi(s)
+	millis, _ := strconv.Atoi(ms)
+	return time.Duration(hours)*time.Hour +
+		time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second +
+		time.Duration(millis)*time.Millisecond
+}
+
+func formatWebVTTTimestamp(d time.Duration) string {
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	s := d / time.Second
+	d -= s * time.Second
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", h, m, s, d/time.Millisecond)
+}
@@ -1,6 +1,10 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -16,3 +20,48 @@ func CompareHashAndPassword(pswd, hashPswd []byte) error {
 	return bcrypt.CompareHashAndPassword(hashPswd, pswd)
 
 }
+
+func Contains[T comparable](list []T, value T) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+var slugReplacer = strings.NewReplacer(
+	"à", "a", "â", "a", "ä", "a", "á", "a", "ã", "a",
+	"ç", "c",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"î", "i", "ï", "i", "í", "i",
+	"ô", "o", "ö", "o", "ó", "o", "õ", "o",
+	"ù", "u", "û", "u", "ü", "u", "ú", "u",
+	"ñ", "n", "œ", "oe", "æ", "ae",
+)
+
+// Slugify lowercases s, folds common accents and joins the remaining words with dashes.
+func Slugify(s string) string {
+	s = slugReplacer.Replace(strings.ToLower(s))
+	var b strings.Builder
+	dash := false
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
@@ -0,0 +1,191 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"goflix/billing"
+	"goflix/config"
+	"goflix/db"
+	"goflix/models"
+	"goflix/utils"
+)
+
+const (
+	EVENT_HEADER    = "X-Goflix-Event"
+	DELIVERY_HEADER = "X-Goflix-Delivery"
+)
+
+// Payload is the body posted to the webhooks, Id identifies the event across webhooks and retries.
+type Payload struct {
+	Id      string    `json:"id"`
+	Type    string    `json:"type"`
+	Created time.Time `json:"created"`
+	Data    any       `json:"data"`
+}
+
+// Dispatcher queues the events for the webhooks subscribed to them and sends the due deliveries.
+type Dispatcher struct {
+	store  db.Storage
+	client *http.Client
+	// sending keeps a single run sending at a time, a run started meanwhile has nothing to do
+	sending sync.Mutex
+}
+
+func NewDispatcher(store db.Storage) *Dispatcher {
+	client := &http.Client{
+		Timeout: config.WEBHOOK_TIMEOUT,
+		// a redirect would forward the signed payload to another receiver, the 3xx fails the attempt
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return &Dispatcher{store: store, client: client}
+}
+
+// Enqueue stores a delivery of the event for each enabled webhook subscribed to it and returns
+// how many were queued.
+func (d *Dispatcher) Enqueue(ctx context.Context, event string, data any) (int, error) {
+	webhooks, err := d.store.GetWebhooks(ctx)
+	if err != nil {
+		return 0, err
+	}
+	token, err := utils.RandomToken(16)
+	if err != nil {
+		return 0, err
+	}
+	payload := Payload{Id: "evt_" + token, Type: event, Created: time.Now().UTC(), Data: data}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	queued := 0
+	for _, webhook := range webhooks {
+		if !webhook.Enabled || !Subscribed(webhook, event) {
+			continue
+		}
+		err = d.store.AddWebhookDelivery(ctx, &models.WebhookDelivery{
+			WebhookId: webhook.Id, EventId: payload.Id, Event: event, Payload: body, Status: config.DELIVERY_PENDING,
+		})
+		if err != nil {
+			return queued, err
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+// MoviesCreated queues movie.created for the titles of ids, created by an import or a library
+// scan, and returns how many deliveries were queued.
+func (d *Dispatcher) MoviesCreated(ctx context.Context, ids []int) (int, error) {
+	movies, err := d.store.GetMoviesByIds(ctx, ids, nil)
+	if err != nil {
+		return 0, err
+	}
+	queued := 0
+	for _, movie := range movies {
+		n, err := d.Enqueue(ctx, config.WEBHOOK_MOVIE_CREATED, movie)
+		queued += n
+		if err != nil {
+			return queued, err
+		}
+	}
+	return queued, nil
+}
+
+// Subscribed tells whether the webhook receives the event.
+func Subscribed(webhook *models.Webhook, event string) bool {
+	return utils.Contains(webhook.Events, event) || utils.Contains(webhook.Events, config.WEBHOOK_ALL_EVENTS)
+}
+
+// Deliver sends the deliveries due at now. A 2xx response settles a delivery, any other outcome
+// schedules a retry after the next of WEBHOOK_RETRY_DELAYS, or gives it up when none is left.
+func (d *Dispatcher) Deliver(ctx context.Context, now time.Time) error {
+	if !d.sending.TryLock() {
+		return nil
+	}
+	defer d.sending.Unlock()
+	deliveries, err := d.store.GetDueWebhookDeliveries(ctx, now, config.WEBHOOK_BATCH)
+	if err != nil {
+		return err
+	}
+	webhooks := map[int]*models.Webhook{}
+	for _, delivery := range deliveries {
+		webhook, ok := webhooks[delivery.WebhookId]
+		if !ok {
+			webhook, err = d.store.GetWebhook(ctx, delivery.WebhookId)
+			if err != nil && !db.IsNotFound(err) {
+				return err
+			}
+			webhooks[delivery.WebhookId] = webhook
+		}
+		attempt := &models.WebhookAttempt{DeliveryId: delivery.Id, Attempted: time.Now().UTC()}
+		if webhook == nil || !webhook.Enabled {
+			attempt.Error = "webhook deleted or disabled"
+			delivery.Attempts = len(config.WEBHOOK_RETRY_DELAYS)
+		} else {
+			attempt.ResponseCode, attempt.Error = d.send(ctx, webhook, delivery)
+		}
+		attempt.Duration = time.Since(attempt.Attempted).Milliseconds()
+		settle(delivery, attempt)
+		err = d.store.SaveWebhookAttempt(ctx, delivery, attempt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send posts the payload signed with the secret of the webhook, it returns the status code of
+// the response and an error message unless the receiver accepted it.
+func (d *Dispatcher) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err.Error()
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Goflix-Webhooks/1.0")
+	req.Header.Set(EVENT_HEADER, delivery.Event)
+	req.Header.Set(DELIVERY_HEADER, delivery.EventId)
+	req.Header.Set(billing.SIGNATURE_HEADER, billing.Sign([]byte(webhook.Secret), delivery.Payload, time.Now()))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, config.WEBHOOK_ERROR_MAX_LENGTH))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, ""
+	}
+	message := fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	if text := strings.TrimSpace(string(body)); text != "" {
+		message += ": " + text
+	}
+	return resp.StatusCode, message
+}
+
+// settle records the outcome of the attempt on the delivery.
+func settle(delivery *models.WebhookDelivery, attempt *models.WebhookAttempt) {
+	delivery.Attempts++
+	delivery.LastAttempt = &attempt.Attempted
+	delivery.ResponseCode = attempt.ResponseCode
+	delivery.LastError = attempt.Error
+	switch {
+	case attempt.Error == "":
+		delivery.Status = config.DELIVERY_SUCCEEDED
+		delivery.NextAttempt = nil
+	case delivery.Attempts > len(config.WEBHOOK_RETRY_DELAYS):
+		delivery.Status = config.DELIVERY_FAILED
+		delivery.NextAttempt = nil
+	default:
+		next := attempt.Attempted.Add(config.WEBHOOK_RETRY_DELAYS[delivery.Attempts-1])
+		delivery.NextAttempt = &next
+	}
+}